@@ -0,0 +1,191 @@
+package query
+
+import "strings"
+
+//AlterTableBuilder is a builder for ALTER TABLE statements
+type AlterTableBuilder struct {
+	dialect Dialect
+	table   string
+	actions []alteration
+}
+
+// alteration is a single action of an ALTER TABLE statement,
+// rendered for the builder's dialect by String. name and arg are
+// the column and its type of an add, the old and new names of a rename.
+type alteration struct {
+	kind   string
+	sql    string
+	name   string
+	arg    string
+	suffix string
+}
+
+const (
+	alterAdd    = "add"
+	alterRename = "rename"
+)
+
+//NewAlterTableBuilder returns a new *AlterTableBuilder
+func NewAlterTableBuilder() *AlterTableBuilder {
+	return new(AlterTableBuilder)
+}
+
+//WithDialect sets the dialect the builder's query is rendered for
+func (a *AlterTableBuilder) WithDialect(d Dialect) *AlterTableBuilder {
+	a.dialect = d
+	return a
+}
+
+//AlterTable starts an ALTER TABLE statement for table,
+//erasing any action previously added.
+func (a *AlterTableBuilder) AlterTable(table string) *AlterTableBuilder {
+	a.table = table
+	a.actions = nil
+	return a
+}
+
+//AddColumn adds a column named name of type typ to the table
+func (a *AlterTableBuilder) AddColumn(name string, typ string) *AlterTableBuilder {
+	a.actions = append(a.actions, alteration{kind: alterAdd, name: name, arg: typ})
+	return a
+}
+
+//NotNull adds NOT NULL to the last column added
+func (a *AlterTableBuilder) NotNull() *AlterTableBuilder {
+	return a.appendLast(" NOT NULL")
+}
+
+//Default sets the default value of the last column added,
+//value is added verbatim, so string values MUST be quoted with single-quotes
+func (a *AlterTableBuilder) Default(value string) *AlterTableBuilder {
+	return a.appendLast(" DEFAULT " + value)
+}
+
+//DropColumn drops the column named name from the table
+func (a *AlterTableBuilder) DropColumn(name string) *AlterTableBuilder {
+	return a.add("DROP COLUMN " + name)
+}
+
+//RenameColumn renames the column old to new,
+//Postgres and SQL Server get it as a statement of its own.
+func (a *AlterTableBuilder) RenameColumn(old string, new string) *AlterTableBuilder {
+	a.actions = append(a.actions, alteration{kind: alterRename, name: old, arg: new})
+	return a
+}
+
+//AddPrimaryKey adds a PRIMARY KEY constraint named name on fields
+func (a *AlterTableBuilder) AddPrimaryKey(name string, fields ...string) *AlterTableBuilder {
+	return a.add("ADD CONSTRAINT " + name + " " + fieldList("PRIMARY KEY", true, fields...))
+}
+
+//AddUnique adds a UNIQUE constraint named name on fields
+func (a *AlterTableBuilder) AddUnique(name string, fields ...string) *AlterTableBuilder {
+	return a.add("ADD CONSTRAINT " + name + " " + fieldList("UNIQUE", true, fields...))
+}
+
+//AddCheck adds a CHECK constraint named name with the specified condition
+func (a *AlterTableBuilder) AddCheck(name string, condition string) *AlterTableBuilder {
+	return a.add("ADD CONSTRAINT " + name + " CHECK (" + condition + ")")
+}
+
+//AddForeignKey adds a FOREIGN KEY constraint named name on fields,
+//References MUST be called after AddForeignKey.
+func (a *AlterTableBuilder) AddForeignKey(name string, fields ...string) *AlterTableBuilder {
+	return a.add("ADD CONSTRAINT " + name + " " + fieldList("FOREIGN KEY", true, fields...))
+}
+
+//References adds the referenced table and fields to the last
+//foreign key added
+func (a *AlterTableBuilder) References(table string, fields ...string) *AlterTableBuilder {
+	return a.appendLast(fieldList(" REFERENCES "+table, true, fields...))
+}
+
+//OnDelete sets the action taken on the last foreign key added
+//when a referenced row is deleted, e.g CASCADE or SET NULL
+func (a *AlterTableBuilder) OnDelete(action string) *AlterTableBuilder {
+	return a.appendLast(" ON DELETE " + action)
+}
+
+//DropConstraint drops the constraint named name
+//MySQL accepts it from 8.0.19 onwards.
+func (a *AlterTableBuilder) DropConstraint(name string) *AlterTableBuilder {
+	return a.add("DROP CONSTRAINT " + name)
+}
+
+func (a *AlterTableBuilder) add(action string) *AlterTableBuilder {
+	a.actions = append(a.actions, alteration{sql: action})
+	return a
+}
+
+func (a *AlterTableBuilder) appendLast(s string) *AlterTableBuilder {
+	if l := len(a.actions); l > 0 && a.actions[l-1].kind != alterRename {
+		a.actions[l-1].suffix += s
+	}
+	return a
+}
+
+//Clear erases the builder's query
+func (a *AlterTableBuilder) Clear() {
+	a.AlterTable("")
+}
+
+//String renders the builder's query.
+//Consecutive actions are combined into a single statement,
+//except for renames on Postgres and SQL Server, and SQL Server
+//gets a statement per action.
+func (a *AlterTableBuilder) String() string {
+	var stmts []string
+	var combined []string
+	flush := func() {
+		if len(combined) > 0 {
+			stmts = append(stmts, "ALTER TABLE "+a.table+" "+strings.Join(combined, ","))
+			combined = nil
+		}
+	}
+	for _, act := range a.actions {
+		sql, standalone := a.render(act)
+		if standalone {
+			flush()
+			stmts = append(stmts, sql)
+			continue
+		}
+		combined = append(combined, sql)
+		if a.dialect == SQLServer {
+			flush()
+		}
+	}
+	flush()
+	return strings.Join(stmts, ";") + ";"
+}
+
+// render returns act's SQL for the builder's dialect, and whether
+// it is a whole statement that can't be combined with other actions.
+func (a *AlterTableBuilder) render(act alteration) (string, bool) {
+	switch act.kind {
+	case alterAdd:
+		if a.dialect == SQLServer {
+			return "ADD " + act.name + " " + act.arg + act.suffix, false
+		}
+		return "ADD COLUMN " + act.name + " " + act.arg + act.suffix, false
+	case alterRename:
+		switch a.dialect {
+		case SQLServer:
+			return "EXEC sp_rename '" + a.table + "." + act.name + "','" + act.arg + "','COLUMN'", true
+		case Postgres:
+			return "ALTER TABLE " + a.table + " RENAME COLUMN " + act.name + " TO " + act.arg, true
+		}
+		return "RENAME COLUMN " + act.name + " TO " + act.arg, false
+	}
+	return act.sql + act.suffix, false
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (a *AlterTableBuilder) Pretty() string {
+	return Format(a.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query
+func (a *AlterTableBuilder) Fingerprint() Fingerprint {
+	return FingerprintSQL(a.String())
+}
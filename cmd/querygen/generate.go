@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goType maps a SQL column type to the Go type used in row structs,
+// nullable columns are represented by pointers.
+func goType(sqlType string, nullable bool) string {
+	base := strings.ToLower(sqlType)
+	if ix := strings.IndexAny(base, "( "); ix >= 0 {
+		base = base[:ix]
+	}
+
+	typ := "interface{}"
+	switch {
+	case strings.HasSuffix(strings.TrimSpace(sqlType), "[]"):
+		// a nil slice holds NULL, so elements are never pointers
+		return "[]" + goType(strings.TrimSuffix(strings.TrimSpace(sqlType), "[]"), false)
+	case base == "array":
+		// information_schema doesn't tell the type of the elements
+		return "[]interface{}"
+	case base == "smallint" || base == "int2" || base == "smallserial" || base == "tinyint":
+		typ = "int16"
+	case base == "int" || base == "integer" || base == "int4" || base == "serial" || base == "mediumint":
+		typ = "int32"
+	case base == "bigint" || base == "int8" || base == "bigserial":
+		typ = "int64"
+	case base == "real" || base == "float4":
+		typ = "float32"
+	case base == "float" || base == "float8" || base == "double" || base == "decimal" ||
+		base == "numeric" || base == "money" || base == "smallmoney":
+		typ = "float64"
+	case base == "bool" || base == "boolean" || base == "bit":
+		typ = "bool"
+	case base == "date" || strings.HasPrefix(base, "time") || strings.HasPrefix(base, "datetime") ||
+		base == "smalldatetime":
+		typ = "time.Time"
+	case base == "bytea" || strings.HasSuffix(base, "blob") || base == "binary" ||
+		base == "varbinary" || base == "image" || base == "json" || base == "jsonb":
+		return "[]byte"
+	case base == "char" || base == "character" || strings.HasSuffix(base, "char") ||
+		strings.HasSuffix(base, "text") || base == "uuid" || base == "uniqueidentifier" ||
+		base == "citext" || base == "enum" || base == "xml":
+		typ = "string"
+	default:
+		return typ
+	}
+
+	if nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+// packageName returns the package a table of schema is generated into
+func packageName(schema, fallback string) string {
+	if schema == "" {
+		return fallback
+	}
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, schema)
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		return "schema" + name
+	}
+	return name
+}
+
+// exported converts a SQL identifier such as order_id or OrderID
+// into an exported Go identifier.
+func exported(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return "X" + s
+	}
+	return s
+}
+
+// unused returns name, suffixed with a number if it is already taken,
+// and marks the name returned as taken.
+func unused(name string, taken map[string]bool) string {
+	candidate := name
+	for n := 2; taken[candidate]; n++ {
+		candidate = name + strconv.Itoa(n)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// structTag returns the literal of the struct tag mapping a field to column
+func structTag(column string) string {
+	tag := "db:" + strconv.Quote(column)
+	if strconv.CanBackquote(tag) {
+		return "`" + tag + "`"
+	}
+	return strconv.Quote(tag)
+}
+
+// generate renders the Go source for a package holding tables.
+// Tables and columns whose names convert to the same Go identifier,
+// such as order_id and OrderId, get numbered suffixes.
+func generate(pkg string, tables []table) ([]byte, error) {
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	usesTime := false
+	for _, t := range tables {
+		for _, c := range t.Columns {
+			if strings.HasSuffix(goType(c.Type, c.Nullable), "time.Time") {
+				usesTime = true
+			}
+		}
+	}
+
+	// every name is declared at package level, table constants first
+	taken := map[string]bool{}
+	names := make([]string, len(tables))
+	for ix, t := range tables {
+		names[ix] = unused(exported(t.Name), taken)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by querygen. DO NOT EDIT.\n\n")
+	b.WriteString("package " + pkg + "\n\n")
+	if usesTime {
+		b.WriteString("import \"time\"\n\n")
+	}
+
+	for i, t := range tables {
+		name := names[i]
+		fieldsTaken := map[string]bool{}
+		fields := make([]string, len(t.Columns))
+		consts := make([]string, len(t.Columns))
+		for ix, c := range t.Columns {
+			fields[ix] = unused(exported(c.Name), fieldsTaken)
+			consts[ix] = unused(name+fields[ix], taken)
+		}
+		columns, row := unused(name+"Columns", taken), unused(name+"Row", taken)
+
+		b.WriteString("// " + name + " is the " + t.qualified() + " table\n")
+		b.WriteString("const " + name + " = " + strconv.Quote(t.qualified()) + "\n\n")
+
+		b.WriteString("// Columns of " + t.qualified() + "\n")
+		b.WriteString("const (\n")
+		for ix, c := range t.Columns {
+			b.WriteString(consts[ix] + " = " + strconv.Quote(c.Name) + "\n")
+		}
+		b.WriteString(")\n\n")
+
+		b.WriteString("// " + columns + " lists every column of " + t.qualified() + " in table order\n")
+		b.WriteString("var " + columns + " = []string{\n")
+		for ix := range t.Columns {
+			b.WriteString(consts[ix] + ",\n")
+		}
+		b.WriteString("}\n\n")
+
+		b.WriteString("// " + row + " is a row of " + t.qualified() + "\n")
+		b.WriteString("type " + row + " struct {\n")
+		for ix, c := range t.Columns {
+			b.WriteString(fields[ix] + " " + goType(c.Type, c.Nullable) + " " + structTag(c.Name) + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	return format.Source(b.Bytes())
+}
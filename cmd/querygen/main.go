@@ -0,0 +1,154 @@
+// Command querygen generates Go packages holding table and column
+// constants and row structs from a database schema, so that names passed to
+// the query builders stay in sync with migrations.
+//
+// The schema is read either from CREATE TABLE statements in .sql files:
+//
+//	querygen -out ./tables migrations/*.sql
+//
+// or from the information_schema of a live database:
+//
+//	querygen -out ./tables -driver postgres -dsn "$DATABASE_URL" -schema Sales,Stock
+//
+// Introspection goes through database/sql, so the driver named by -driver must be
+// linked into the binary, for example by adding a file with a blank import of the
+// driver package next to this one.
+//
+// One package is generated per schema, named after the schema in lower case.
+// Tables without a schema are generated into the package named by -pkg.
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	out := flag.String("out", ".", "directory the generated packages are written to")
+	pkg := flag.String("pkg", "tables", "package name for tables without a schema")
+	driver := flag.String("driver", "", "database/sql driver used to introspect a live schema")
+	dsn := flag.String("dsn", "", "data source name passed to the driver")
+	schemas := flag.String("schema", "", "comma separated schemas to introspect, all schemas when empty")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: querygen [flags] [file.sql ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*out, *pkg, *driver, *dsn, *schemas, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(out, pkg, driver, dsn, schemas string, files []string) error {
+	var tables []table
+	switch {
+	case driver != "":
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			return fmt.Errorf("querygen: %v (is the %s driver linked into querygen?)", err, driver)
+		}
+		defer db.Close()
+
+		var filter []string
+		if schemas != "" {
+			filter = strings.Split(schemas, ",")
+		}
+		if tables, err = introspect(db, filter); err != nil {
+			return err
+		}
+	case len(files) > 0:
+		for _, f := range files {
+			src, err := ioutil.ReadFile(f)
+			if err != nil {
+				return err
+			}
+			parsed, err := parseDDL(string(src))
+			if err != nil {
+				return fmt.Errorf("%s: %v", f, err)
+			}
+			tables = append(tables, parsed...)
+		}
+	default:
+		return errors.New("querygen: no .sql files given and no -driver set")
+	}
+
+	return write(out, pkg, tables)
+}
+
+// write generates one package per schema under out
+func write(out, fallback string, tables []table) error {
+	packages := map[string][]table{}
+	for _, t := range tables {
+		p := packageName(t.Schema, fallback)
+		packages[p] = append(packages[p], t)
+	}
+
+	for p, ts := range packages {
+		src, err := generate(p, ts)
+		if err != nil {
+			return fmt.Errorf("querygen: generating package %s: %v", p, err)
+		}
+		dir := filepath.Join(out, p)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "tables.go"), src, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// introspect reads table definitions from information_schema.columns,
+// schemas are filtered here rather than in SQL, to stay independent of
+// the driver's placeholder style.
+func introspect(db *sql.DB, schemas []string) ([]table, error) {
+	rows, err := db.Query("SELECT table_schema,table_name,column_name,data_type,is_nullable " +
+		"FROM information_schema.columns ORDER BY table_schema,table_name,ordinal_position")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wanted := map[string]bool{}
+	for _, s := range schemas {
+		wanted[strings.ToLower(strings.TrimSpace(s))] = true
+	}
+
+	var tables []table
+	for rows.Next() {
+		var schema, name, col, typ, nullable string
+		if err := rows.Scan(&schema, &name, &col, &typ, &nullable); err != nil {
+			return nil, err
+		}
+		if len(wanted) > 0 && !wanted[strings.ToLower(schema)] {
+			continue
+		}
+		if isSystemSchema(schema) {
+			continue
+		}
+
+		if l := len(tables); l == 0 || tables[l-1].Schema != schema || tables[l-1].Name != name {
+			tables = append(tables, table{Schema: schema, Name: name})
+		}
+		t := &tables[len(tables)-1]
+		t.Columns = append(t.Columns, column{Name: col, Type: typ, Nullable: strings.EqualFold(nullable, "YES")})
+	}
+	return tables, rows.Err()
+}
+
+func isSystemSchema(schema string) bool {
+	switch strings.ToLower(schema) {
+	case "information_schema", "pg_catalog", "mysql", "performance_schema", "sys":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/danvixent/query/internal/sqlscan"
+)
+
+// table is a table definition read from DDL or from information_schema
+type table struct {
+	Schema  string
+	Name    string
+	Columns []column
+}
+
+// column is a single column of a table
+type column struct {
+	Name     string
+	Type     string
+	Nullable bool
+}
+
+// qualified returns the table name prefixed by its schema, if any
+func (t table) qualified() string {
+	if t.Schema == "" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// parseDDL extracts every CREATE TABLE statement from src,
+// statements other than CREATE TABLE are ignored.
+func parseDDL(src string) ([]table, error) {
+	var tables []table
+	for _, stmt := range splitStatements(stripComments(src)) {
+		words := strings.Fields(stmt)
+		if len(words) < 3 || !strings.EqualFold(words[0], "CREATE") {
+			continue
+		}
+		// skip modifiers such as TEMPORARY or UNLOGGED
+		ix := 1
+		for ix < len(words) && isTableModifier(words[ix]) {
+			ix++
+		}
+		if ix >= len(words) || !strings.EqualFold(words[ix], "TABLE") {
+			continue
+		}
+
+		t, err := parseCreateTable(stmt)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
+
+func parseCreateTable(stmt string) (table, error) {
+	open := strings.IndexByte(stmt, '(')
+	closing := strings.LastIndexByte(stmt, ')')
+	if open < 0 || closing < open {
+		return table{}, errors.New("querygen: malformed CREATE TABLE statement: " + firstLine(stmt))
+	}
+
+	head := strings.Fields(stmt[:open])
+	name := head[len(head)-1]
+	var t table
+	t.Schema, t.Name = splitQualified(name)
+	if t.Name == "" {
+		return table{}, errors.New("querygen: CREATE TABLE without a table name: " + firstLine(stmt))
+	}
+
+	primary := map[string]bool{}
+	for _, def := range splitTopLevel(stmt[open+1:closing], ',') {
+		words := strings.Fields(def)
+		if len(words) == 0 {
+			continue
+		}
+		switch strings.ToUpper(words[0]) {
+		case "PRIMARY":
+			for _, c := range parenList(def) {
+				primary[c] = true
+			}
+			continue
+		case "CONSTRAINT":
+			if len(words) > 2 && strings.EqualFold(words[2], "PRIMARY") {
+				for _, c := range parenList(def) {
+					primary[c] = true
+				}
+			}
+			continue
+		case "FOREIGN", "UNIQUE", "CHECK", "INDEX", "KEY", "EXCLUDE", "LIKE":
+			continue
+		}
+
+		c, pk := parseColumn(words)
+		if pk {
+			primary[c.Name] = true
+		}
+		t.Columns = append(t.Columns, c)
+	}
+
+	for i := range t.Columns {
+		if primary[t.Columns[i].Name] {
+			t.Columns[i].Nullable = false
+		}
+	}
+	return t, nil
+}
+
+// parseColumn parses a column definition split into words,
+// it reports whether the column is declared as a PRIMARY KEY inline.
+func parseColumn(words []string) (column, bool) {
+	c := column{Name: unquote(words[0]), Nullable: true}
+	pk := false
+
+	var typ []string
+	ix := 1
+	for ; ix < len(words); ix++ {
+		if isColumnConstraint(words[ix]) {
+			break
+		}
+		typ = append(typ, words[ix])
+	}
+	c.Type = strings.Join(typ, " ")
+
+	for ; ix < len(words); ix++ {
+		switch strings.ToUpper(words[ix]) {
+		case "NOT":
+			if ix+1 < len(words) && strings.EqualFold(words[ix+1], "NULL") {
+				c.Nullable = false
+				ix++
+			}
+		case "PRIMARY":
+			pk = true
+		case "IDENTITY", "SERIAL", "AUTO_INCREMENT", "AUTOINCREMENT":
+			c.Nullable = false
+		}
+	}
+	return c, pk
+}
+
+func isTableModifier(word string) bool {
+	switch strings.ToUpper(word) {
+	case "TEMP", "TEMPORARY", "UNLOGGED", "GLOBAL", "LOCAL", "OR", "REPLACE":
+		return true
+	}
+	return false
+}
+
+func isColumnConstraint(word string) bool {
+	switch strings.ToUpper(word) {
+	case "NOT", "NULL", "DEFAULT", "PRIMARY", "REFERENCES", "UNIQUE", "CHECK",
+		"CONSTRAINT", "COLLATE", "GENERATED", "IDENTITY", "AUTO_INCREMENT", "AUTOINCREMENT":
+		return true
+	}
+	return strings.HasPrefix(strings.ToUpper(word), "IDENTITY(")
+}
+
+// stripComments replaces -- and /* */ comments of src with a space,
+// leaving quoted strings intact.
+func stripComments(src string) string {
+	var b strings.Builder
+	copied := 0
+	for _, tok := range sqlscan.Tokenize(src) {
+		if tok.Kind == sqlscan.Comment {
+			b.WriteString(src[copied:tok.Pos])
+			b.WriteByte(' ')
+			copied = tok.Pos + len(tok.Text)
+		}
+	}
+	b.WriteString(src[copied:])
+	return b.String()
+}
+
+// splitStatements splits src on semicolons that are not quoted
+func splitStatements(src string) []string {
+	var stmts []string
+	for _, s := range splitTopLevel(src, ';') {
+		if s = strings.TrimSpace(s); s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// splitTopLevel splits s on sep when it appears outside quotes and parentheses
+func splitTopLevel(s string, sep byte) []string {
+	return sqlscan.Split(s, sep)
+}
+
+// parenList returns the unquoted names inside the first parenthesized list in s
+func parenList(s string) []string {
+	open := strings.IndexByte(s, '(')
+	closing := strings.IndexByte(s, ')')
+	if open < 0 || closing < open {
+		return nil
+	}
+	var names []string
+	for _, n := range strings.Split(s[open+1:closing], ",") {
+		names = append(names, unquote(strings.TrimSpace(n)))
+	}
+	return names
+}
+
+// splitQualified splits a possibly schema-qualified name
+func splitQualified(name string) (string, string) {
+	parts := strings.Split(name, ".")
+	for i := range parts {
+		parts[i] = unquote(parts[i])
+	}
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
+
+func unquote(name string) string {
+	if len(name) < 2 {
+		return name
+	}
+	switch name[0] {
+	case '"', '`':
+		if name[len(name)-1] == name[0] {
+			return name[1 : len(name)-1]
+		}
+	case '[':
+		if name[len(name)-1] == ']' {
+			return name[1 : len(name)-1]
+		}
+	}
+	return name
+}
+
+func firstLine(s string) string {
+	if ix := strings.IndexFunc(s, func(r rune) bool { return r == '\n' }); ix >= 0 {
+		s = s[:ix]
+	}
+	return strings.TrimRightFunc(s, unicode.IsSpace)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const ddl = `
+-- orders placed by stores
+CREATE TABLE IF NOT EXISTS Sales.OrderHeader (
+	OrderID int IDENTITY(1,1) NOT NULL,
+	StoreID int NOT NULL REFERENCES Sales.Store(StoreID),
+	OrderDate timestamp NOT NULL DEFAULT now(),
+	TotalAmountDue decimal(12, 2),
+	/* free text */ comment varchar(255),
+	CONSTRAINT pk_order PRIMARY KEY (OrderID)
+);
+
+CREATE INDEX ix_store ON Sales.OrderHeader (StoreID);
+
+CREATE TABLE "Person"."Contact" (
+	"ContactID" bigint PRIMARY KEY,
+	first_name text
+);
+`
+
+func TestParseDDL(t *testing.T) {
+	tables, err := parseDDL(ddl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("got %d tables, want 2", len(tables))
+	}
+
+	want := []column{
+		{"OrderID", "int", false},
+		{"StoreID", "int", false},
+		{"OrderDate", "timestamp", false},
+		{"TotalAmountDue", "decimal(12, 2)", true},
+		{"comment", "varchar(255)", true},
+	}
+	got := tables[0]
+	if got.qualified() != "Sales.OrderHeader" {
+		t.Errorf("got table %s, want Sales.OrderHeader", got.qualified())
+	}
+	if len(got.Columns) != len(want) {
+		t.Fatalf("got columns %v, want %v", got.Columns, want)
+	}
+	for i := range want {
+		if got.Columns[i] != want[i] {
+			t.Errorf("got column %v, want %v", got.Columns[i], want[i])
+		}
+	}
+
+	if c := tables[1].Columns[0]; tables[1].qualified() != "Person.Contact" || c.Name != "ContactID" || c.Nullable {
+		t.Errorf("got %s %v, want a non-null Person.Contact.ContactID", tables[1].qualified(), c)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tables, err := parseDDL(ddl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(packageName(tables[0].Schema, "tables"), tables[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"package sales",
+		`const OrderHeader = "Sales.OrderHeader"`,
+		`OrderHeaderTotalAmountDue = "TotalAmountDue"`,
+		"OrderDate      time.Time `db:\"OrderDate\"`",
+		"TotalAmountDue *float64  `db:\"TotalAmountDue\"`",
+		"Comment        *string   `db:\"comment\"`",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source is missing %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateArraysAndCollisions(t *testing.T) {
+	tables, err := parseDDL(`CREATE TABLE Stock.Product (
+	ProductID int NOT NULL,
+	Tags text[],
+	Sizes int[] NOT NULL,
+	Columns varchar(50),
+	Row int
+);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate("stock", tables)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Tags      []string `db:\"Tags\"`",
+		"Sizes     []int32  `db:\"Sizes\"`",
+		`ProductColumns   = "Columns"`,
+		"var ProductColumns2 = []string{",
+		"type ProductRow2 struct {",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source is missing %q:\n%s", want, src)
+		}
+	}
+	if got := goType("ARRAY", true); got != "[]interface{}" {
+		t.Errorf("got %s for an introspected ARRAY, want []interface{}", got)
+	}
+}
+
+func TestGenerateQuotedNames(t *testing.T) {
+	src, err := generate("sales", []table{
+		{Schema: "Sales", Name: "order_item", Columns: []column{
+			{Name: "order_id", Type: "int"},
+			{Name: "OrderId", Type: "int"},
+			{Name: `say "hi"`, Type: "text"},
+			{Name: "back`tick", Type: "text"},
+		}},
+		{Schema: "Sales", Name: "OrderItem", Columns: []column{{Name: "id", Type: "int"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`const OrderItem = "Sales.OrderItem"`,
+		`const OrderItem2 = "Sales.order_item"`,
+		`OrderItem2OrderId2 = "OrderId"`,
+		`OrderItem2SayHi    = "say \"hi\""`,
+		"OrderId2 int32  `db:\"OrderId\"`",
+		"SayHi    string `db:\"say \\\"hi\\\"\"`",
+		`BackTick string "db:\"back` + "`" + `tick\""`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated source is missing %q:\n%s", want, src)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package query
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//CallerKey is the comment key holding the file:line of the code
+//which commented the query, e.g orders/store.go:42
+const CallerKey = "caller"
+
+//CommentSource returns tags to comment queries with from ctx, such as
+//the traceparent of the span it carries.
+type CommentSource func(ctx context.Context) map[string]string
+
+var (
+	sourcesMu sync.RWMutex
+	sources   []CommentSource
+)
+
+//AddCommentSource registers src for every comment, the tags set by
+//WithTag take precedence over the ones it returns.
+//
+//Usage example:
+//	query.AddCommentSource(func(ctx context.Context) map[string]string {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return nil
+//		}
+//		return map[string]string{"traceparent": "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"}
+//	})
+func AddCommentSource(src CommentSource) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources = append(sources, src)
+}
+
+type tagsKey struct{}
+
+//WithTag returns a copy of ctx carrying the tag key=value,
+//which queries commented with ctx are tagged with.
+//
+//Usage example:
+//	ctx = query.WithTag(ctx, "route", "/orders/{id}")
+//	ctx = query.WithTag(ctx, "controller", "orders")
+func WithTag(ctx context.Context, key, value string) context.Context {
+	parent, _ := ctx.Value(tagsKey{}).(map[string]string)
+	tags := make(map[string]string, len(parent)+1)
+	for k, v := range parent {
+		tags[k] = v
+	}
+	tags[key] = value
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+//AppendComment returns sql followed by the comment of ctx, ahead of its
+//trailing semicolon if it has one. See the Comment method of builders.
+func AppendComment(ctx context.Context, sql string) string {
+	c := comment(ctx)
+	if strings.HasSuffix(sql, ";") {
+		return sql[:len(sql)-1] + " " + c + ";"
+	}
+	return sql + " " + c
+}
+
+//comment renders the sqlcommenter comment of ctx: the tags of the comment
+//sources and of ctx, and the caller, as key='value' pairs sorted by key,
+//with keys and values URL-encoded.
+func comment(ctx context.Context) string {
+	tags := map[string]string{}
+	sourcesMu.RLock()
+	for _, src := range sources {
+		for k, v := range src(ctx) {
+			tags[k] = v
+		}
+	}
+	sourcesMu.RUnlock()
+	if ctxTags, ok := ctx.Value(tagsKey{}).(map[string]string); ok {
+		for k, v := range ctxTags {
+			tags[k] = v
+		}
+	}
+	if caller := caller(); caller != "" {
+		tags[CallerKey] = caller
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("/*")
+	for ix, k := range keys {
+		if ix > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(commentEscape(k))
+		b.WriteString("='")
+		b.WriteString(commentEscape(tags[k]))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+//commentEscape URL-encodes s as sqlcommenter requires, which leaves
+//no quote to escape, nor any * or / to end the comment.
+func commentEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+//pkgDir is the directory of the package's source files
+var pkgDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+//caller returns the file:line of the first caller outside the package,
+//the file being named after its directory, e.g orders/store.go:42
+func caller() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != pkgDir || strings.HasSuffix(frame.File, "_test.go") {
+			if frame.File == "" {
+				return ""
+			}
+			return filepath.Base(filepath.Dir(frame.File)) + "/" + filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package query
+
+//CreateIndexBuilder is a builder for CREATE INDEX statements
+type CreateIndexBuilder struct {
+	dialect      Dialect
+	name         string
+	table        string
+	unique       bool
+	concurrently bool
+	ifNotExists  bool
+	columns      []string
+	where        string
+}
+
+//NewCreateIndexBuilder returns a new *CreateIndexBuilder
+func NewCreateIndexBuilder() *CreateIndexBuilder {
+	return new(CreateIndexBuilder)
+}
+
+//WithDialect sets the dialect the builder's query is rendered for
+func (c *CreateIndexBuilder) WithDialect(d Dialect) *CreateIndexBuilder {
+	c.dialect = d
+	return c
+}
+
+//CreateIndex starts a CREATE INDEX statement for an index named name,
+//erasing anything previously added.
+func (c *CreateIndexBuilder) CreateIndex(name string) *CreateIndexBuilder {
+	*c = CreateIndexBuilder{dialect: c.dialect, name: name}
+	return c
+}
+
+//Unique makes the index a UNIQUE index
+func (c *CreateIndexBuilder) Unique() *CreateIndexBuilder {
+	c.unique = true
+	return c
+}
+
+//Concurrently builds the index without locking out writes on the table.
+//It renders CONCURRENTLY on Postgres, WITH (ONLINE=ON) on SQL Server
+//and ALGORITHM=INPLACE LOCK=NONE on MySQL.
+func (c *CreateIndexBuilder) Concurrently() *CreateIndexBuilder {
+	c.concurrently = true
+	return c
+}
+
+//IfNotExists only creates the index if it doesn't exist yet.
+//MySQL has no such clause, so it is ignored there.
+func (c *CreateIndexBuilder) IfNotExists() *CreateIndexBuilder {
+	c.ifNotExists = true
+	return c
+}
+
+//On sets the table and the columns to be indexed
+func (c *CreateIndexBuilder) On(table string, columns ...string) *CreateIndexBuilder {
+	c.table = table
+	c.columns = append(c.columns, columns...)
+	return c
+}
+
+//Expression adds an expression to be indexed, e.g lower(Email),
+//On MUST be called prior to Expression.
+func (c *CreateIndexBuilder) Expression(expr string) *CreateIndexBuilder {
+	c.columns = append(c.columns, "("+expr+")")
+	return c
+}
+
+//Where makes the index a partial index over rows matching condition.
+//MySQL has no partial indexes, Err reports them with an *UnsupportedError.
+func (c *CreateIndexBuilder) Where(condition string) *CreateIndexBuilder {
+	c.where = condition
+	return c
+}
+
+//Err returns an *UnsupportedError for a partial index on MySQL
+func (c *CreateIndexBuilder) Err() error {
+	if c.where != "" && c.dialect == MySQL {
+		return &UnsupportedError{Dialect: c.dialect, Clause: "CREATE INDEX ... WHERE"}
+	}
+	return nil
+}
+
+//Clear erases the builder's query
+func (c *CreateIndexBuilder) Clear() {
+	c.CreateIndex("")
+}
+
+func (c *CreateIndexBuilder) String() string {
+	qry := "CREATE "
+	if c.unique {
+		qry += "UNIQUE "
+	}
+	qry += "INDEX "
+	if c.concurrently && c.dialect == Postgres {
+		qry += "CONCURRENTLY "
+	}
+	if c.ifNotExists {
+		switch c.dialect {
+		case Postgres:
+			qry += "IF NOT EXISTS "
+		case SQLServer:
+			//index names are only unique per table
+			qry = "IF NOT EXISTS (SELECT * FROM sys.indexes WHERE name=" + quote(c.name) +
+				" AND object_id=OBJECT_ID(" + quote(c.table) + ")) " + qry
+		}
+	}
+	qry += c.name + fieldList(" ON "+c.table, true, c.columns...)
+
+	if c.where != "" {
+		qry += " WHERE " + c.where
+	}
+	if c.concurrently {
+		switch c.dialect {
+		case SQLServer:
+			qry += " WITH (ONLINE=ON)"
+		case MySQL:
+			qry += " ALGORITHM=INPLACE LOCK=NONE"
+		}
+	}
+	return qry + ";"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (c *CreateIndexBuilder) Pretty() string {
+	return Format(c.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query
+func (c *CreateIndexBuilder) Fingerprint() Fingerprint {
+	return FingerprintSQL(c.String())
+}
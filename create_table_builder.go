@@ -0,0 +1,149 @@
+package query
+
+import "strings"
+
+//CreateTableBuilder is a builder for CREATE TABLE statements
+type CreateTableBuilder struct {
+	dialect     Dialect
+	table       string
+	ifNotExists bool
+	constraint  string
+	defs        []string
+}
+
+//NewCreateTableBuilder returns a new *CreateTableBuilder
+func NewCreateTableBuilder() *CreateTableBuilder {
+	return new(CreateTableBuilder)
+}
+
+//WithDialect sets the dialect the builder's query is rendered for
+func (c *CreateTableBuilder) WithDialect(d Dialect) *CreateTableBuilder {
+	c.dialect = d
+	return c
+}
+
+//CreateTable starts a CREATE TABLE statement for table,
+//erasing any column or constraint previously added.
+func (c *CreateTableBuilder) CreateTable(table string) *CreateTableBuilder {
+	c.table = table
+	c.ifNotExists = false
+	c.constraint = ""
+	c.defs = nil
+	return c
+}
+
+//IfNotExists only creates the table if it doesn't exist yet
+func (c *CreateTableBuilder) IfNotExists() *CreateTableBuilder {
+	c.ifNotExists = true
+	return c
+}
+
+//Column adds a column named name of type typ to the table.
+//
+//Usage example:
+//	Column("FirstName", "varchar(50)").NotNull().Default("''")
+func (c *CreateTableBuilder) Column(name string, typ string) *CreateTableBuilder {
+	c.defs = append(c.defs, name+" "+typ)
+	return c
+}
+
+//NotNull adds NOT NULL to the last column or constraint added
+func (c *CreateTableBuilder) NotNull() *CreateTableBuilder {
+	return c.appendLast(" NOT NULL")
+}
+
+//Default sets the default value of the last column added,
+//value is added verbatim, so string values MUST be quoted with single-quotes
+func (c *CreateTableBuilder) Default(value string) *CreateTableBuilder {
+	return c.appendLast(" DEFAULT " + value)
+}
+
+//Constraint names the next constraint added to the table
+func (c *CreateTableBuilder) Constraint(name string) *CreateTableBuilder {
+	c.constraint = name
+	return c
+}
+
+//PrimaryKey adds a PRIMARY KEY constraint on fields
+func (c *CreateTableBuilder) PrimaryKey(fields ...string) *CreateTableBuilder {
+	return c.addConstraint(fieldList("PRIMARY KEY", true, fields...))
+}
+
+//Unique adds a UNIQUE constraint on fields
+func (c *CreateTableBuilder) Unique(fields ...string) *CreateTableBuilder {
+	return c.addConstraint(fieldList("UNIQUE", true, fields...))
+}
+
+//Check adds a CHECK constraint with the specified condition
+func (c *CreateTableBuilder) Check(condition string) *CreateTableBuilder {
+	return c.addConstraint("CHECK (" + condition + ")")
+}
+
+//ForeignKey adds a FOREIGN KEY constraint on fields,
+//References MUST be called after ForeignKey.
+func (c *CreateTableBuilder) ForeignKey(fields ...string) *CreateTableBuilder {
+	return c.addConstraint(fieldList("FOREIGN KEY", true, fields...))
+}
+
+//References adds the referenced table and fields to the last
+//foreign key added
+func (c *CreateTableBuilder) References(table string, fields ...string) *CreateTableBuilder {
+	return c.appendLast(fieldList(" REFERENCES "+table, true, fields...))
+}
+
+//OnDelete sets the action taken on the last foreign key added
+//when a referenced row is deleted, e.g CASCADE or SET NULL
+func (c *CreateTableBuilder) OnDelete(action string) *CreateTableBuilder {
+	return c.appendLast(" ON DELETE " + action)
+}
+
+//OnUpdate sets the action taken on the last foreign key added
+//when a referenced row is updated
+func (c *CreateTableBuilder) OnUpdate(action string) *CreateTableBuilder {
+	return c.appendLast(" ON UPDATE " + action)
+}
+
+func (c *CreateTableBuilder) addConstraint(def string) *CreateTableBuilder {
+	if c.constraint != "" {
+		def = "CONSTRAINT " + c.constraint + " " + def
+		c.constraint = ""
+	}
+	c.defs = append(c.defs, def)
+	return c
+}
+
+func (c *CreateTableBuilder) appendLast(s string) *CreateTableBuilder {
+	if l := len(c.defs); l > 0 {
+		c.defs[l-1] += s
+	}
+	return c
+}
+
+//Clear erases the builder's query
+func (c *CreateTableBuilder) Clear() {
+	c.CreateTable("")
+}
+
+func (c *CreateTableBuilder) String() string {
+	qry := "CREATE TABLE "
+	if c.ifNotExists {
+		switch c.dialect {
+		case SQLServer:
+			qry = "IF OBJECT_ID(N'" + c.table + "',N'U') IS NULL " + qry
+		default:
+			qry += "IF NOT EXISTS "
+		}
+	}
+	return qry + c.table + " (" + strings.Join(c.defs, ",") + ");"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (c *CreateTableBuilder) Pretty() string {
+	return Format(c.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query
+func (c *CreateTableBuilder) Fingerprint() Fingerprint {
+	return FingerprintSQL(c.String())
+}
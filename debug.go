@@ -0,0 +1,502 @@
+package query
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+//DebugMarker starts every query rendered by Debug, whose values
+//are interpolated for reading and must never be executed.
+const DebugMarker = "/* DEBUG: values interpolated, not for execution */ "
+
+//Redacted replaces the values redacted by Debug
+const Redacted = "<redacted>"
+
+//RedactRule reports whether Debug should redact v, the value of a
+//placeholder compared with or assigned to column, or "" when the
+//column can't be told. Quoted literals inlined in the query are
+//checked too, with v the unquoted string.
+type RedactRule func(column string, v interface{}) bool
+
+//RedactColumns returns a RedactRule redacting the values of the
+//columns named names, which are matched case-insensitively and
+//regardless of any table qualifier or quotes.
+func RedactColumns(names ...string) RedactRule {
+	return func(column string, v interface{}) bool {
+		for _, name := range names {
+			if strings.EqualFold(column, name) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//RedactTypes returns a RedactRule redacting the values with
+//the same type as one of samples, e.g RedactTypes(Secret(""))
+func RedactTypes(samples ...interface{}) RedactRule {
+	types := make([]reflect.Type, len(samples))
+	for ix, s := range samples {
+		types[ix] = reflect.TypeOf(s)
+	}
+	return func(column string, v interface{}) bool {
+		t := reflect.TypeOf(v)
+		for _, typ := range types {
+			if t == typ {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//DebugOptions configures Debug, the zero value
+//redacts and truncates nothing.
+type DebugOptions struct {
+	//Redact lists the rules redacting values, a value
+	//is redacted if any of them reports it should be.
+	Redact []RedactRule
+	//MaxValueLen is the number of characters strings and
+	//byte slices are truncated to, 0 doesn't truncate them.
+	MaxValueLen int
+	//MaxListLen is the number of values lists such as IN(...)
+	//are truncated to, 0 doesn't truncate them.
+	MaxListLen int
+}
+
+//Debug renders sql, as returned by Bind, with the positional
+//placeholders $n, @pn and ? replaced by their value in args,
+//for logs and error messages. The result starts with DebugMarker,
+//redacted values are replaced by Redacted and truncated ones end
+//with the number of characters or values left out, e.g 'abc'…(+12),
+//and placeholders without a value are rendered as <missing>,
+//so it is never meant to be executed.
+//
+//Usage example:
+//	qry, args, _ := s.Bind(params)
+//	log.Println(query.Debug(qry, args, query.DebugOptions{
+//		Redact:     []query.RedactRule{query.RedactColumns("password", "ssn")},
+//		MaxListLen: 10,
+//	}))
+func Debug(sql string, args []interface{}, opts DebugOptions) string {
+	d := &debugger{sql: sql, args: args, opts: opts, toks: tokenize(sql), tuple: -1}
+	d.b.WriteString(DebugMarker)
+	for i := 0; i < len(d.toks); i++ {
+		i = d.token(i)
+	}
+	d.b.WriteString(sql[d.copied:])
+	return d.b.String()
+}
+
+type debugger struct {
+	sql    string
+	args   []interface{}
+	opts   DebugOptions
+	toks   []fmtToken
+	b      strings.Builder
+	copied int //offset of sql up to which b holds the result
+	next   int //index in args of the next ? placeholder
+
+	column  string   //column the next placeholder is compared with
+	columns []string //columns of an INSERT, or nil
+	tuple   int      //index in columns of the current VALUES item, or -1
+	depth   int      //parentheses open in a VALUES tuple
+}
+
+//token renders the i-th token, returning the index of the last token rendered
+func (d *debugger) token(i int) int {
+	tok := d.toks[i]
+	if ix, ok := placeholder(tok); ok {
+		if column, ok := d.columnAfter(i); ok {
+			//the placeholder comes first, as in $1=Password
+			d.column = column
+		}
+		d.replace(tok, tok, d.value(d.arg(ix)))
+		return i
+	}
+
+	switch tok.kind {
+	case fmtWord:
+		upper := strings.ToUpper(tok.text)
+		switch {
+		case upper == "INTO" || upper == "INSERT":
+			d.columns = nil
+			d.tuple = -1
+			return d.insertColumns(i)
+		case upper == "VALUES" && d.columns != nil:
+			d.depth = 0
+		case keywords[upper] || tok.text[0] >= '0' && tok.text[0] <= '9':
+		case i+1 < len(d.toks) && d.toks[i+1].text == "(" && !d.toks[i+1].space:
+			//a function
+		default:
+			d.column = tok.text
+		}
+	case fmtQuoted:
+		if tok.text[0] != '\'' && tok.text[0] != '$' {
+			d.column = tok.text
+			break
+		}
+		if column, ok := d.columnAfter(i); ok {
+			//the literal comes first, as in 'hunter2'=Password
+			d.column = column
+		} else if !d.compared(i) {
+			break
+		}
+		d.replace(tok, tok, d.literal(tok.text))
+	case fmtPunct:
+		switch tok.text {
+		case "(":
+			if d.columns == nil {
+				if end, ok := d.list(i); ok {
+					return end
+				}
+				break
+			}
+			if d.depth == 0 {
+				d.tuple = 0
+			}
+			d.depth++
+		case ")":
+			if d.depth > 0 {
+				d.depth--
+			}
+		case ",":
+			if d.depth == 1 {
+				d.tuple++
+			}
+		case ";":
+			d.columns = nil
+		}
+	}
+	return i
+}
+
+//columnAfter returns the column the i-th token, a placeholder,
+//is compared with when the column follows it, e.g $1=Password.
+func (d *debugger) columnAfter(i int) (string, bool) {
+	j := i + 1
+	for ; j < len(d.toks) && j <= i+2 && d.toks[j].kind == fmtPunct && strings.Contains("=<>!", d.toks[j].text); j++ {
+	}
+	if j == i+1 || j >= len(d.toks) {
+		return "", false
+	}
+	tok := d.toks[j]
+	switch tok.kind {
+	case fmtWord:
+		if _, ok := placeholder(tok); ok || keywords[strings.ToUpper(tok.text)] ||
+			tok.text[0] >= '0' && tok.text[0] <= '9' || tok.text[0] == ':' || tok.text[0] == '@' {
+			return "", false
+		}
+		if j+1 < len(d.toks) && d.toks[j+1].text == "(" && !d.toks[j+1].space {
+			return "", false
+		}
+		if strings.HasSuffix(tok.text, ".") && j+1 < len(d.toks) && d.toks[j+1].kind == fmtQuoted {
+			//a qualified quoted column, e.g pc."SSN"
+			return d.toks[j+1].text, true
+		}
+	case fmtQuoted:
+		if tok.text[0] == '\'' || tok.text[0] == '$' {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return tok.text, true
+}
+
+//compared reports whether the i-th token, a literal, is compared with
+//the column before it, as in Password='hunter2' or SSN IN('a','b'),
+//or is an item of a VALUES tuple.
+func (d *debugger) compared(i int) bool {
+	if d.columns != nil && d.tuple >= 0 && d.depth == 1 {
+		return true
+	}
+	j := i - 1
+	if j >= 0 && (d.toks[j].text == "," || d.toks[j].text == "(") {
+		//walk back to the parenthesis opening the list
+		for ; j >= 0 && d.toks[j].text != "("; j-- {
+			tok := d.toks[j]
+			if _, ok := placeholder(tok); !ok && tok.text != "," && tok.kind != fmtQuoted &&
+				(tok.kind != fmtWord || tok.text[0] < '0' || tok.text[0] > '9') {
+				return false
+			}
+		}
+		return j > 0 && strings.EqualFold(d.toks[j-1].text, "IN")
+	}
+	if j >= 0 && (strings.EqualFold(d.toks[j].text, "LIKE") || strings.EqualFold(d.toks[j].text, "ILIKE")) {
+		return true
+	}
+	return j >= 0 && d.toks[j].kind == fmtPunct && strings.Contains("=<>", d.toks[j].text)
+}
+
+//insertColumns collects the columns listed after INTO table, or after
+//INSERT in a MERGE, from the i-th token, returning the index of the last one.
+func (d *debugger) insertColumns(i int) int {
+	j := i + 1
+	if strings.EqualFold(d.toks[i].text, "INTO") {
+		j++
+	}
+	if j >= len(d.toks) || d.toks[j].text != "(" {
+		return i
+	}
+	var columns []string
+	for j++; j < len(d.toks); j += 2 {
+		columns = append(columns, d.toks[j].text)
+		if j+1 >= len(d.toks) || d.toks[j+1].text != "," {
+			break
+		}
+	}
+	if j+1 >= len(d.toks) || d.toks[j+1].text != ")" {
+		return i
+	}
+	d.columns = columns
+	d.tuple = -1
+	return j + 1
+}
+
+//list renders the list opened by the i-th token, a parenthesis,
+//if it has more than MaxListLen values, returning the index of
+//its closing parenthesis.
+func (d *debugger) list(i int) (int, bool) {
+	if d.opts.MaxListLen <= 0 {
+		return 0, false
+	}
+	var items []fmtToken
+	end := i + 1
+	for ; end < len(d.toks); end += 2 {
+		tok := d.toks[end]
+		if _, ok := placeholder(tok); !ok && tok.kind != fmtQuoted && (tok.kind != fmtWord || tok.text[0] < '0' || tok.text[0] > '9') {
+			return 0, false
+		}
+		items = append(items, tok)
+		if end+1 >= len(d.toks) || d.toks[end+1].text != "," {
+			break
+		}
+	}
+	end++
+	if end >= len(d.toks) || d.toks[end].text != ")" || len(items) <= d.opts.MaxListLen {
+		return 0, false
+	}
+
+	var b strings.Builder
+	for ix, item := range items {
+		ph, isPlaceholder := placeholder(item)
+		if isPlaceholder {
+			//? placeholders left out still consume their value
+			ph = d.arg(ph)
+		}
+		if ix >= d.opts.MaxListLen {
+			continue
+		}
+		if ix > 0 {
+			b.WriteByte(',')
+		}
+		switch {
+		case isPlaceholder:
+			b.WriteString(d.value(ph))
+		case item.kind == fmtQuoted:
+			b.WriteString(d.literal(item.text))
+		default:
+			b.WriteString(item.text)
+		}
+	}
+	b.WriteString(",…(+" + strconv.Itoa(len(items)-d.opts.MaxListLen) + ")")
+	d.replace(items[0], items[len(items)-1], b.String())
+	return end, true
+}
+
+//placeholder reports whether tok is a placeholder, along with the index
+//in args of its value, which is -1 for the ? placeholder.
+func placeholder(tok fmtToken) (int, bool) {
+	var digits string
+	switch {
+	case tok.kind == fmtPunct && tok.text == "?":
+		return -1, true
+	case tok.kind != fmtWord:
+		return 0, false
+	case strings.HasPrefix(tok.text, "$"):
+		digits = tok.text[1:]
+	case strings.HasPrefix(tok.text, "@p"):
+		digits = tok.text[2:]
+	default:
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+//arg returns the index in args of the value of ix, returned by placeholder
+func (d *debugger) arg(ix int) int {
+	if ix >= 0 {
+		return ix
+	}
+	d.next++
+	return d.next - 1
+}
+
+//replace writes sql up to from, then text in place of the tokens from to to
+func (d *debugger) replace(from, to fmtToken, text string) {
+	d.b.WriteString(d.sql[d.copied:from.pos])
+	d.b.WriteString(text)
+	d.copied = to.pos + len(to.text)
+}
+
+//value renders args[ix], redacting or truncating it as configured
+func (d *debugger) value(ix int) string {
+	if ix >= len(d.args) {
+		return "<missing>"
+	}
+	v := d.args[ix]
+	if d.redacts(v) {
+		return Redacted
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		if dv, err := valuer.Value(); err == nil {
+			v = dv
+		}
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		return d.array(rv)
+	}
+	return debugValue(v, d.opts.MaxValueLen)
+}
+
+//array renders rv, a slice bound as an array, truncated to MaxListLen values
+func (d *debugger) array(rv reflect.Value) string {
+	var b strings.Builder
+	b.WriteString("ARRAY[")
+	for ix := 0; ix < rv.Len(); ix++ {
+		if d.opts.MaxListLen > 0 && ix == d.opts.MaxListLen {
+			b.WriteString(",…(+" + strconv.Itoa(rv.Len()-ix) + ")")
+			break
+		}
+		if ix > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(debugValue(rv.Index(ix).Interface(), d.opts.MaxValueLen))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+//literal renders lit, a quoted literal inlined in sql,
+//redacting it as configured. The rules get its unquoted string.
+func (d *debugger) literal(lit string) string {
+	var v string
+	if lit[0] == '$' {
+		//a dollar-quoted string, e.g $tag$text$tag$
+		tag := lit[:strings.IndexByte(lit[1:], '$')+2]
+		v = strings.TrimSuffix(lit[len(tag):], tag)
+	} else {
+		v = strings.Replace(strings.TrimSuffix(lit[1:], "'"), "''", "'", -1)
+	}
+	if d.redacts(v) {
+		return Redacted
+	}
+	return lit
+}
+
+//redacts reports whether a rule redacts v, compared with or
+//assigned to the current column
+func (d *debugger) redacts(v interface{}) bool {
+	column := d.column
+	if d.columns != nil && d.tuple >= 0 && d.tuple < len(d.columns) && d.depth > 0 {
+		column = d.columns[d.tuple]
+	}
+	column = unquoteIdent(column)
+	for _, rule := range d.opts.Redact {
+		if rule(column, v) {
+			return true
+		}
+	}
+	return false
+}
+
+//debugValue renders v as a SQL literal, strings and
+//byte slices longer than max characters are truncated.
+func debugValue(v interface{}, max int) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quoteDebug(v, max)
+	case []byte:
+		if utf8.Valid(v) {
+			return quoteDebug(string(v), max)
+		}
+		if max > 0 && len(v) > max {
+			return "0x" + hex.EncodeToString(v[:max]) + "…(+" + strconv.Itoa(len(v)-max) + ")"
+		}
+		return "0x" + hex.EncodeToString(v)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	case *time.Time:
+		if v == nil {
+			return "NULL"
+		}
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return "NULL"
+		}
+		return debugValue(rv.Elem().Interface(), max)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.String:
+		return quoteDebug(rv.String(), max)
+	}
+	if s, ok := v.(stringer); ok {
+		return quoteDebug(s.String(), max)
+	}
+	return "<" + rv.Type().String() + ">"
+}
+
+//quoteDebug quotes s, truncated to max characters
+func quoteDebug(s string, max int) string {
+	suffix := ""
+	if n := utf8.RuneCountInString(s); max > 0 && n > max {
+		cut := 0
+		for i := 0; i < max; i++ {
+			_, size := utf8.DecodeRuneInString(s[cut:])
+			cut += size
+		}
+		s = s[:cut]
+		suffix = "…(+" + strconv.Itoa(n-max) + ")"
+	}
+	return "'" + strings.Replace(s, "'", "''", -1) + "'" + suffix
+}
+
+//unquoteIdent strips the table qualifier and quotes of column
+func unquoteIdent(column string) string {
+	if ix := strings.LastIndexByte(column, '.'); ix >= 0 {
+		column = column[ix+1:]
+	}
+	if len(column) >= 2 {
+		switch column[0] {
+		case '"', '`', '[':
+			column = column[1 : len(column)-1]
+		}
+	}
+	return column
+}
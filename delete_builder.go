@@ -1,8 +1,29 @@
 package query
 
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
 //DeleteBuilder is a builder for DELETE statements
 type DeleteBuilder struct {
-	query string
+	query   strings.Builder
+	dialect Dialect
+	// hasWhere is set once the query has a WHERE clause
+	hasWhere bool
+	guard    guard
+	idents   identifiers
+	// err is the first error of the query, reported by Err
+	err error
+	// lists are the conditions of WhereFieldIn, for Fingerprint
+	lists inLists
+	// table is the table deleted from, tableEnd is where it ends in query,
+	// whereAt and returningAt are where WHERE and RETURNING start, for Preview
+	table       string
+	tableEnd    int
+	whereAt     int
+	returningAt int
 }
 
 //NewDeleteBuilder returns a new *DeleteBuilder
@@ -10,52 +31,222 @@ func NewDeleteBuilder() *DeleteBuilder {
 	return new(DeleteBuilder)
 }
 
+//WithDialect sets the dialect the builder's query is rendered for,
+//it affects how Using and bound parameters are rendered.
+func (d *DeleteBuilder) WithDialect(dialect Dialect) *DeleteBuilder {
+	d.dialect = dialect
+	return d
+}
+
+//WithIdentMode sets how the table, column and alias names given
+//to the builder are rendered, they are added verbatim by default.
+func (d *DeleteBuilder) WithIdentMode(m IdentMode) *DeleteBuilder {
+	d.idents.mode = m
+	return d
+}
+
+//Err returns the first error since the query was started:
+//a name rejected by IdentStrict or ErrNamedList.
+func (d *DeleteBuilder) Err() error {
+	return d.err
+}
+
+//name renders name as set by the builder's IdentMode,
+//keeping the error of a name it rejects for Err
+func (d *DeleteBuilder) name(name string) string {
+	rendered, err := d.idents.name(d.dialect, name)
+	d.fail(err)
+	return rendered
+}
+
+//names renders each of names like name
+func (d *DeleteBuilder) names(names []string) []string {
+	rendered, err := d.idents.names(d.dialect, names)
+	d.fail(err)
+	return rendered
+}
+
+//fail keeps err for Err, unless the query already failed
+func (d *DeleteBuilder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
 //Delete adds a DELETE statment to the builder's query
 //table is the database table to delete from
 func (d *DeleteBuilder) Delete(table string) *DeleteBuilder {
-	d.query = "DELETE FROM " + table
+	d.Clear()
+	d.table = d.name(table)
+	d.query.WriteString("DELETE FROM ")
+	d.query.WriteString(d.table)
+	d.tableEnd = d.query.Len()
+	return d
+}
+
+//Using adds other tables whose columns may be used in Where,
+//to the builder's query. It renders DELETE FROM a USING b on Postgres and MySQL,
+//and DELETE FROM a FROM b on SQL Server.
+//
+//MySQL and SQL Server expect the table deleted from to be
+//repeated in tables: DELETE FROM a USING a JOIN b ON ...
+func (d *DeleteBuilder) Using(tables ...string) *DeleteBuilder {
+	tables = d.names(tables)
+	if d.dialect == SQLServer {
+		addFields(&d.query, " FROM", false, tables...)
+		return d
+	}
+	addFields(&d.query, " USING", false, tables...)
+	return d
+}
+
+//Join adds a JOIN clause to the builder's query,
+//Using MUST be called prior to Join.
+func (d *DeleteBuilder) Join(table string) *DeleteBuilder {
+	d.query.WriteString(" JOIN ")
+	d.query.WriteString(d.name(table))
+	return d
+}
+
+//As sets an alias for the last table added
+func (d *DeleteBuilder) As(alias string) *DeleteBuilder {
+	d.query.WriteString(" AS ")
+	d.query.WriteString(d.name(alias))
+	return d
+}
+
+//On adds the matching colmuns in joined tables.
+func (d *DeleteBuilder) On(column1 string, column2 string) *DeleteBuilder {
+	d.query.WriteString(" ON " + d.name(column1) + "=" + d.name(column2))
 	return d
 }
 
 //Where adds a WHERE clause to u's query.
 //condition is the desired condition
+//Once there's a WHERE clause, condition is added along with an AND,
+//the clause and condition being parenthesized if they have an OR.
 func (d *DeleteBuilder) Where(condition string) *DeleteBuilder {
-	d.query += where(condition)
+	if d.hasWhere {
+		if andWhere(&d.query, d.whereAt, condition) {
+			d.lists.parenthesized(d.whereAt)
+		}
+		return d
+	}
+	d.markWhere()
+	where(&d.query, condition)
 	return d
 }
 
+func (d *DeleteBuilder) markWhere() {
+	if !d.hasWhere {
+		d.whereAt = d.query.Len()
+		d.hasWhere = true
+	}
+}
+
 //WhereWithMap adds a WHERE clause to u's query with fields and conditions
 //derived from ixToCond which should map integers(allows for proper ordering)
 //to conditions desired to be met.
 //You should use consecutive integers starting from zero.
 func (d *DeleteBuilder) WhereWithMap(ixToCond map[int]interface{}) *DeleteBuilder {
-	d.query += withMap("WHERE", ixToCond, false)
+	if len(ixToCond) == 0 {
+		return d
+	}
+	if d.hasWhere {
+		return d.Where(mapCondition(ixToCond))
+	}
+	d.markWhere()
+	withMap(&d.query, "WHERE", ixToCond, false)
 	return d
 }
 
-//WhereFieldIn adds a WHERE clause along with an IN operator
+//WhereFieldIn adds a WHERE clause along with an IN operator,
+//large lists are rendered with the strategy InAuto picks for the dialect.
 func (d *DeleteBuilder) WhereFieldIn(field string, values ...interface{}) *DeleteBuilder {
-	d.query += whereIn(field, values...)
+	return d.WhereFieldInWith(InAuto, field, values...)
+}
+
+//WhereFieldInWith adds a WHERE clause matching field against values
+//with the specified strategy.
+//
+//Usage example:
+//	WhereFieldInWith(InArray, "ProductID", Named("ids")).Bind(map[string]interface{}{"ids": ids})
+func (d *DeleteBuilder) WhereFieldInWith(strategy InStrategy, field string, values ...interface{}) *DeleteBuilder {
+	if values == nil {
+		return d
+	}
+	if err := strategy.check(d.dialect, values); err != nil {
+		d.fail(err)
+		return d
+	}
+	keyword := conditionKeyword(d.hasWhere)
+	if d.hasWhere {
+		if parenthesizeWhere(&d.query, d.whereAt) {
+			d.lists.parenthesized(d.whereAt)
+		}
+	}
+	d.markWhere()
+	d.lists = append(d.lists, whereInWith(&d.query, keyword, d.dialect, strategy, field, values...))
 	return d
 }
 
 //Returning returns the specified field values
 func (d *DeleteBuilder) Returning(fields ...string) *DeleteBuilder {
-	d.query += " RETURNING" + addFields("", false, fields...)
+	d.markReturning()
+	d.query.WriteString(" RETURNING")
+	addFields(&d.query, "", false, d.names(fields)...)
 	return d
 }
 
 //ReturningAll returns all fields
 func (d *DeleteBuilder) ReturningAll() *DeleteBuilder {
-	d.query += " RETURNING *"
+	d.markReturning()
+	d.query.WriteString(" RETURNING *")
 	return d
 }
 
+func (d *DeleteBuilder) markReturning() {
+	if d.returningAt == 0 {
+		d.returningAt = d.query.Len()
+	}
+}
+
+//Preview returns a *SelectBuilder selecting the rows the builder's query
+//deletes, from the same tables and with the same WHERE clause.
+//
+//On MySQL and SQL Server the table deleted from is expected to be
+//repeated in Using, as documented there.
+func (d *DeleteBuilder) Preview() *SelectBuilder {
+	return d.preview(false)
+}
+
+//PreviewCount is like Preview, selecting the number of rows deleted
+//with COUNT(*) instead.
+func (d *DeleteBuilder) PreviewCount() *SelectBuilder {
+	return d.preview(true)
+}
+
+func (d *DeleteBuilder) preview(count bool) *SelectBuilder {
+	qry := d.query.String()
+	end := len(qry)
+	if d.returningAt > 0 {
+		end = d.returningAt
+	}
+	whereAt := end
+	if d.hasWhere {
+		whereAt = d.whereAt
+	}
+
+	sources := qry[d.tableEnd:whereAt]
+	from := previewFrom(d.table, sources, d.dialect != Postgres)
+	return preview(d.dialect, previewColumns(d.table, sources, count), from, qry[whereAt:end])
+}
+
 //And is an alternative to WhereWithMap
 //
 //it adds an AND along with the condition specified
 func (d *DeleteBuilder) And(condition string) *DeleteBuilder {
-	d.query += and(condition)
+	and(&d.query, condition)
 	return d
 }
 
@@ -63,15 +254,172 @@ func (d *DeleteBuilder) And(condition string) *DeleteBuilder {
 //
 //it adds an OR along with the condition specified
 func (d *DeleteBuilder) Or(condition string) *DeleteBuilder {
-	d.query += or(condition)
+	or(&d.query, condition)
 	return d
 }
 
-//Clear erases the builder's query
+//WhereIf adds condition like Where, only if cond is true
+func (d *DeleteBuilder) WhereIf(cond bool, condition string) *DeleteBuilder {
+	if cond {
+		d.Where(condition)
+	}
+	return d
+}
+
+//AndIf adds condition along with an AND, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (d *DeleteBuilder) AndIf(cond bool, condition string) *DeleteBuilder {
+	if cond {
+		d.Where(condition)
+	}
+	return d
+}
+
+//OrIf adds condition along with an OR, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (d *DeleteBuilder) OrIf(cond bool, condition string) *DeleteBuilder {
+	if !cond {
+		return d
+	}
+	if d.hasWhere {
+		or(&d.query, condition)
+		return d
+	}
+	return d.Where(condition)
+}
+
+//WhereNonZero adds the condition built by op on field and v like Where,
+//only if v isn't a zero value. Non-nil pointers are never skipped.
+//
+//Usage example:
+//	WhereNonZero(Eq, "FirstName", req.FirstName).WhereNonZero(G, "TotalAmountDue", req.MinAmount)
+func (d *DeleteBuilder) WhereNonZero(op Operator, field string, v interface{}) *DeleteBuilder {
+	if !isZero(v) {
+		d.Where(op(field, v))
+	}
+	return d
+}
+
+//AllRows allows the query to have no WHERE clause, so that it deletes
+//every row of the table. Without it, ToSQL, Bind, Exec and the Template
+//of Compile return ErrNoWhere.
+//It MUST be called after Delete, which starts a new query with it unset,
+//so that reusing the builder never affects every row by accident.
+func (d *DeleteBuilder) AllRows() *DeleteBuilder {
+	d.guard.allRows = true
+	return d
+}
+
+//MaxAffected makes Exec run the query in a transaction, which is rolled
+//back with a *MaxAffectedError if more than n rows were affected. Given
+//a *sql.Tx, Exec rolls back to a savepoint taken before the query instead.
+func (d *DeleteBuilder) MaxAffected(n int64) *DeleteBuilder {
+	d.guard.maxAffected = n
+	return d
+}
+
+//ToSQL returns the builder's query, or ErrNoWhere if it has no WHERE clause
+//and AllRows wasn't called. Names rejected by IdentStrict are reported too.
+func (d *DeleteBuilder) ToSQL() (string, error) {
+	if err := d.check(); err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
+
+//Exec binds params to the builder's query like Bind and runs it on ex,
+//a *sql.DB, *sql.Conn or *sql.Tx, checking the number of affected rows
+//if MaxAffected was called. The hooks registered by AddHook are called around it.
+func (d *DeleteBuilder) Exec(ctx context.Context, ex Executor, params map[string]interface{}) (sql.Result, error) {
+	return NewRunner(ex).Exec(ctx, d, params)
+}
+
+func (d *DeleteBuilder) guarded() (guard, Dialect) {
+	return d.guard, d.dialect
+}
+
+func (d *DeleteBuilder) check() error {
+	if d.err != nil {
+		return d.err
+	}
+	return d.guard.check(d.hasWhere)
+}
+
+//Comment appends the sqlcommenter comment of ctx to the query: the tags
+//set by WithTag and AddCommentSource, and the file:line Comment is called
+//from, e.g /*caller='orders%2Fstore.go%3A42',route='%2Forders'*/.
+//It should be called once the query is complete.
+func (d *DeleteBuilder) Comment(ctx context.Context) *DeleteBuilder {
+	d.query.WriteByte(' ')
+	d.query.WriteString(comment(ctx))
+	return d
+}
+
+//Bind renders the builder's query with named parameters replaced by the
+//dialect's positional placeholders, returning the values of params in
+//the same order. Names without a value and values not used by any name
+//are reported by a *BindError.
+func (d *DeleteBuilder) Bind(params map[string]interface{}) (string, []interface{}, error) {
+	if err := d.check(); err != nil {
+		return "", nil, err
+	}
+	return bindMap(d.sql(), d.dialect, params)
+}
+
+//BindStruct is like Bind, taking values from the fields of v, a struct
+//or a pointer to one. Fields are named after their db tag if they have one.
+func (d *DeleteBuilder) BindStruct(v interface{}) (string, []interface{}, error) {
+	if err := d.check(); err != nil {
+		return "", nil, err
+	}
+	return bindStruct(d.sql(), d.dialect, v)
+}
+
+//Debug binds params like Bind, then renders the query with the values
+//interpolated by Debug, for logs. It must never be executed.
+func (d *DeleteBuilder) Debug(params map[string]interface{}, opts DebugOptions) (string, error) {
+	qry, args, err := d.Bind(params)
+	if err != nil {
+		return "", err
+	}
+	return Debug(qry, args, opts), nil
+}
+
+//Compile renders the builder's query into a Template, whose named
+//parameters are bound to new values on each execution. The errors
+//ToSQL would return are returned by the Template's Args, ArgsStruct and Stmt.
+func (d *DeleteBuilder) Compile() *Template {
+	t := compile(d.sql(), d.dialect)
+	if err := d.check(); err != nil {
+		t.err = err
+	}
+	return t
+}
+
+//Clear erases the builder's query, keeping its dialect, IdentMode and
+//the setting of MaxAffected. AllRows is reset, it only applies to one query.
 func (d *DeleteBuilder) Clear() {
-	d.query = ""
+	*d = DeleteBuilder{dialect: d.dialect, idents: identifiers{mode: d.idents.mode}, guard: guard{maxAffected: d.guard.maxAffected}}
 }
 
+//String returns the builder's query, with the values of an Expr rendered as ?
 func (d *DeleteBuilder) String() string {
-	return d.query + ";"
+	return exprPlaceholders(d.sql())
+}
+
+//sql returns the builder's query, as bound by Bind
+func (d *DeleteBuilder) sql() string {
+	return d.query.String() + ";"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (d *DeleteBuilder) Pretty() string {
+	return Format(d.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query, whose
+//WhereFieldIn conditions have the same one whatever their InStrategy.
+func (d *DeleteBuilder) Fingerprint() Fingerprint {
+	return fingerprint(d.sql(), d.lists)
 }
@@ -0,0 +1,36 @@
+package query
+
+//Dialect identifies the SQL flavour a builder renders for.
+//
+//The zero value is Postgres, which is the syntax every builder
+//renders when no dialect is set.
+type Dialect int
+
+const (
+	//Postgres renders PostgreSQL syntax
+	Postgres Dialect = iota
+	//MySQL renders MySQL syntax
+	MySQL
+	//SQLServer renders Microsoft SQL Server syntax
+	SQLServer
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case MySQL:
+		return "mysql"
+	case SQLServer:
+		return "sqlserver"
+	}
+	return "postgres"
+}
+
+//UnsupportedError reports a clause a builder can't render for Dialect
+type UnsupportedError struct {
+	Dialect Dialect
+	Clause  string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "query: " + e.Clause + " is not supported on " + e.Dialect.String()
+}
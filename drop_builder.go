@@ -0,0 +1,103 @@
+package query
+
+//DropBuilder is a builder for DROP TABLE and DROP INDEX statements
+type DropBuilder struct {
+	dialect  Dialect
+	kind     string
+	names    []string
+	table    string
+	ifExists bool
+	cascade  bool
+}
+
+//NewDropBuilder returns a new *DropBuilder
+func NewDropBuilder() *DropBuilder {
+	return new(DropBuilder)
+}
+
+//WithDialect sets the dialect the builder's query is rendered for
+func (d *DropBuilder) WithDialect(dialect Dialect) *DropBuilder {
+	d.dialect = dialect
+	return d
+}
+
+//DropTable starts a DROP TABLE statement for tables
+func (d *DropBuilder) DropTable(tables ...string) *DropBuilder {
+	*d = DropBuilder{dialect: d.dialect, kind: "TABLE", names: tables}
+	return d
+}
+
+//DropIndex starts a DROP INDEX statement for the index named name
+func (d *DropBuilder) DropIndex(name string) *DropBuilder {
+	*d = DropBuilder{dialect: d.dialect, kind: "INDEX", names: []string{name}}
+	return d
+}
+
+//On sets the table the dropped index belongs to,
+//it is required by MySQL and SQL Server and ignored by Postgres.
+func (d *DropBuilder) On(table string) *DropBuilder {
+	d.table = table
+	return d
+}
+
+//IfExists only drops what exists.
+//MySQL can't drop an index only if it exists, Err reports it with an *UnsupportedError.
+func (d *DropBuilder) IfExists() *DropBuilder {
+	d.ifExists = true
+	return d
+}
+
+//Cascade also drops objects depending on what is dropped,
+//SQL Server has no such clause, so it is ignored there.
+//MySQL has none for indexes, Err reports it with an *UnsupportedError.
+func (d *DropBuilder) Cascade() *DropBuilder {
+	d.cascade = true
+	return d
+}
+
+//Err returns an *UnsupportedError for IfExists or Cascade
+//on a MySQL DROP INDEX
+func (d *DropBuilder) Err() error {
+	if d.kind != "INDEX" || d.dialect != MySQL {
+		return nil
+	}
+	if d.ifExists {
+		return &UnsupportedError{Dialect: d.dialect, Clause: "DROP INDEX IF EXISTS"}
+	}
+	if d.cascade {
+		return &UnsupportedError{Dialect: d.dialect, Clause: "DROP INDEX ... CASCADE"}
+	}
+	return nil
+}
+
+//Clear erases the builder's query
+func (d *DropBuilder) Clear() {
+	*d = DropBuilder{dialect: d.dialect}
+}
+
+func (d *DropBuilder) String() string {
+	qry := "DROP " + d.kind
+	mysqlIndex := d.kind == "INDEX" && d.dialect == MySQL
+	if d.ifExists && !mysqlIndex {
+		qry += " IF EXISTS"
+	}
+	qry += fieldList("", false, d.names...)
+	if d.kind == "INDEX" && d.table != "" && d.dialect != Postgres {
+		qry += " ON " + d.table
+	}
+	if d.cascade && d.dialect != SQLServer && !mysqlIndex {
+		qry += " CASCADE"
+	}
+	return qry + ";"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (d *DropBuilder) Pretty() string {
+	return Format(d.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query
+func (d *DropBuilder) Fingerprint() Fingerprint {
+	return FingerprintSQL(d.String())
+}
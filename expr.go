@@ -0,0 +1,284 @@
+package query
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Expression is a raw SQL expression, which operators and builders
+//render as it is instead of quoting it like a string value.
+type Expression struct {
+	sql  string
+	args []interface{}
+}
+
+//Expr returns an Expression made of sql, where each ? is replaced by
+//a parameter bound to the next value of args, so that Bind and BindStruct
+//render it as the dialect's next positional placeholder, with the value
+//among the arguments they return; values are never written into the query.
+//A Named value stays a parameter, bound by Bind or BindStruct in the
+//order it appears in the query. ?? renders a literal ?, and question
+//marks inside quotes are left alone.
+//
+//Values may be of the types database/sql accepts: nil, strings, byte slices,
+//booleans, numbers, time.Time and driver.Valuer values, or pointers to them,
+//and are bound with their own type. Other values, and Valuers failing, are
+//reported by an *ExprError from Bind, BindStruct and Compile's Template.
+//String and Pretty render the values as ?.
+//
+//Usage example:
+//	Set(Eq("Quantity", Expr("Quantity+?", 1)))      // Quantity=Quantity+$1
+//	Where(G("DueDate", Expr("now()-interval ?", "7 days")))
+//	Where(Eq("StoreID", Expr("coalesce(?,0)", Named("store"))))
+func Expr(sql string, args ...interface{}) Expression {
+	return Expression{sql: sql, args: args}
+}
+
+func (e Expression) String() string {
+	if len(e.args) == 0 && !strings.Contains(e.sql, "??") {
+		return e.sql
+	}
+
+	var b strings.Builder
+	next, copied := 0, 0
+	toks := tokenize(e.sql)
+	for ix := 0; ix < len(toks); ix++ {
+		tok := toks[ix]
+		if tok.kind != fmtPunct || tok.text != "?" {
+			continue
+		}
+		b.WriteString(e.sql[copied:tok.pos])
+		copied = tok.pos + 1
+		switch {
+		case ix+1 < len(toks) && toks[ix+1].text == "?" && toks[ix+1].pos == tok.pos+1:
+			b.WriteByte('?')
+			copied++
+			ix++
+		case next < len(e.args):
+			b.WriteString(exprParam(e.args[next]))
+			next++
+		default:
+			b.WriteByte('?')
+		}
+	}
+	b.WriteString(e.sql[copied:])
+	return b.String()
+}
+
+//exprPrefix starts the names of the parameters an Expression renders
+//its values as, names which hold the value itself: exprPrefix, its type,
+//an underscore, then its text in hexadecimal. Builders render them as ?
+//in String and Pretty, Bind binds them.
+const exprPrefix = "_expr_"
+
+//exprArray is the type of the parameters holding a list of values
+//bound as a slice, the names of their parameters separated by commas.
+const exprArray = "a"
+
+//exprFailed is the type of the parameters holding the error of a
+//value that can't be bound, which Bind and Compile report.
+const exprFailed = "x"
+
+//ExprError reports a value of an Expression which can't be bound,
+//because database/sql doesn't support its type or its Value method failed.
+type ExprError struct {
+	Reason string
+}
+
+func (e *ExprError) Error() string {
+	return "query: Expr value " + e.Reason
+}
+
+//exprParam renders v, a value of an Expression, as a parameter
+func exprParam(v interface{}) string {
+	switch v := v.(type) {
+	case NamedParam:
+		return v.String()
+	case Expression:
+		return v.String()
+	case driver.Valuer:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return exprName("n", "")
+		}
+		dv, err := v.Value()
+		if err != nil {
+			return exprName(exprFailed, "of type "+reflect.TypeOf(v).String()+": "+err.Error())
+		}
+		return exprParam(dv)
+	case time.Time:
+		return exprName("d", v.Format(time.RFC3339Nano))
+	case []byte:
+		if v == nil {
+			return exprName("n", "")
+		}
+		return exprName("b", string(v))
+	case nil:
+		return exprName("n", "")
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return exprName("n", "")
+		}
+		return exprParam(rv.Elem().Interface())
+	case reflect.String:
+		return exprName("s", rv.String())
+	case reflect.Bool:
+		return exprName("t", strconv.FormatBool(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return exprName("i"+exprBits(rv), strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return exprName("u"+exprBits(rv), strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return exprName("f"+exprBits(rv), strconv.FormatFloat(rv.Float(), 'g', -1, rv.Type().Bits()))
+	}
+	return exprName(exprFailed, "of unsupported type "+rv.Type().String())
+}
+
+//arrayParam renders values, literals of a single type as reported by
+//arrayType, as one parameter bound to a slice of their type
+func arrayParam(values []interface{}) string {
+	items := make([]string, len(values))
+	for ix, v := range values {
+		items[ix] = exprParam(v)[1:]
+	}
+	return exprName(exprArray, strings.Join(items, ","))
+}
+
+//exprBits returns the size of the number rv, or "" for int and uint
+func exprBits(rv reflect.Value) string {
+	if rv.Kind() == reflect.Int || rv.Kind() == reflect.Uint {
+		return ""
+	}
+	return strconv.Itoa(rv.Type().Bits())
+}
+
+//exprName returns the parameter holding text, a value of type typ
+func exprName(typ, text string) string {
+	return ":" + exprPrefix + typ + "_" + hex.EncodeToString([]byte(text))
+}
+
+//exprValue returns the value held by name, if it is the name of a
+//parameter rendered by exprParam, along with the error it holds.
+func exprValue(name string) (interface{}, bool, error) {
+	if !strings.HasPrefix(name, exprPrefix) {
+		return nil, false, nil
+	}
+	sep := strings.IndexByte(name[len(exprPrefix):], '_')
+	if sep < 0 {
+		return nil, false, nil
+	}
+	typ := name[len(exprPrefix) : len(exprPrefix)+sep]
+	raw, err := hex.DecodeString(name[len(exprPrefix)+sep+1:])
+	if err != nil {
+		return nil, false, nil
+	}
+
+	text := string(raw)
+	var v interface{}
+	switch {
+	case typ == exprFailed:
+		return nil, true, &ExprError{Reason: text}
+	case typ == "n":
+		return nil, true, nil
+	case typ == exprArray:
+		return exprSlice(strings.Split(text, ","))
+	case typ == "s":
+		return text, true, nil
+	case typ == "b":
+		return raw, true, nil
+	case typ == "t":
+		v, err = strconv.ParseBool(text)
+	case typ == "d":
+		v, err = time.Parse(time.RFC3339Nano, text)
+	case typ[0] == 'i':
+		var n int64
+		n, err = strconv.ParseInt(text, 10, 64)
+		v = map[string]interface{}{"": int(n), "8": int8(n), "16": int16(n), "32": int32(n), "64": n}[typ[1:]]
+	case typ[0] == 'u':
+		var n uint64
+		n, err = strconv.ParseUint(text, 10, 64)
+		v = map[string]interface{}{"": uint(n), "8": uint8(n), "16": uint16(n), "32": uint32(n), "64": n}[typ[1:]]
+	case typ == "f32":
+		var f float64
+		f, err = strconv.ParseFloat(text, 32)
+		v = float32(f)
+	case typ == "f64":
+		v, err = strconv.ParseFloat(text, 64)
+	default:
+		return nil, false, nil
+	}
+	return v, err == nil && v != nil, nil
+}
+
+//exprSlice returns the slice held by the parameters named names,
+//whose values have a single type
+func exprSlice(names []string) (interface{}, bool, error) {
+	var slice reflect.Value
+	for ix, name := range names {
+		v, ok, err := exprValue(name)
+		if !ok || err != nil || v == nil {
+			return nil, false, err
+		}
+		if ix == 0 {
+			slice = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(v)), len(names), len(names))
+		}
+		slice.Index(ix).Set(reflect.ValueOf(v))
+	}
+	return slice.Interface(), true, nil
+}
+
+//withExprValues returns lookup, looking up the values held by the names
+//of Expression parameters first, the first error one holds is set to *err.
+func withExprValues(lookup func(name string) (interface{}, bool), err *error) func(name string) (interface{}, bool) {
+	return func(name string) (interface{}, bool) {
+		v, ok, e := exprValue(name)
+		if e != nil && *err == nil {
+			*err = e
+		}
+		if ok {
+			return v, true
+		}
+		return lookup(name)
+	}
+}
+
+//exprPlaceholders returns qry with the Expression parameters rendered as ?,
+//as they were written, for String and Pretty.
+func exprPlaceholders(qry string) string {
+	if !strings.Contains(qry, ":"+exprPrefix) {
+		return qry
+	}
+	var b strings.Builder
+	copied := 0
+	for _, tok := range tokenize(qry) {
+		if tok.kind == fmtWord && strings.HasPrefix(tok.text, ":"+exprPrefix) {
+			b.WriteString(qry[copied:tok.pos])
+			b.WriteByte('?')
+			copied = tok.pos + len(tok.text)
+		}
+	}
+	b.WriteString(qry[copied:])
+	return b.String()
+}
+
+//exprErr returns the first error held by the Expression parameters of qry
+func exprErr(qry string) error {
+	if !strings.Contains(qry, ":"+exprPrefix+exprFailed+"_") {
+		return nil
+	}
+	for _, tok := range tokenize(qry) {
+		if tok.kind == fmtWord && strings.HasPrefix(tok.text, ":"+exprPrefix) {
+			if _, _, err := exprValue(tok.text[1:]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
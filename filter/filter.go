@@ -0,0 +1,265 @@
+// Package filter compiles filters sent by API clients into conditions
+// for the builders of package query.
+//
+// Filters are written in an OData $filter-like language:
+//
+//	status eq 'open' and (amount gt 100 or priority in (1,2))
+//
+// Only the fields of a Schema may be filtered on, and their values must
+// match the field's type. Values are never rendered into the condition,
+// they are bound to named parameters instead:
+//
+//	cond, err := filter.Parse(req.Filter, schema)
+//	if err != nil {
+//		return err // e.g filter: unknown field "password" at column 1
+//	}
+//	sql, args, err := query.NewSelectBuilder().SelectAll("Sales.OrderHeader").
+//		WhereIf(cond.SQL != "", cond.SQL).Bind(cond.Params)
+//
+// Mongo-style JSON filters are compiled the same way by ParseJSON:
+//
+//	{"status": "open", "$or": [{"amount": {"$gt": 100}}, {"priority": {"$in": [1, 2]}}]}
+//
+// Conditions used in the same query need parameters named apart,
+// which WithPrefix does, and their Params merged:
+//
+//	a, b := q.WithPrefix("q"), j.WithPrefix("j")
+//	s.Where(a.SQL).Where(b.SQL).Bind(a.Merge(b))
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danvixent/query"
+)
+
+// MaxDepth is the maximum nesting of parentheses and not operators in a filter
+const MaxDepth = 32
+
+// Type is the type of a field's values, each is coerced to a Go type
+type Type int
+
+const (
+	// String values are strings, written between single quotes
+	String Type = iota
+	// Int values are int64
+	Int
+	// Float values are float64, integers are accepted
+	Float
+	// Bool values are true or false
+	Bool
+	// Time values are time.Time, written between single quotes
+	// in RFC 3339 format or as a 2006-01-02 date
+	Time
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int:
+		return "int"
+	case Float:
+		return "float"
+	case Bool:
+		return "bool"
+	case Time:
+		return "time"
+	}
+	return "string"
+}
+
+// Field is a field clients may filter on
+type Field struct {
+	// Column is the column the field is rendered as, e.g soh.Status
+	Column string
+	Type   Type
+}
+
+// Schema maps the names clients use in filters to their Field
+type Schema map[string]Field
+
+// Condition is a compiled filter
+type Condition struct {
+	// SQL is the condition, to be given to a builder's Where or And.
+	// It is parenthesized when it has a top-level OR, and empty
+	// for an empty filter.
+	SQL string
+	// Params are the values of the named parameters used in SQL,
+	// to be given to the builder's Bind. They are named f1, f2...
+	// unless the Condition was renamed by WithPrefix.
+	Params map[string]interface{}
+
+	// n is the parsed filter, nil for an empty one
+	n node
+}
+
+func (c *Condition) String() string {
+	return c.SQL
+}
+
+// WithPrefix returns c with its parameters named prefix1, prefix2...
+// so that it can be used in the same query as other Conditions, whose
+// parameters are otherwise named alike. prefix must start with a letter
+// or an underscore, and be followed by letters, digits and underscores.
+func (c *Condition) WithPrefix(prefix string) *Condition {
+	return render(c.n, prefix)
+}
+
+// Merge returns the Params of c and others in a single map, for the Bind of
+// a query using all of them. Their parameters must be named apart by WithPrefix.
+func (c *Condition) Merge(others ...*Condition) map[string]interface{} {
+	params := make(map[string]interface{}, len(c.Params))
+	for _, cond := range append([]*Condition{c}, others...) {
+		for name, v := range cond.Params {
+			params[name] = v
+		}
+	}
+	return params
+}
+
+// Error is a filter rejected by Parse, Pos is the byte offset
+// in the filter where the problem was found.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter: %s at column %d", e.Msg, e.Pos+1)
+}
+
+// Parse compiles src into a Condition on the fields of schema.
+// An empty src yields a Condition with an empty SQL and no Params.
+func Parse(src string, schema Schema) (*Condition, error) {
+	if strings.TrimSpace(src) == "" {
+		return render(nil, "f"), nil
+	}
+	p := &parser{lex: lexer{src: src}, schema: schema}
+	p.next()
+	n, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	return render(n, "f"), nil
+}
+
+// node is a parsed filter
+type node interface{}
+
+// logical is an AND or an OR of two filters
+type logical struct {
+	op          string
+	left, right node
+}
+
+// negation is a negated filter
+type negation struct {
+	n node
+}
+
+// comparison compares a column to values
+type comparison struct {
+	column string
+	op     string
+	values []interface{}
+}
+
+// render renders n into a Condition whose parameters are named after prefix
+func render(n node, prefix string) *Condition {
+	r := renderer{prefix: prefix, params: map[string]interface{}{}}
+	// the condition is ANDed with others by the builders
+	return &Condition{SQL: r.render(n, "AND"), Params: r.params, n: n}
+}
+
+type renderer struct {
+	prefix string
+	params map[string]interface{}
+}
+
+// render renders n, a child of an operator parent
+func (r *renderer) render(n node, parent string) string {
+	switch n := n.(type) {
+	case *logical:
+		sql := r.render(n.left, n.op) + " " + n.op + " " + r.render(n.right, n.op)
+		if parent == "NOT" || parent == "AND" && n.op == "OR" {
+			return "(" + sql + ")"
+		}
+		return sql
+	case *negation:
+		return "NOT " + r.render(n.n, "NOT")
+	case *comparison:
+		return r.comparison(n, parent == "NOT")
+	}
+	return ""
+}
+
+func (r *renderer) comparison(c *comparison, negated bool) string {
+	var sql string
+	switch c.op {
+	case "IS NULL":
+		sql = query.IsNull(c.column)
+	case "IS NOT NULL":
+		sql = query.IsNotNull(c.column)
+	case "IN":
+		params := make([]interface{}, len(c.values))
+		for ix, v := range c.values {
+			params[ix] = r.param(v)
+		}
+		sql = query.In(c.column, params...)
+	default:
+		op := map[string]query.Operator{
+			"=": query.Eq, "!=": query.NEq, ">": query.G, ">=": query.GEq, "<": query.L, "<=": query.LEq,
+		}[c.op]
+		sql = op(c.column, r.param(c.values[0]))
+	}
+	if negated {
+		return "(" + sql + ")"
+	}
+	return sql
+}
+
+// param binds v to a new named parameter
+func (r *renderer) param(v interface{}) query.NamedParam {
+	name := r.prefix + strconv.Itoa(len(r.params)+1)
+	r.params[name] = v
+	return query.Named(name)
+}
+
+// coerce converts the literal of tok to a value of field's type
+func coerce(tok token, t Type) (interface{}, bool) {
+	switch {
+	case t == String && tok.kind == tokString:
+		return tok.text, true
+	case t == Int && tok.kind == tokNumber:
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		return v, err == nil
+	case t == Float && tok.kind == tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		return v, err == nil
+	case t == Bool && tok.kind == tokIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		}
+	case t == Time && tok.kind == tokString:
+		return parseTime(tok.text)
+	}
+	return nil, false
+}
+
+// parseTime parses s as a Time value
+func parseTime(s string) (interface{}, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if v, err := time.Parse(layout, s); err == nil {
+			return v, true
+		}
+	}
+	return nil, false
+}
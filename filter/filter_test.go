@@ -0,0 +1,136 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/danvixent/query"
+)
+
+var schema = Schema{
+	"status":   {Column: "soh.Status", Type: String},
+	"amount":   {Column: "soh.TotalAmountDue", Type: Float},
+	"priority": {Column: "soh.Priority", Type: Int},
+	"paid":     {Column: "soh.Paid", Type: Bool},
+	"due":      {Column: "soh.DueDate", Type: Time},
+}
+
+func TestParse(t *testing.T) {
+	due := time.Date(2020, 10, 11, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		src        string
+		wantSQL    string
+		wantParams map[string]interface{}
+	}{
+		{
+			"filter1",
+			"status eq 'open' and (amount gt 100 or priority in (1,2))",
+			"soh.Status=:f1 AND (soh.TotalAmountDue>:f2 OR soh.Priority IN(:f3,:f4))",
+			map[string]interface{}{"f1": "open", "f2": 100.0, "f3": int64(1), "f4": int64(2)},
+		},
+		{
+			"filter2",
+			"status eq 'O''Brien' or amount le -1.5e2",
+			"(soh.Status=:f1 OR soh.TotalAmountDue<=:f2)",
+			map[string]interface{}{"f1": "O'Brien", "f2": -150.0},
+		},
+		{
+			"filter3",
+			"not (paid eq true and due lt '2020-10-11') AND status NE null",
+			"NOT (soh.Paid=:f1 AND soh.DueDate<:f2) AND soh.Status IS NOT NULL",
+			map[string]interface{}{"f1": true, "f2": due},
+		},
+		{
+			"filter4",
+			"not status eq null or priority ge 3 and priority ne 5",
+			"(NOT (soh.Status IS NULL) OR soh.Priority>=:f1 AND soh.Priority!=:f2)",
+			map[string]interface{}{"f1": int64(3), "f2": int64(5)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.src, schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SQL != tt.wantSQL {
+				t.Errorf("got = {%v} \n want = {%v}", got.SQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(got.Params, tt.wantParams) {
+				t.Errorf("got params = %v \n want = %v", got.Params, tt.wantParams)
+			}
+		})
+	}
+
+	if got, err := Parse("  ", schema); err != nil || got.SQL != "" || len(got.Params) != 0 {
+		t.Errorf("empty filter: got = %v, err = %v", got, err)
+	}
+
+	got, err := Parse("status eq 'open' and priority in (1,2)", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = got.WithPrefix("q")
+	if want := "soh.Status=:q1 AND soh.Priority IN(:q2,:q3)"; got.SQL != want {
+		t.Errorf("prefixed: got = {%v} \n want = {%v}", got.SQL, want)
+	}
+	if want := map[string]interface{}{"q1": "open", "q2": int64(1), "q3": int64(2)}; !reflect.DeepEqual(got.Params, want) {
+		t.Errorf("prefixed: got params = %v \n want = %v", got.Params, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	deep := ""
+	for i := 0; i <= MaxDepth; i++ {
+		deep += "("
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"password eq 'x'", `filter: unknown field "password" at column 1`},
+		{"status eq 3", `filter: number 3 is not a valid string value for field "status" at column 11`},
+		{"priority eq 1.5", `filter: number 1.5 is not a valid int value for field "priority" at column 13`},
+		{"status like 'a%'", `filter: unknown operator "like" at column 8`},
+		{"status eq 'open", `filter: unterminated string at column 11`},
+		{"(status eq 'open'", `filter: expected ) but found end of filter at column 18`},
+		{"status eq 'open' amount gt 1", `filter: unexpected "amount" at column 18`},
+		{"paid gt true", `filter: bool field "paid" can't be compared with gt at column 9`},
+		{"amount gt null", `filter: null can't be compared with gt at column 11`},
+		{"priority in (1;2)", `filter: unexpected character ";" at column 15`},
+		{"due eq 'yesterday'", `filter: string "yesterday" is not a valid time value for field "due" at column 8`},
+		{deep + "status eq 'open'", `filter: filter nested deeper than 32 at column 34`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src, schema)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got err = %v \n want = %v", err, tt.want)
+			}
+			if _, ok := err.(*Error); !ok {
+				t.Errorf("got err of type %T, want *Error", err)
+			}
+		})
+	}
+}
+
+func TestParse_Bind(t *testing.T) {
+	cond, err := Parse("status eq 'open' or priority in (1,2)", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, args, err := query.NewSelectBuilder().SelectAll("Sales.OrderHeader AS soh").
+		Where("soh.StoreID=3").And(cond.SQL).Bind(cond.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSQL := "SELECT * FROM Sales.OrderHeader AS soh WHERE soh.StoreID=3 AND (soh.Status=$1 OR soh.Priority IN($2,$3));"
+	if sql != wantSQL {
+		t.Errorf("got = {%v} \n want = {%v}", sql, wantSQL)
+	}
+	if want := []interface{}{"open", int64(1), int64(2)}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got args = %v \n want = %v", args, want)
+	}
+}
@@ -0,0 +1,320 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits bounds the JSON filters accepted by ParseJSONWith,
+// a zero field sets no limit.
+type Limits struct {
+	// MaxBytes is the maximum size of a document
+	MaxBytes int
+	// MaxDepth is the maximum nesting of $and, $or, $nor and $not
+	MaxDepth int
+	// MaxConditions is the maximum number of values compared,
+	// each value of $in and $nin is counted.
+	MaxConditions int
+}
+
+// DefaultLimits are the limits of ParseJSON
+var DefaultLimits = Limits{MaxBytes: 16 << 10, MaxDepth: 8, MaxConditions: 100}
+
+// JSONError is a JSON filter rejected by ParseJSON, Path locates
+// the problem in the document, e.g $or[1].price.$gte
+type JSONError struct {
+	Path string
+	Msg  string
+}
+
+func (e *JSONError) Error() string {
+	if e.Path == "" {
+		return "filter: " + e.Msg
+	}
+	return "filter: " + e.Msg + " at " + e.Path
+}
+
+// ParseJSON compiles a Mongo-style JSON filter into a Condition on the
+// fields of schema, within DefaultLimits:
+//
+//	{"price": {"$gte": 10}, "$or": [{"tag": "a"}, {"tag": "b"}]}
+//
+// A document's fields are ANDed, in alphabetical order. A field is compared
+// with a value, or with the operators $eq, $ne, $gt, $gte, $lt, $lte,
+// $in, $nin and $not. $and, $or and $nor combine documents. Values are
+// coerced to the type of their field, so "10" is a valid Int.
+// An empty document yields a Condition with an empty SQL and no Params.
+// Its parameters are named like those of Parse, see WithPrefix to use both
+// in one query.
+func ParseJSON(data []byte, schema Schema) (*Condition, error) {
+	return ParseJSONWith(data, schema, DefaultLimits)
+}
+
+// ParseJSONWith is like ParseJSON, within limits
+func ParseJSONWith(data []byte, schema Schema, limits Limits) (*Condition, error) {
+	if limits.MaxBytes > 0 && len(data) > limits.MaxBytes {
+		return nil, &JSONError{Msg: fmt.Sprintf("document larger than %d bytes", limits.MaxBytes)}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, &JSONError{Msg: "invalid document: " + err.Error()}
+	}
+	if dec.More() {
+		return nil, &JSONError{Msg: "invalid document: data after the top-level object"}
+	}
+
+	p := &jsonParser{schema: schema, limits: limits}
+	n, err := p.document(doc, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return render(n, "f"), nil
+}
+
+type jsonParser struct {
+	schema     Schema
+	limits     Limits
+	conditions int
+}
+
+func (p *jsonParser) errorf(path string, format string, args ...interface{}) error {
+	return &JSONError{Path: path, Msg: fmt.Sprintf(format, args...)}
+}
+
+// document parses doc, found at path, ANDing its fields
+func (p *jsonParser) document(doc map[string]interface{}, path string, depth int) (node, error) {
+	var n node
+	for _, key := range sortedKeys(doc) {
+		var (
+			c   node
+			err error
+		)
+		switch key {
+		case "$and", "$or", "$nor":
+			c, err = p.combine(key, doc[key], join(path, key), depth+1)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return nil, p.errorf(join(path, key), "unknown operator %s", key)
+			}
+			c, err = p.field(key, doc[key], join(path, key), depth)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n = and(n, c)
+	}
+	return n, nil
+}
+
+// combine parses v, the documents combined by op
+func (p *jsonParser) combine(op string, v interface{}, path string, depth int) (node, error) {
+	if p.limits.MaxDepth > 0 && depth > p.limits.MaxDepth {
+		return nil, p.errorf(path, "filter nested deeper than %d", p.limits.MaxDepth)
+	}
+	docs, ok := v.([]interface{})
+	if !ok || len(docs) == 0 {
+		return nil, p.errorf(path, "%s expects a non-empty array of documents", op)
+	}
+
+	var n node
+	for ix, d := range docs {
+		dpath := path + "[" + strconv.Itoa(ix) + "]"
+		doc, ok := d.(map[string]interface{})
+		if !ok || len(doc) == 0 {
+			return nil, p.errorf(dpath, "%s expects a non-empty array of documents", op)
+		}
+		c, err := p.document(doc, dpath, depth)
+		if err != nil {
+			return nil, err
+		}
+		if n == nil {
+			n = c
+		} else if op == "$and" {
+			n = &logical{op: "AND", left: n, right: c}
+		} else {
+			n = &logical{op: "OR", left: n, right: c}
+		}
+	}
+	if op == "$nor" {
+		return &negation{n: n}, nil
+	}
+	return n, nil
+}
+
+// field parses v, the value or operators a field is compared with
+func (p *jsonParser) field(name string, v interface{}, path string, depth int) (node, error) {
+	field, ok := p.schema[name]
+	if !ok {
+		return nil, p.errorf(path, "unknown field %q", name)
+	}
+	ops, ok := v.(map[string]interface{})
+	if !ok {
+		return p.compare(name, field, "$eq", v, path)
+	}
+	if len(ops) == 0 {
+		return nil, p.errorf(path, "empty operator document")
+	}
+
+	var n node
+	for _, op := range sortedKeys(ops) {
+		var (
+			c   node
+			err error
+		)
+		opPath := join(path, op)
+		switch op {
+		case "$not":
+			if p.limits.MaxDepth > 0 && depth+1 > p.limits.MaxDepth {
+				return nil, p.errorf(opPath, "filter nested deeper than %d", p.limits.MaxDepth)
+			}
+			inner, ok := ops[op].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf(opPath, "$not expects an operator document")
+			}
+			c, err = p.field(name, inner, opPath, depth+1)
+			if err == nil {
+				c = &negation{n: c}
+			}
+		case "$in", "$nin":
+			c, err = p.in(name, field, ops[op], opPath)
+			if err == nil && op == "$nin" {
+				c = &negation{n: c}
+			}
+		default:
+			c, err = p.compare(name, field, op, ops[op], opPath)
+		}
+		if err != nil {
+			return nil, err
+		}
+		n = and(n, c)
+	}
+	return n, nil
+}
+
+var jsonOperators = map[string]string{
+	"$eq": "=", "$ne": "!=", "$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<=",
+}
+
+// compare parses a comparison of field with v using op
+func (p *jsonParser) compare(name string, field Field, op string, v interface{}, path string) (node, error) {
+	sqlOp, ok := jsonOperators[op]
+	if !ok {
+		return nil, p.errorf(path, "unknown operator %s", op)
+	}
+	if v == nil {
+		switch sqlOp {
+		case "=":
+			return &comparison{column: field.Column, op: "IS NULL"}, nil
+		case "!=":
+			return &comparison{column: field.Column, op: "IS NOT NULL"}, nil
+		}
+		return nil, p.errorf(path, "null can't be compared with %s", op)
+	}
+	if field.Type == Bool && sqlOp != "=" && sqlOp != "!=" {
+		return nil, p.errorf(path, "bool field %q can't be compared with %s", name, op)
+	}
+	value, err := p.value(name, field, v, path)
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{column: field.Column, op: sqlOp, values: []interface{}{value}}, nil
+}
+
+// in parses the values of $in and $nin
+func (p *jsonParser) in(name string, field Field, v interface{}, path string) (node, error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, p.errorf(path, "expected a non-empty array")
+	}
+	c := &comparison{column: field.Column, op: "IN"}
+	for ix, e := range list {
+		value, err := p.value(name, field, e, path+"["+strconv.Itoa(ix)+"]")
+		if err != nil {
+			return nil, err
+		}
+		c.values = append(c.values, value)
+	}
+	return c, nil
+}
+
+// value coerces v to the type of field, counting it against MaxConditions
+func (p *jsonParser) value(name string, field Field, v interface{}, path string) (interface{}, error) {
+	p.conditions++
+	if p.limits.MaxConditions > 0 && p.conditions > p.limits.MaxConditions {
+		return nil, p.errorf(path, "more than %d values compared", p.limits.MaxConditions)
+	}
+	value, ok := coerceJSON(v, field.Type)
+	if !ok {
+		text, _ := json.Marshal(v)
+		return nil, p.errorf(path, "%s is not a valid %s value for field %q", text, field.Type, name)
+	}
+	return value, nil
+}
+
+// coerceJSON converts v, a decoded JSON value, to a value of type t
+func coerceJSON(v interface{}, t Type) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		switch t {
+		case String:
+			return v, true
+		case Int:
+			i, err := strconv.ParseInt(v, 10, 64)
+			return i, err == nil
+		case Float:
+			f, err := strconv.ParseFloat(v, 64)
+			return f, err == nil
+		case Bool:
+			b, err := strconv.ParseBool(v)
+			return b, err == nil
+		case Time:
+			return parseTime(v)
+		}
+	case json.Number:
+		switch t {
+		case String:
+			return v.String(), true
+		case Int:
+			i, err := v.Int64()
+			return i, err == nil
+		case Float:
+			f, err := v.Float64()
+			return f, err == nil
+		}
+	case bool:
+		return v, t == Bool
+	}
+	return nil, false
+}
+
+// and ANDs n and c, n may be nil
+func and(n, c node) node {
+	if n == nil {
+		return c
+	}
+	return &logical{op: "AND", left: n, right: c}
+}
+
+// join returns the path of key in the document at path
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
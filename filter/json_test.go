@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/danvixent/query"
+)
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		wantSQL    string
+		wantParams map[string]interface{}
+	}{
+		{
+			"json1",
+			`{"amount": {"$gte": 10}, "$or": [{"status": "a"}, {"status": "b"}]}`,
+			"(soh.Status=:f1 OR soh.Status=:f2) AND soh.TotalAmountDue>=:f3",
+			map[string]interface{}{"f1": "a", "f2": "b", "f3": 10.0},
+		},
+		{
+			"json2",
+			`{"priority": {"$in": ["1", 2], "$ne": 3}, "paid": "true", "due": null}`,
+			"soh.DueDate IS NULL AND soh.Paid=:f1 AND soh.Priority IN(:f2,:f3) AND soh.Priority!=:f4",
+			map[string]interface{}{"f1": true, "f2": int64(1), "f3": int64(2), "f4": int64(3)},
+		},
+		{
+			"json3",
+			`{"$nor": [{"status": {"$nin": ["open"]}}, {"amount": {"$not": {"$lt": 5.5}}}]}`,
+			"NOT (NOT (soh.Status IN(:f1)) OR NOT (soh.TotalAmountDue<:f2))",
+			map[string]interface{}{"f1": "open", "f2": 5.5},
+		},
+		{
+			"json4",
+			`{"$or": [{"status": "a", "priority": 1}, {"$and": [{"status": {"$ne": null}}]}]}`,
+			"(soh.Priority=:f1 AND soh.Status=:f2 OR soh.Status IS NOT NULL)",
+			map[string]interface{}{"f1": int64(1), "f2": "a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJSON([]byte(tt.doc), schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.SQL != tt.wantSQL {
+				t.Errorf("got = {%v} \n want = {%v}", got.SQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(got.Params, tt.wantParams) {
+				t.Errorf("got params = %v \n want = %v", got.Params, tt.wantParams)
+			}
+		})
+	}
+
+	if got, err := ParseJSON([]byte(`{}`), schema); err != nil || got.SQL != "" || len(got.Params) != 0 {
+		t.Errorf("empty document: got = %v, err = %v", got, err)
+	}
+
+	cond, err := ParseJSON([]byte(`{"status": "open"}`), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sql, args, err := query.NewDeleteBuilder().WithDialect(query.SQLServer).Delete("Sales.OrderHeader").
+		Where(cond.SQL).Bind(cond.Params)
+	if want := "DELETE FROM Sales.OrderHeader WHERE soh.Status=@p1;"; err != nil || sql != want {
+		t.Errorf("got = {%v}, err = %v \n want = {%v}", sql, err, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"open"}) {
+		t.Errorf("got args = %v", args)
+	}
+	q, err := Parse("amount gt 100 or priority in (1,2)", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := ParseJSON([]byte(`{"status": {"$in": ["open", "new"]}}`), schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, j = q.WithPrefix("q"), j.WithPrefix("j")
+	sql, args, err = query.NewSelectBuilder().SelectAll("Sales.OrderHeader AS soh").
+		Where(q.SQL).Where(j.SQL).Bind(q.Merge(j))
+	if want := "SELECT * FROM Sales.OrderHeader AS soh WHERE (soh.TotalAmountDue>$1 OR soh.Priority IN($2,$3)) AND soh.Status IN($4,$5);"; err != nil || sql != want {
+		t.Errorf("combined: got = {%v}, err = %v \n want = {%v}", sql, err, want)
+	}
+	if want := []interface{}{100.0, int64(1), int64(2), "open", "new"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("combined: got args = %v \n want = %v", args, want)
+	}
+}
+
+func TestParseJSON_Errors(t *testing.T) {
+	in := `{"priority": {"$in": [` + strings.Repeat("1,", 100) + `1]}}`
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{`{"password": "x"}`, `filter: unknown field "password" at password`},
+		{`{"$where": "1=1"}`, `filter: unknown operator $where at $where`},
+		{`{"$or": [{"status": "a"}, {"amount": {"$regex": "x"}}]}`, `filter: unknown operator $regex at $or[1].amount.$regex`},
+		{`{"priority": 1.5}`, `filter: 1.5 is not a valid int value for field "priority" at priority`},
+		{`{"paid": {"$gt": true}}`, `filter: bool field "paid" can't be compared with $gt at paid.$gt`},
+		{`{"$or": []}`, `filter: $or expects a non-empty array of documents at $or`},
+		{`{"status": {"$in": "a"}}`, `filter: expected a non-empty array at status.$in`},
+		{`{"status": "a"} {}`, `filter: invalid document: data after the top-level object`},
+		{`["status"]`, `filter: invalid document: json: cannot unmarshal array into Go value of type map[string]interface {}`},
+		{in, `filter: more than 100 values compared at priority.$in[100]`},
+		{`{"$or": [{"$or": [{"$or": [{"$or": [{"$or": [{"$or": [{"$or": [{"$or": [{"$or": [{"status": "a"}]}]}]}]}]}]}]}]}]}`,
+			`filter: filter nested deeper than 8 at $or[0].$or[0].$or[0].$or[0].$or[0].$or[0].$or[0].$or[0].$or`},
+		{`{"status": "` + strings.Repeat("a", 16<<10) + `"}`, `filter: document larger than 16384 bytes`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			_, err := ParseJSON([]byte(tt.doc), schema)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got err = %v \n want = %v", err, tt.want)
+			}
+		})
+	}
+
+	_, err := ParseJSONWith([]byte(in), schema, Limits{})
+	if err != nil {
+		t.Errorf("no limits: got err = %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokComma
+	tokInvalid
+)
+
+// token is a lexeme of a filter, text of a string is unquoted
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return "string " + strconv.Quote(t.text)
+	case tokNumber:
+		return "number " + t.text
+	}
+	return strconv.Quote(t.text)
+}
+
+// is reports whether t is the keyword kw
+func (t token) is(kw string) bool {
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token of src. Unterminated strings
+// and unexpected characters are returned as tokInvalid.
+func (l *lexer) next() token {
+	for l.pos < len(l.src) && isSpace(l.src[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos == len(l.src) {
+		return token{kind: tokEOF, pos: start}
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "(", pos: start}
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")", pos: start}
+	case c == ',':
+		l.pos++
+		return token{kind: tokComma, text: ",", pos: start}
+	case c == '\'':
+		return l.string()
+	case c == '-' || c == '.' || isDigit(c):
+		l.pos++
+		for l.pos < len(l.src) && isNumberPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], pos: start}
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}
+	}
+	l.pos++
+	return token{kind: tokInvalid, text: l.src[start:l.pos], pos: start}
+}
+
+// string lexes a single-quoted string, where '' is a quote
+func (l *lexer) string() token {
+	start := l.pos
+	var b strings.Builder
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		c := l.src[l.pos]
+		if c != '\'' {
+			b.WriteByte(c)
+			continue
+		}
+		if l.pos+1 < len(l.src) && l.src[l.pos+1] == '\'' {
+			b.WriteByte('\'')
+			l.pos++
+			continue
+		}
+		l.pos++
+		return token{kind: tokString, text: b.String(), pos: start}
+	}
+	return token{kind: tokInvalid, text: l.src[start:], pos: start}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNumberPart(c byte) bool {
+	return isDigit(c) || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.'
+}
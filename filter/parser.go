@@ -0,0 +1,191 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parser parses a filter with the grammar:
+//
+//	or         = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | primary
+//	primary    = "(" or ")" | comparison
+//	comparison = field ( op value | "in" "(" value { "," value } ")" )
+//	op         = "eq" | "ne" | "gt" | "ge" | "lt" | "le"
+//	value      = string | number | "true" | "false" | "null"
+//
+// Keywords are case-insensitive, and null may only be compared with eq and ne.
+type parser struct {
+	lex    lexer
+	tok    token
+	schema Schema
+}
+
+var operators = map[string]string{
+	"eq": "=", "ne": "!=", "gt": ">", "ge": ">=", "lt": "<", "le": "<=",
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+}
+
+// errorf returns an *Error at the current token
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.tok.kind == tokInvalid {
+		if strings.HasPrefix(p.tok.text, "'") {
+			return &Error{Pos: p.tok.pos, Msg: "unterminated string"}
+		}
+		return &Error{Pos: p.tok.pos, Msg: fmt.Sprintf("unexpected character %q", p.tok.text)}
+	}
+	return &Error{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseOr(depth int) (node, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.is("or") {
+		p.next()
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (node, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.is("and") {
+		p.next()
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, p.errorf("filter nested deeper than %d", MaxDepth)
+	}
+	if p.tok.is("not") {
+		p.next()
+		n, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &negation{n: n}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	if p.tok.kind == tokLParen {
+		p.next()
+		n, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ) but found %s", p.tok)
+		}
+		p.next()
+		return n, nil
+	}
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf("expected a field but found %s", p.tok)
+	}
+
+	name := p.tok.text
+	field, ok := p.schema[name]
+	if !ok {
+		return nil, p.errorf("unknown field %q", name)
+	}
+	p.next()
+
+	if p.tok.is("in") {
+		return p.parseIn(name, field)
+	}
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf("expected an operator but found %s", p.tok)
+	}
+	op, ok := operators[strings.ToLower(p.tok.text)]
+	if !ok {
+		return nil, p.errorf("unknown operator %q", p.tok.text)
+	}
+	opText := p.tok.text
+	p.next()
+
+	if p.tok.is("null") {
+		switch op {
+		case "=":
+			op = "IS NULL"
+		case "!=":
+			op = "IS NOT NULL"
+		default:
+			return nil, p.errorf("null can't be compared with %s", opText)
+		}
+		p.next()
+		return &comparison{column: field.Column, op: op}, nil
+	}
+	if field.Type == Bool && op != "=" && op != "!=" {
+		return nil, p.errorf("bool field %q can't be compared with %s", name, opText)
+	}
+	v, err := p.parseValue(name, field)
+	if err != nil {
+		return nil, err
+	}
+	return &comparison{column: field.Column, op: op, values: []interface{}{v}}, nil
+}
+
+// parseIn parses the list of values of an in operator
+func (p *parser) parseIn(name string, field Field) (node, error) {
+	p.next()
+	if p.tok.kind != tokLParen {
+		return nil, p.errorf("expected ( but found %s", p.tok)
+	}
+	p.next()
+
+	c := &comparison{column: field.Column, op: "IN"}
+	for {
+		v, err := p.parseValue(name, field)
+		if err != nil {
+			return nil, err
+		}
+		c.values = append(c.values, v)
+
+		switch p.tok.kind {
+		case tokComma:
+			p.next()
+		case tokRParen:
+			p.next()
+			return c, nil
+		default:
+			return nil, p.errorf("expected , or ) but found %s", p.tok)
+		}
+	}
+}
+
+// parseValue parses a value of field, named name
+func (p *parser) parseValue(name string, field Field) (interface{}, error) {
+	switch p.tok.kind {
+	case tokString, tokNumber, tokIdent:
+	default:
+		return nil, p.errorf("expected a value but found %s", p.tok)
+	}
+	v, ok := coerce(p.tok, field.Type)
+	if !ok {
+		return nil, p.errorf("%s is not a valid %s value for field %q", p.tok, field.Type, name)
+	}
+	p.next()
+	return v, nil
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+//Fingerprint identifies the shape of a query, regardless of its values,
+//for grouping queries in metrics and slow query logs.
+type Fingerprint struct {
+	//Normalized is the query with every value and parameter replaced by ?,
+	//lists of values collapsed to (...), identifier quotes and comments
+	//removed, keywords in upper case, unquoted identifiers and function
+	//names in lower case and whitespace canonicalized. Quoted identifiers
+	//keep their case, and their quotes unless they are in lower case.
+	Normalized string
+	//Hash is a hash of Normalized, 16 hexadecimal digits
+	Hash string
+}
+
+func (f Fingerprint) String() string {
+	return f.Hash
+}
+
+//FingerprintSQL returns the Fingerprint of sql. The placeholders and
+//identifier quotes of every dialect normalize alike, so a query has the
+//same Fingerprint whatever its dialect and however many values it matches.
+func FingerprintSQL(sql string) Fingerprint {
+	var out []string
+	toks := tokenize(sql)
+	for ix, tok := range toks {
+		text := tok.text
+		switch {
+		case tok.kind == fmtComment || text == ";":
+			continue
+		case tok.kind == fmtQuoted && text[0] == '[' && len(out) > 0 && out[len(out)-1] == "ARRAY":
+			text = "[...]"
+		case tok.kind == fmtQuoted && (text[0] == '\'' || text[0] == '$'):
+			text = "?"
+		case tok.kind == fmtQuoted:
+			text = normalizeQuoted(text)
+		case tok.kind == fmtWord && (text[0] >= '0' && text[0] <= '9' || text[0] == ':' || text[0] == '@' || text[0] == '$'):
+			text = "?"
+		case tok.kind == fmtWord && keywords[strings.ToUpper(text)]:
+			text = strings.ToUpper(text)
+			if text == "TRUE" || text == "FALSE" {
+				text = "?"
+			}
+		case tok.kind == fmtWord:
+			text = strings.ToLower(text)
+		case text == "?":
+		case text == "-" && ix+1 < len(toks) && toks[ix+1].kind == fmtWord && toks[ix+1].text[0] >= '0' && toks[ix+1].text[0] <= '9' &&
+			(len(out) == 0 || !isWordish(out[len(out)-1]) && out[len(out)-1] != ")" || keywords[out[len(out)-1]]):
+			//the sign of a negative number
+			continue
+		}
+		out = append(out, text)
+	}
+
+	out = collapseLists(out)
+	var b strings.Builder
+	for ix, text := range out {
+		if ix > 0 && (isWordish(out[ix-1]) || strings.HasSuffix(out[ix-1], ")")) && isWordish(text) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(b.String()))
+	hash := strconv.FormatUint(h.Sum64(), 16)
+	return Fingerprint{
+		Normalized: b.String(),
+		Hash:       strings.Repeat("0", 16-len(hash)) + hash,
+	}
+}
+
+//fingerprint returns the Fingerprint of qry, the query of a builder
+//which rendered lists at the positions they record, so that they are
+//normalized whatever their InStrategy, as the builder knows they are values.
+func fingerprint(qry string, lists inLists) Fingerprint {
+	var b strings.Builder
+	copied := 0
+	for _, l := range lists {
+		b.WriteString(qry[copied:l.start])
+		b.WriteString(l.shape)
+		copied = l.end
+	}
+	b.WriteString(qry[copied:])
+	return FingerprintSQL(b.String())
+}
+
+//normalizeQuoted normalizes the quoted identifier text, which keeps its case,
+//unless it is written as the dialects fold unquoted identifiers: a lower
+//case identifier that isn't a keyword normalizes as if it were unquoted.
+//Others are double-quoted, with the quotes within them escaped.
+func normalizeQuoted(text string) string {
+	inner := text[1 : len(text)-1]
+	rq := text[len(text)-1:]
+	inner = strings.Replace(inner, rq+rq, rq, -1)
+	if inner == strings.ToLower(inner) && validIdent(inner, false) && !keywords[strings.ToUpper(inner)] {
+		return inner
+	}
+	return `"` + strings.Replace(inner, `"`, `""`, -1) + `"`
+}
+
+//isWordish reports whether text, a normalized token, is a word rather than punctuation
+func isWordish(text string) bool {
+	c := text[0]
+	return isNamePart(c) || c == '?' || c == '*' || c == '"' || c >= 0x80
+}
+
+//collapseLists collapses the lists of values of out, normalized tokens
+func collapseLists(out []string) []string {
+	var res []string
+	for ix := 0; ix < len(out); ix++ {
+		//(?,?,...)
+		if out[ix] == "(" {
+			end := ix + 1
+			for end < len(out) && out[end] == "?" && end+1 < len(out) && out[end+1] == "," {
+				end += 2
+			}
+			if end+1 < len(out) && out[end] == "?" && out[end+1] == ")" {
+				res = append(res, "(...)")
+				//the rows of a VALUES list
+				if hasSuffix(res, "(...)", ",", "(...)") {
+					res = res[:len(res)-2]
+				}
+				ix = end + 1
+				continue
+			}
+		}
+		res = append(res, out[ix])
+	}
+	return res
+}
+
+//hasSuffix reports whether s ends with suffix
+func hasSuffix(s []string, suffix ...string) bool {
+	if len(s) < len(suffix) {
+		return false
+	}
+	for ix, text := range suffix {
+		if s[len(s)-len(suffix)+ix] != text {
+			return false
+		}
+	}
+	return true
+}
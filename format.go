@@ -0,0 +1,329 @@
+package query
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/danvixent/query/internal/sqlscan"
+)
+
+//KeywordCase selects how Format writes SQL keywords
+type KeywordCase int
+
+const (
+	//KeywordsUpper writes keywords in upper case
+	KeywordsUpper KeywordCase = iota
+	//KeywordsLower writes keywords in lower case
+	KeywordsLower
+	//KeywordsAsIs leaves keywords as they are written
+	KeywordsAsIs
+)
+
+//FormatOptions configures Format, the zero value indents
+//with two spaces and writes keywords in upper case.
+type FormatOptions struct {
+	//Indent is the indentation of a nesting level
+	Indent string
+	//Keywords is the case keywords are written in
+	Keywords KeywordCase
+}
+
+//Format lays sql out for reading: a clause per line, the items of
+//select, SET, ORDER BY and other lists aligned one per line, the
+//conditions of WHERE and HAVING one per line, and subqueries and CTEs
+//indented inside their parentheses. Only the whitespace between tokens
+//and the case of keywords change; strings, quoted identifiers and
+//comments are left as they are.
+//
+//Usage example:
+//	fmt.Println(query.Format(sql, query.FormatOptions{Indent: "\t", Keywords: query.KeywordsLower}))
+func Format(sql string, opts FormatOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	f := &formatter{opts: opts, toks: tokenize(sql)}
+	f.blocks = []*fmtBlock{{}}
+	for i := range f.toks {
+		f.token(i)
+	}
+	return f.b.String()
+}
+
+type fmtKind int
+
+const (
+	fmtWord    = fmtKind(sqlscan.Word)
+	fmtQuoted  = fmtKind(sqlscan.Quoted)
+	fmtComment = fmtKind(sqlscan.Comment)
+	fmtPunct   = fmtKind(sqlscan.Punct)
+)
+
+//fmtToken is a token of the SQL given to Format
+type fmtToken struct {
+	text  string
+	kind  fmtKind
+	space bool //whether whitespace precedes the token
+	pos   int  //offset of the token in the SQL
+}
+
+//tokenize splits sql into words, quoted strings and identifiers,
+//comments and punctuation, dropping whitespace.
+func tokenize(sql string) []fmtToken {
+	scanned := sqlscan.Tokenize(sql)
+	toks := make([]fmtToken, len(scanned))
+	for ix, t := range scanned {
+		toks[ix] = fmtToken{text: t.Text, kind: fmtKind(t.Kind), space: t.Space, pos: t.Pos}
+	}
+	return toks
+}
+
+//fmtBlock is a statement, or a subquery inside parentheses
+type fmtBlock struct {
+	indent  string //indentation of the block's clauses
+	outer   string //indentation of the line holding the opening parenthesis
+	clause  string //clause being written
+	align   string //indentation aligning list items with the clause's first one
+	aligned bool   //whether align is set for the clause
+	parens  int    //parentheses open in the block, outside subqueries
+	cases   int    //CASE expressions open in the block
+	between bool   //whether the next AND belongs to a BETWEEN
+}
+
+type formatter struct {
+	opts   FormatOptions
+	toks   []fmtToken
+	b      strings.Builder
+	blocks []*fmtBlock
+	line   string //indentation of the current line
+	col    int    //length of the current line, in runes
+	start  bool   //whether the current line is still empty
+	prev   string //previous word, upper-cased, "" after any other token
+}
+
+//breakKeywords start a new line when found outside parentheses
+var breakKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "HAVING": true,
+	"ORDER": true, "LIMIT": true, "OFFSET": true, "FETCH": true, "FOR": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true, "WINDOW": true,
+	"INSERT": true, "VALUES": true, "UPDATE": true, "SET": true, "DELETE": true,
+	"RETURNING": true, "OUTPUT": true, "MERGE": true, "WHEN": true, "USING": true, "ON": true, "WITH": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true, "NATURAL": true,
+}
+
+//joinKeywords start a JOIN clause
+var joinKeywords = map[string]bool{
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "CROSS": true, "NATURAL": true,
+}
+
+//continuingKeywords are followed by keywords continuing the same clause,
+//as in LEFT OUTER JOIN, DELETE FROM or THEN UPDATE, or by an alias.
+var continuingKeywords = map[string]bool{
+	"AS": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "OUTER": true, "CROSS": true,
+	"NATURAL": true, "FOR": true, "DO": true, "KEY": true, "DELETE": true, "DISTINCT": true,
+	"THEN": true, "ON": true,
+}
+
+//clauseWords complete the keyword starting a clause, list items
+//are aligned with the first token following them.
+var clauseWords = map[string]bool{
+	"BY": true, "DISTINCT": true, "ALL": true, "INTO": true, "FROM": true, "OUTER": true, "JOIN": true,
+	"RECURSIVE": true,
+}
+
+//keywords are the words whose case Format sets
+var keywords = map[string]bool{
+	"ALL": true, "AND": true, "ANY": true, "ARRAY": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BY": true, "CASE": true, "CONFLICT": true, "CROSS": true,
+	"DELETE": true, "DESC": true, "DISTINCT": true, "DO": true, "DUPLICATE": true,
+	"ELSE": true, "END": true, "EXCEPT": true, "EXISTS": true, "FALSE": true,
+	"FETCH": true, "FIRST": true, "FOR": true, "FROM": true, "FULL": true,
+	"GROUP": true, "HAVING": true, "ILIKE": true, "IN": true, "INNER": true,
+	"INSERT": true, "INTERSECT": true, "INTO": true, "IS": true, "JOIN": true,
+	"KEY": true, "LAST": true, "LATERAL": true, "LEFT": true, "LIKE": true,
+	"LIMIT": true, "MATCHED": true, "MERGE": true, "NATURAL": true, "NEXT": true,
+	"NOT": true, "NOTHING": true, "NULL": true, "NULLS": true, "OFFSET": true,
+	"ON": true, "ONLY": true, "OR": true, "ORDER": true, "OUTER": true,
+	"OUTPUT": true, "OVER": true, "PARTITION": true, "RECURSIVE": true,
+	"RETURNING": true, "RIGHT": true, "ROWS": true, "SELECT": true, "SET": true,
+	"THEN": true, "TOP": true, "TRUE": true, "UNION": true, "UPDATE": true,
+	"USING": true, "VALUES": true, "WHEN": true, "WHERE": true, "WINDOW": true,
+	"WITH": true,
+}
+
+func (f *formatter) block() *fmtBlock {
+	return f.blocks[len(f.blocks)-1]
+}
+
+//token lays out the i-th token
+func (f *formatter) token(i int) {
+	tok := f.toks[i]
+	blk := f.block()
+	switch {
+	case tok.kind == fmtComment:
+		f.write(tok.text, tok.space)
+		if strings.HasPrefix(tok.text, "--") {
+			f.newline(f.line)
+		}
+		return
+	case tok.kind == fmtWord:
+		f.word(i)
+		return
+	case tok.text == ";":
+		f.write(";", tok.space)
+		f.blocks = f.blocks[:1]
+		*f.blocks[0] = fmtBlock{}
+		f.newline("")
+	case tok.text == "(":
+		if !f.subquery(i) {
+			blk.parens++
+			f.write("(", tok.space)
+			break
+		}
+		f.write("(", tok.space)
+		f.blocks = append(f.blocks, &fmtBlock{indent: f.line + f.opts.Indent, outer: f.line})
+		f.newline(f.block().indent)
+	case tok.text == ")":
+		if blk.parens > 0 || len(f.blocks) == 1 {
+			blk.parens--
+			f.write(")", tok.space)
+			break
+		}
+		f.blocks = f.blocks[:len(f.blocks)-1]
+		f.newline(blk.outer)
+		f.write(")", false)
+	case tok.text == ",":
+		f.write(",", tok.space)
+		if blk.parens == 0 && blk.cases == 0 && blk.clause != "" {
+			if blk.clause == "WITH" {
+				//CTEs start at the statement's indentation
+				f.newline(blk.indent)
+			} else if blk.aligned {
+				f.newline(blk.align)
+			} else {
+				f.newline(blk.indent + f.opts.Indent)
+			}
+		}
+	default:
+		f.write(tok.text, tok.space)
+	}
+	f.prev = ""
+}
+
+//word lays out the i-th token, a word
+func (f *formatter) word(i int) {
+	tok := f.toks[i]
+	blk := f.block()
+	upper := strings.ToUpper(tok.text)
+	text := tok.text
+	if keywords[upper] && f.prev != "AS" {
+		switch f.opts.Keywords {
+		case KeywordsUpper:
+			text = upper
+		case KeywordsLower:
+			text = strings.ToLower(text)
+		}
+	}
+
+	if blk.parens == 0 && blk.cases == 0 {
+		switch {
+		case f.breaks(i, upper):
+			f.newline(blk.indent)
+			blk.clause = upper
+			if joinKeywords[upper] {
+				blk.clause = "JOIN"
+			}
+			blk.aligned = false
+			f.write(text, tok.space)
+			f.prev = upper
+			return
+		case upper == "AND" && blk.between:
+			blk.between = false
+		case (upper == "AND" || upper == "OR") && (blk.clause == "WHERE" || blk.clause == "HAVING"):
+			f.newline(blk.indent + f.opts.Indent)
+		case upper == "BETWEEN":
+			blk.between = true
+		}
+	}
+	switch upper {
+	case "CASE":
+		blk.cases++
+	case "END":
+		if blk.cases > 0 {
+			blk.cases--
+		}
+	}
+	f.write(text, tok.space)
+	f.prev = upper
+}
+
+//breaks reports whether the i-th token, a word, starts a clause
+func (f *formatter) breaks(i int, upper string) bool {
+	if !breakKeywords[upper] || continuingKeywords[f.prev] {
+		return false
+	}
+	blk := f.block()
+	switch upper {
+	case "LEFT", "RIGHT":
+		//LEFT(s,n) and RIGHT(s,n) are functions
+		return i+1 == len(f.toks) || f.toks[i+1].text != "(" || f.toks[i+1].space
+	case "USING":
+		return blk.clause != "JOIN"
+	case "ON":
+		next := ""
+		if i+1 < len(f.toks) {
+			next = strings.ToUpper(f.toks[i+1].text)
+		}
+		return next == "CONFLICT" || next == "DUPLICATE"
+	case "SET":
+		return blk.clause != ""
+	case "WITH":
+		//WITH only starts a clause ahead of the statement, as in WITH (NOLOCK)
+		//or WITH TIES it's part of another.
+		return blk.clause == ""
+	}
+	return true
+}
+
+//subquery reports whether the parenthesis at i opens a subquery
+func (f *formatter) subquery(i int) bool {
+	for _, tok := range f.toks[i+1:] {
+		if tok.kind == fmtComment {
+			continue
+		}
+		switch strings.ToUpper(tok.text) {
+		case "SELECT", "WITH", "VALUES":
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+//newline ends the current line, the next token is written
+//on a new line indented with indent.
+func (f *formatter) newline(indent string) {
+	f.start = true
+	f.line = indent
+}
+
+//write writes text, after a space if space is true
+func (f *formatter) write(text string, space bool) {
+	blk := f.block()
+	if f.start {
+		if f.b.Len() > 0 {
+			f.b.WriteByte('\n')
+		}
+		f.b.WriteString(f.line)
+		f.col = utf8.RuneCountInString(f.line)
+		f.start = false
+	} else if space {
+		f.b.WriteByte(' ')
+		f.col++
+	}
+	if !blk.aligned && blk.clause != "" && !clauseWords[strings.ToUpper(text)] && !breakKeywords[strings.ToUpper(text)] {
+		blk.align = f.line + strings.Repeat(" ", f.col-utf8.RuneCountInString(f.line))
+		blk.aligned = true
+	}
+	f.b.WriteString(text)
+	f.col += utf8.RuneCountInString(text)
+}
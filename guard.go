@@ -0,0 +1,122 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+//ErrNoWhere is returned for an UPDATE or DELETE without a WHERE clause,
+//unless AllRows was called on its builder.
+var ErrNoWhere = errors.New("query: UPDATE or DELETE without WHERE, call AllRows to affect every row")
+
+//MaxAffectedError is returned by Exec when the statement affected more
+//rows than allowed by MaxAffected, its transaction was rolled back, or
+//only the statement when it ran in the caller's transaction.
+type MaxAffectedError struct {
+	Max      int64
+	Affected int64
+}
+
+func (e *MaxAffectedError) Error() string {
+	return "query: " + strconv.FormatInt(e.Affected, 10) + " rows affected, more than the maximum of " +
+		strconv.FormatInt(e.Max, 10) + ", rolled back"
+}
+
+//guard holds the safety settings of UPDATE and DELETE builders
+type guard struct {
+	allRows     bool
+	maxAffected int64
+}
+
+//check returns ErrNoWhere if a query without a WHERE clause isn't allowed
+func (g guard) check(hasWhere bool) error {
+	if !hasWhere && !g.allRows {
+		return ErrNoWhere
+	}
+	return nil
+}
+
+//beginner is implemented by the Executors starting
+//transactions, *sql.DB and *sql.Conn
+type beginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+//guardSavepoint is the savepoint exec rolls back to in a caller's transaction
+const guardSavepoint = "query_guard"
+
+//exec runs qry on ex with args. When a maximum number of affected rows is set,
+//qry runs in a transaction which is rolled back if the maximum is exceeded,
+//or after a savepoint rolled back to if ex is already a transaction.
+func (g guard) exec(ctx context.Context, ex Executor, d Dialect, qry string, args []interface{}) (sql.Result, error) {
+	if g.maxAffected <= 0 {
+		return ex.ExecContext(ctx, qry, args...)
+	}
+	db, ok := ex.(beginner)
+	if !ok {
+		return g.execSavepoint(ctx, ex, d, qry, args)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tx.ExecContext(ctx, qry, args...)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if n > g.maxAffected {
+		if err := tx.Rollback(); err != nil {
+			return nil, err
+		}
+		return nil, &MaxAffectedError{Max: g.maxAffected, Affected: n}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//execSavepoint is exec in the transaction ex, whose statements before qry
+//are kept when qry is rolled back. SQL Server has no RELEASE SAVEPOINT,
+//its savepoints last until the transaction ends.
+func (g guard) execSavepoint(ctx context.Context, ex Executor, d Dialect, qry string, args []interface{}) (sql.Result, error) {
+	save, rollback, release := "SAVEPOINT "+guardSavepoint, "ROLLBACK TO SAVEPOINT "+guardSavepoint, "RELEASE SAVEPOINT "+guardSavepoint
+	if d == SQLServer {
+		save, rollback, release = "SAVE TRANSACTION "+guardSavepoint, "ROLLBACK TRANSACTION "+guardSavepoint, ""
+	}
+
+	if _, err := ex.ExecContext(ctx, save); err != nil {
+		return nil, err
+	}
+	res, err := ex.ExecContext(ctx, qry, args...)
+	if err != nil {
+		ex.ExecContext(ctx, rollback)
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		ex.ExecContext(ctx, rollback)
+		return nil, err
+	}
+	if n > g.maxAffected {
+		if _, err := ex.ExecContext(ctx, rollback); err != nil {
+			return nil, err
+		}
+		return nil, &MaxAffectedError{Max: g.maxAffected, Affected: n}
+	}
+	if release != "" {
+		if _, err := ex.ExecContext(ctx, release); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,232 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Hook observes the queries run by a Runner, and by
+//the Exec and Query methods of the builders.
+type Hook interface {
+	//BeforeQuery is called before sql runs with args. The context it
+	//returns is the one sql runs with and AfterQuery receives, so it can
+	//carry e.g a tracing span.
+	BeforeQuery(ctx context.Context, sql string, args []interface{}) context.Context
+	//AfterQuery is called once sql ran for d, with the number of rows
+	//it affected, which is -1 for queries returning rows and whenever
+	//the driver can't tell, and the error it failed with, if any.
+	AfterQuery(ctx context.Context, sql string, args []interface{}, d time.Duration, rowsAffected int64, err error)
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   []Hook
+)
+
+//AddHook registers h for every query run, ahead of the hooks of each Runner
+func AddHook(h Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, h)
+}
+
+//Binder is a builder whose query can be bound to parameters,
+//which every statement builder is.
+type Binder interface {
+	Bind(params map[string]interface{}) (string, []interface{}, error)
+}
+
+//guarded is implemented by the builders with a guard
+type guarded interface {
+	guarded() (guard, Dialect)
+}
+
+//Executor runs queries, as *sql.DB, *sql.Conn and *sql.Tx do
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+//Runner runs the queries of builders on an Executor, calling
+//the global hooks and its own around each of them.
+//A Runner is safe for concurrent use if its Executor is.
+type Runner struct {
+	ex    Executor
+	hooks []Hook
+}
+
+//NewRunner returns a *Runner running queries on ex, calling
+//hooks after the ones registered by AddHook.
+func NewRunner(ex Executor, hooks ...Hook) *Runner {
+	return &Runner{ex: ex, hooks: hooks}
+}
+
+//Exec binds the query of b to params and executes it. The guard
+//of UPDATE and DELETE builders applies, as it does with their Exec.
+func (r *Runner) Exec(ctx context.Context, b Binder, params map[string]interface{}) (sql.Result, error) {
+	qry, args, err := b.Bind(params)
+	if err != nil {
+		return nil, err
+	}
+	var g guard
+	var d Dialect
+	if gb, ok := b.(guarded); ok {
+		g, d = gb.guarded()
+	}
+
+	var res sql.Result
+	err = r.run(ctx, qry, args, func(ctx context.Context) (int64, error) {
+		var err error
+		if res, err = g.exec(ctx, r.ex, d, qry, args); err != nil {
+			if e, ok := err.(*MaxAffectedError); ok {
+				return e.Affected, err
+			}
+			return -1, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			return n, nil
+		}
+		return -1, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//Query binds the query of b to params and runs it,
+//hooks are called once it returns, before rows are read.
+func (r *Runner) Query(ctx context.Context, b Binder, params map[string]interface{}) (*sql.Rows, error) {
+	qry, args, err := b.Bind(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows *sql.Rows
+	err = r.run(ctx, qry, args, func(ctx context.Context) (int64, error) {
+		var err error
+		rows, err = r.ex.QueryContext(ctx, qry, args...)
+		return -1, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+//run calls fn, which runs qry, between the hooks. AfterQuery
+//is called in the reverse order BeforeQuery is.
+func (r *Runner) run(ctx context.Context, qry string, args []interface{}, fn func(ctx context.Context) (int64, error)) error {
+	hooksMu.RLock()
+	all := append(append([]Hook(nil), hooks...), r.hooks...)
+	hooksMu.RUnlock()
+
+	for _, h := range all {
+		ctx = h.BeforeQuery(ctx, qry, args)
+	}
+	start := time.Now()
+	n, err := fn(ctx)
+	d := time.Since(start)
+	for ix := len(all) - 1; ix >= 0; ix-- {
+		all[ix].AfterQuery(ctx, qry, args, d, n, err)
+	}
+	return err
+}
+
+//StatementType returns the lower-cased keyword starting sql, e.g select,
+//insert or update, skipping leading comments, for labelling queries.
+func StatementType(sql string) string {
+	for _, tok := range tokenize(sql) {
+		if tok.kind == fmtComment {
+			continue
+		}
+		if tok.kind == fmtWord {
+			return strings.ToLower(tok.text)
+		}
+		break
+	}
+	return "unknown"
+}
+
+//Logger is the subset of *slog.Logger's methods LogHook uses
+type Logger interface {
+	DebugContext(ctx context.Context, msg string, args ...interface{})
+	WarnContext(ctx context.Context, msg string, args ...interface{})
+	ErrorContext(ctx context.Context, msg string, args ...interface{})
+}
+
+//LogHook is a Hook logging queries with a structured Logger, such as a
+//*slog.Logger. Each record has a statement, query, duration and rows
+//attribute, plus an error attribute for failed queries, and the query
+//is rendered by Debug with Options.
+type LogHook struct {
+	Logger  Logger
+	Options DebugOptions
+	//Slow is the duration from which queries are logged as warnings
+	//rather than debug messages, 0 logs every query as a debug message.
+	Slow time.Duration
+}
+
+//BeforeQuery returns ctx
+func (h *LogHook) BeforeQuery(ctx context.Context, sql string, args []interface{}) context.Context {
+	return ctx
+}
+
+//AfterQuery logs sql
+func (h *LogHook) AfterQuery(ctx context.Context, sql string, args []interface{}, d time.Duration, rowsAffected int64, err error) {
+	attrs := []interface{}{
+		"statement", StatementType(sql),
+		"query", Debug(sql, args, h.Options),
+		"duration", d,
+		"rows", rowsAffected,
+	}
+	switch {
+	case err != nil:
+		h.Logger.ErrorContext(ctx, "query failed", append(attrs, "error", err)...)
+	case h.Slow > 0 && d >= h.Slow:
+		h.Logger.WarnContext(ctx, "slow query", attrs...)
+	default:
+		h.Logger.DebugContext(ctx, "query", attrs...)
+	}
+}
+
+//Metrics receives the measurements of MetricsHook. It is shaped after
+//Prometheus vectors labelled by statement and status, e.g:
+//
+//	func (m promMetrics) ObserveQuery(statement, status string, seconds float64) {
+//		m.duration.WithLabelValues(statement, status).Observe(seconds)
+//	}
+type Metrics interface {
+	//ObserveQuery records a query of statement type statement, as returned
+	//by StatementType, which took seconds and ended with status, ok or error.
+	ObserveQuery(statement, status string, seconds float64)
+	//AddRows records rows affected by a statement of type statement
+	AddRows(statement string, rows int64)
+}
+
+//MetricsHook is a Hook reporting the latency and the rows affected by
+//queries, per statement type, to Metrics.
+type MetricsHook struct {
+	Metrics Metrics
+}
+
+//BeforeQuery returns ctx
+func (h *MetricsHook) BeforeQuery(ctx context.Context, sql string, args []interface{}) context.Context {
+	return ctx
+}
+
+//AfterQuery reports the measurements of sql
+func (h *MetricsHook) AfterQuery(ctx context.Context, sql string, args []interface{}, d time.Duration, rowsAffected int64, err error) {
+	statement := StatementType(sql)
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	h.Metrics.ObserveQuery(statement, status, d.Seconds())
+	if rowsAffected > 0 {
+		h.Metrics.AddRows(statement, rowsAffected)
+	}
+}
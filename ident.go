@@ -0,0 +1,156 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+//IdentMode sets how a builder renders the table, column and alias names
+//given to methods like Select, From, Join, Fields or OrderBy.
+//Modes may be combined, e.g IdentQuote|IdentStrict.
+//
+//Names are expected to be identifiers, optionally schema-qualified and
+//followed by an alias: Sales.OrderHeader AS soh. With IdentQuote anything
+//else, such as COUNT(*), is quoted as an identifier too.
+type IdentMode int
+
+const (
+	//IdentVerbatim renders names as given, it is the default
+	IdentVerbatim IdentMode = 0
+	//IdentQuote quotes each part of a name with the dialect's quotes:
+	//"x" on Postgres, `x` on MySQL and [x] on SQL Server
+	IdentQuote IdentMode = 1
+	//IdentStrict rejects names whose parts aren't made of letters,
+	//digits and underscores, or *. The first name rejected is reported
+	//by the builder's Err, ToSQL and Bind.
+	IdentStrict IdentMode = 2
+)
+
+//IdentError reports a name rejected by IdentStrict
+type IdentError struct {
+	Name string
+}
+
+func (e *IdentError) Error() string {
+	return "query: invalid identifier " + strconv.Quote(e.Name)
+}
+
+//QuoteIdent quotes each part of name, a possibly schema-qualified identifier,
+//with the quotes of d: Sales.OrderHeader is rendered "Sales"."OrderHeader"
+//on Postgres. Quotes in name are escaped, * and parts already quoted, whose
+//quotes are all escaped, are left as is, dots between quotes being part of them.
+func (d Dialect) QuoteIdent(name string) string {
+	lq, rq := `"`, `"`
+	switch d {
+	case MySQL:
+		lq, rq = "`", "`"
+	case SQLServer:
+		lq, rq = "[", "]"
+	}
+
+	parts := splitIdent(name)
+	for ix, p := range parts {
+		if p == "*" || quotedIdent(p, lq, rq) {
+			continue
+		}
+		parts[ix] = lq + strings.Replace(p, rq, rq+rq, -1) + rq
+	}
+	return strings.Join(parts, ".")
+}
+
+//splitIdent splits name on the dots outside its quoted parts:
+//"a.b".c has the parts "a.b" and c.
+func splitIdent(name string) []string {
+	var parts []string
+	start := 0
+	for _, tok := range tokenize(name) {
+		if tok.kind == fmtQuoted || tok.kind == fmtComment {
+			continue
+		}
+		for ix := 0; ix < len(tok.text); ix++ {
+			if tok.text[ix] == '.' {
+				parts = append(parts, name[start:tok.pos+ix])
+				start = tok.pos + ix + 1
+			}
+		}
+	}
+	return append(parts, name[start:])
+}
+
+//quotedIdent reports whether p is quoted with lq and rq,
+//with every rq in between escaped by doubling it.
+func quotedIdent(p, lq, rq string) bool {
+	if len(p) < 2 || !strings.HasPrefix(p, lq) || !strings.HasSuffix(p, rq) {
+		return false
+	}
+	inner := p[1 : len(p)-1]
+	return !strings.Contains(strings.Replace(inner, rq+rq, "", -1), rq)
+}
+
+//identifiers renders names as set by an IdentMode
+type identifiers struct {
+	mode IdentMode
+}
+
+//name renders name for d, returning an *IdentError if it is rejected
+func (i identifiers) name(d Dialect, name string) (string, error) {
+	if i.mode == IdentVerbatim {
+		return name, nil
+	}
+
+	ref, alias := name, ""
+	if f := strings.Fields(name); len(f) == 2 || len(f) == 3 && strings.EqualFold(f[1], "AS") {
+		ref, alias = f[0], f[len(f)-1]
+	}
+	if i.mode&IdentStrict != 0 && (!validIdent(ref, true) || alias != "" && !validIdent(alias, false)) {
+		return name, &IdentError{Name: name}
+	}
+	if i.mode&IdentQuote == 0 {
+		return name, nil
+	}
+
+	if alias != "" {
+		return d.QuoteIdent(ref) + " AS " + d.QuoteIdent(alias), nil
+	}
+	return d.QuoteIdent(ref), nil
+}
+
+//names renders each of names for d, returning the first error of name
+func (i identifiers) names(d Dialect, names []string) ([]string, error) {
+	if i.mode == IdentVerbatim {
+		return names, nil
+	}
+	var err error
+	rendered := make([]string, len(names))
+	for ix, name := range names {
+		var e error
+		if rendered[ix], e = i.name(d, name); err == nil {
+			err = e
+		}
+	}
+	return rendered, err
+}
+
+//validIdent reports whether name is made of letters, digits and underscores,
+//not starting with a digit. If qualified, name may have several such
+//parts separated by dots, the last of which may be *.
+func validIdent(name string, qualified bool) bool {
+	parts := []string{name}
+	if qualified {
+		parts = strings.Split(name, ".")
+	}
+	for ix, p := range parts {
+		if qualified && p == "*" && ix == len(parts)-1 {
+			continue
+		}
+		if p == "" || !isNameStart(p[0]) {
+			return false
+		}
+		for j := 1; j < len(p); j++ {
+			if !isNamePart(p[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//InStrategy selects how a field is matched against a list of values
+type InStrategy int
+
+const (
+	//InAuto renders lists with InList, except lists of more than InChunkSize
+	//values: Postgres binds them as an array with InArray, when they are
+	//values of a single type, SQL Server and MySQL get InValues and InChunks.
+	//A single Named value is a list rendered with InArray on Postgres, and
+	//reported by ErrNamedList on the other dialects.
+	InAuto InStrategy = iota
+	//InList renders field IN(v1,v2,...)
+	InList
+	//InArray renders field=ANY(?), binding values of a single type as one
+	//array parameter, a slice of their type, Postgres infers the type of the
+	//array from field. A single Named value renders field=ANY(:name), bound
+	//to the list itself, and other lists are written as ARRAY[v1,v2,...].
+	//Only Postgres supports it, other dialects get InChunks, and a single
+	//Named value is reported by ErrNamedList on them.
+	InArray
+	//InChunks splits the list into OR-ed IN lists of at most
+	//InChunkSize values: (field IN(...) OR field IN(...))
+	InChunks
+	//InValues matches field against an inline VALUES list:
+	//field IN(SELECT v FROM (VALUES (v1),(v2),...) AS t(v))
+	InValues
+)
+
+//InChunkSize is the number of values InAuto keeps in a single IN list,
+//and the size of each list rendered by InChunks.
+const InChunkSize = 1000
+
+//ErrNamedList is reported for a single Named value matched with InAuto or
+//InArray on MySQL or SQL Server, which can't bind a list to one parameter.
+var ErrNamedList = errors.New("query: a Named list can only be bound to an array on Postgres, pass its values instead")
+
+//strategy resolves InAuto to the strategy used for n values on d
+func (st InStrategy) strategy(d Dialect, values []interface{}) InStrategy {
+	if len(values) == 1 {
+		if _, ok := values[0].(NamedParam); ok && (st == InAuto || st == InArray) && d == Postgres {
+			return InArray
+		}
+	}
+	if st == InArray && d != Postgres {
+		return InChunks
+	}
+	if st != InAuto {
+		return st
+	}
+
+	switch {
+	case len(values) <= InChunkSize:
+		return InList
+	case d == Postgres:
+		if arrayType(values) != nil {
+			return InArray
+		}
+		return InList
+	case d == SQLServer:
+		return InValues
+	}
+	return InChunks
+}
+
+//check returns ErrNamedList if values are a Named list d can't bind with st
+func (st InStrategy) check(d Dialect, values []interface{}) error {
+	if len(values) == 1 && d != Postgres && (st == InAuto || st == InArray) {
+		if _, ok := values[0].(NamedParam); ok {
+			return ErrNamedList
+		}
+	}
+	return nil
+}
+
+//arrayType returns the type of values if they are all literals of the same
+//type, which InArray binds as one array parameter, nil otherwise.
+func arrayType(values []interface{}) reflect.Type {
+	var typ reflect.Type
+	for _, v := range values {
+		t := reflect.TypeOf(v)
+		if typ != nil && t != typ {
+			return nil
+		}
+		typ = t
+		if t == reflect.TypeOf(time.Time{}) {
+			continue
+		}
+		switch t.Kind() {
+		case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if t.Kind() == reflect.String && t != reflect.TypeOf("") {
+				//a named string type, as NamedParam, may render otherwise
+				return nil
+			}
+		default:
+			return nil
+		}
+	}
+	return typ
+}
+
+//inList is a condition written by whereInWith, at start up to end
+//in the builder's query, and its shape as a single-value InList,
+//for Fingerprint to normalize every strategy alike.
+type inList struct {
+	start, end int
+	shape      string
+}
+
+//inLists are the conditions written by whereInWith in a builder's query
+type inLists []inList
+
+//parenthesized moves the conditions of the WHERE clause starting at whereAt
+//past the parenthesis parenthesizeWhere opened the clause with.
+func (l inLists) parenthesized(whereAt int) {
+	for ix := range l {
+		if l[ix].start > whereAt {
+			l[ix].start++
+			l[ix].end++
+		}
+	}
+}
+
+//whereInWith writes a condition matching field against values with st,
+//preceded by keyword, which is " WHERE " or " AND ", and returns it.
+func whereInWith(b *strings.Builder, keyword string, d Dialect, st InStrategy, field string, values ...interface{}) inList {
+	if values == nil {
+		return inList{}
+	}
+	b.WriteString(keyword)
+	start := b.Len()
+	writeIn(b, d, st, field, values)
+	return inList{start: start, end: b.Len(), shape: field + " IN(?)"}
+}
+
+//writeIn writes the condition of whereInWith
+func writeIn(b *strings.Builder, d Dialect, st InStrategy, field string, values []interface{}) {
+	switch st.strategy(d, values) {
+	case InArray:
+		b.WriteString(field)
+		if len(values) == 1 {
+			if n, ok := values[0].(NamedParam); ok {
+				b.WriteString("=ANY(")
+				b.WriteString(n.String())
+				b.WriteByte(')')
+				return
+			}
+		}
+		if arrayType(values) != nil {
+			b.WriteString("=ANY(")
+			b.WriteString(arrayParam(values))
+			b.WriteByte(')')
+			return
+		}
+		b.WriteString("=ANY(ARRAY[")
+		for ix, v := range values {
+			if ix > 0 {
+				b.WriteByte(',')
+			}
+			writeQuote(b, v)
+		}
+		b.WriteString("])")
+	case InChunks:
+		if len(values) <= InChunkSize {
+			in(b, field, values)
+			return
+		}
+		b.WriteByte('(')
+		for start := 0; start < len(values); start += InChunkSize {
+			if start > 0 {
+				b.WriteString(" OR ")
+			}
+			end := start + InChunkSize
+			if end > len(values) {
+				end = len(values)
+			}
+			in(b, field, values[start:end])
+		}
+		b.WriteByte(')')
+	case InValues:
+		b.WriteString(field)
+		if d == MySQL {
+			// MySQL names the columns of a VALUES list column_0, column_1...
+			b.WriteString(" IN(SELECT column_0 FROM (VALUES ")
+			for ix, v := range values {
+				if ix > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString("ROW(")
+				writeQuote(b, v)
+				b.WriteByte(')')
+			}
+			b.WriteString(") AS t)")
+			return
+		}
+		b.WriteString(" IN(SELECT v FROM (VALUES ")
+		for ix, v := range values {
+			if ix > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteByte('(')
+			writeQuote(b, v)
+			b.WriteByte(')')
+		}
+		b.WriteString(") AS t(v))")
+	default:
+		in(b, field, values)
+	}
+}
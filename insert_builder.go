@@ -1,8 +1,22 @@
 package query
 
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+//ErrConflictTarget is reported for an OnConflictUpdate without
+//target on Postgres, which can't tell which constraint to update on.
+var ErrConflictTarget = errors.New("query: ON CONFLICT DO UPDATE needs a conflict target on postgres")
+
 //InsertBuilder is a builder for INSERT statements
 type InsertBuilder struct {
-	query string
+	query   strings.Builder
+	dialect Dialect
+	idents  identifiers
+	err     error
 }
 
 //NewInsertBuilder returns a new *InsertBuilder
@@ -10,15 +24,59 @@ func NewInsertBuilder() *InsertBuilder {
 	return new(InsertBuilder)
 }
 
+//WithDialect sets the dialect the builder's query is rendered for
+func (i *InsertBuilder) WithDialect(d Dialect) *InsertBuilder {
+	i.dialect = d
+	return i
+}
+
+//WithIdentMode sets how the table, column and alias names given
+//to the builder are rendered, they are added verbatim by default.
+func (i *InsertBuilder) WithIdentMode(m IdentMode) *InsertBuilder {
+	i.idents.mode = m
+	return i
+}
+
+//Err returns the first error since the query was started, including the
+//inner select's: a name rejected by IdentStrict, an *UnsupportedError
+//or ErrConflictTarget.
+func (i *InsertBuilder) Err() error {
+	return i.err
+}
+
+//name renders name as set by the builder's IdentMode,
+//keeping the error of a name it rejects for Err
+func (i *InsertBuilder) name(name string) string {
+	rendered, err := i.idents.name(i.dialect, name)
+	i.fail(err)
+	return rendered
+}
+
+//names renders each of names like name
+func (i *InsertBuilder) names(names []string) []string {
+	rendered, err := i.idents.names(i.dialect, names)
+	i.fail(err)
+	return rendered
+}
+
+//fail keeps err for Err, unless the query already failed
+func (i *InsertBuilder) fail(err error) {
+	if i.err == nil {
+		i.err = err
+	}
+}
+
 //Insert adds an INSERT statement to the builders'squery
 func (i *InsertBuilder) Insert(table string) *InsertBuilder {
-	i.query = "INSERT INTO " + table
+	i.Clear()
+	i.query.WriteString("INSERT INTO ")
+	i.query.WriteString(i.name(table))
 	return i
 }
 
 //Fields adds the fields to be inserted to the builder's query
 func (i *InsertBuilder) Fields(fields ...string) *InsertBuilder {
-	i.query += addFields("", true, fields...)
+	addFields(&i.query, "", true, i.names(fields)...)
 	return i
 }
 
@@ -35,14 +93,17 @@ func (i *InsertBuilder) Fields(fields ...string) *InsertBuilder {
 // Note that string values in ixToValues beginning with '(' won't be quoted
 // by this method,as they will be assumed to be subqueries.
 func (i *InsertBuilder) ValuesFromMap(ixToValues map[int]interface{}) *InsertBuilder {
-	i.query += " VALUES(" + values(ixToValues) + ")"
+	i.query.WriteString(" VALUES(")
+	values(&i.query, ixToValues)
+	i.query.WriteByte(')')
 	return i
 }
 
 //Values adds a set of values for each corresponding column to the builder's query.
 //Any value for a string colmun should be wrapped in single quotes.
+//VALUES is separated from the field list by a space, as with ValuesFromMap.
 func (i *InsertBuilder) Values(values ...string) *InsertBuilder {
-	i.query += addFields("VALUES", true, values...)
+	addFields(&i.query, " VALUES", true, values...)
 	return i
 }
 
@@ -51,27 +112,195 @@ func (i *InsertBuilder) Values(values ...string) *InsertBuilder {
 // Note that string values in ixToValues beginning with '(' won't be quoted
 // by this method, as they will be assumed to be subqueries.
 func (i *InsertBuilder) ValuesSet(ixToValues map[int]interface{}) *InsertBuilder {
-	i.query += ",(" + values(ixToValues) + ")"
+	i.query.WriteString(",(")
+	values(&i.query, ixToValues)
+	i.query.WriteByte(')')
+	return i
+}
+
+//FromSelect inserts the rows selected by s, in place of a VALUES clause.
+//Fields should be called prior to FromSelect, and s MUST select
+//a value for each field, in the same order.
+//
+//Usage example:
+//	Insert("Sales.OrderArchive").Fields("OrderID", "StoreID").
+//		FromSelect(NewSelectBuilder().Select("OrderID", "StoreID").From("Sales.OrderHeader"))
+func (i *InsertBuilder) FromSelect(s *SelectBuilder) *InsertBuilder {
+	if s.err != nil {
+		i.fail(s.err)
+	}
+	i.query.WriteByte(' ')
+	i.query.WriteString(s.query.String())
+	return i
+}
+
+//FromJoin inserts the rows selected by j, in place of a VALUES clause.
+//Fields should be called prior to FromJoin, and j MUST select
+//a value for each field, in the same order.
+func (i *InsertBuilder) FromJoin(j *JoinBuilder) *InsertBuilder {
+	return i.FromSelect(j.s)
+}
+
+//OnConflictDoNothing skips the rows conflicting with a unique constraint on
+//target, or with any unique constraint if target is empty. It is rendered
+//as ON CONFLICT (target) DO NOTHING on Postgres, and as ON DUPLICATE KEY
+//UPDATE target=target on MySQL, which needs a target for it.
+//It is called after the values, VALUES or FromSelect, and SQL Server,
+//which has no such clause, gets an *UnsupportedError.
+//
+//Usage example:
+//	Insert("Sales.OrderArchive").Fields("OrderID", "StoreID").
+//		FromSelect(NewSelectBuilder().Select("OrderID", "StoreID").From("Sales.OrderHeader")).
+//		OnConflictDoNothing("OrderID")
+func (i *InsertBuilder) OnConflictDoNothing(target ...string) *InsertBuilder {
+	target = i.names(target)
+	switch i.dialect {
+	case MySQL:
+		if len(target) == 0 {
+			i.fail(&UnsupportedError{Dialect: i.dialect, Clause: "ON CONFLICT DO NOTHING without target"})
+			return i
+		}
+		i.query.WriteString(" ON DUPLICATE KEY UPDATE ")
+		i.query.WriteString(target[0] + "=" + target[0])
+	case SQLServer:
+		i.fail(&UnsupportedError{Dialect: i.dialect, Clause: "ON CONFLICT"})
+	default:
+		i.query.WriteString(" ON CONFLICT")
+		if len(target) > 0 {
+			addFields(&i.query, "", true, target...)
+		}
+		i.query.WriteString(" DO NOTHING")
+	}
+	return i
+}
+
+//OnConflictUpdate updates fields of the rows conflicting with a unique
+//constraint on target to the values being inserted. It is rendered as
+//ON CONFLICT (target) DO UPDATE SET f=EXCLUDED.f on Postgres, which needs
+//a target, and as ON DUPLICATE KEY UPDATE f=VALUES(f) on MySQL, which
+//ignores it. It is called after the values, VALUES or FromSelect, and
+//SQL Server, which has no such clause, gets an *UnsupportedError.
+func (i *InsertBuilder) OnConflictUpdate(target []string, fields ...string) *InsertBuilder {
+	target = i.names(target)
+	fields = i.names(fields)
+	switch i.dialect {
+	case MySQL:
+		i.query.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for ix, f := range fields {
+			if ix > 0 {
+				i.query.WriteByte(',')
+			}
+			i.query.WriteString(f + "=VALUES(" + f + ")")
+		}
+	case SQLServer:
+		i.fail(&UnsupportedError{Dialect: i.dialect, Clause: "ON CONFLICT"})
+	default:
+		if len(target) == 0 {
+			i.fail(ErrConflictTarget)
+			return i
+		}
+		i.query.WriteString(" ON CONFLICT")
+		addFields(&i.query, "", true, target...)
+		i.query.WriteString(" DO UPDATE SET ")
+		for ix, f := range fields {
+			if ix > 0 {
+				i.query.WriteByte(',')
+			}
+			i.query.WriteString(f + "=EXCLUDED." + f)
+		}
+	}
 	return i
 }
 
 //Returning selects fields from the temporary inserted table
 func (i *InsertBuilder) Returning(fields ...string) *InsertBuilder {
-	i.query += " RETURNING" + addFields("", false, fields...)
+	i.query.WriteString(" RETURNING")
+	addFields(&i.query, "", false, i.names(fields)...)
 	return i
 }
 
 //ReturningAll selects all fields from the temporary inserted table
 func (i *InsertBuilder) ReturningAll() *InsertBuilder {
-	i.query += " RETURNING *"
+	i.query.WriteString(" RETURNING *")
 	return i
 }
 
+//Comment appends the sqlcommenter comment of ctx to the query: the tags
+//set by WithTag and AddCommentSource, and the file:line Comment is called
+//from, e.g /*caller='orders%2Fstore.go%3A42',route='%2Forders'*/.
+//It should be called once the query is complete.
+func (i *InsertBuilder) Comment(ctx context.Context) *InsertBuilder {
+	i.query.WriteByte(' ')
+	i.query.WriteString(comment(ctx))
+	return i
+}
+
+//Bind renders the builder's query with named parameters replaced by the
+//dialect's positional placeholders, returning the values of params in
+//the same order. Names without a value and values not used by any name
+//are reported by a *BindError.
+func (i *InsertBuilder) Bind(params map[string]interface{}) (string, []interface{}, error) {
+	if i.err != nil {
+		return "", nil, i.err
+	}
+	return bindMap(i.sql(), i.dialect, params)
+}
+
+//BindStruct is like Bind, taking values from the fields of v, a struct
+//or a pointer to one. Fields are named after their db tag if they have one.
+func (i *InsertBuilder) BindStruct(v interface{}) (string, []interface{}, error) {
+	if i.err != nil {
+		return "", nil, i.err
+	}
+	return bindStruct(i.sql(), i.dialect, v)
+}
+
+//Debug binds params like Bind, then renders the query with the values
+//interpolated by Debug, for logs. It must never be executed.
+func (i *InsertBuilder) Debug(params map[string]interface{}, opts DebugOptions) (string, error) {
+	qry, args, err := i.Bind(params)
+	if err != nil {
+		return "", err
+	}
+	return Debug(qry, args, opts), nil
+}
+
+//Exec binds params to the builder's query like Bind and runs it on ex,
+//a *sql.DB, *sql.Conn or *sql.Tx. The hooks registered by AddHook are
+//called around it. Queries with Returning are run with NewRunner's Query.
+func (i *InsertBuilder) Exec(ctx context.Context, ex Executor, params map[string]interface{}) (sql.Result, error) {
+	return NewRunner(ex).Exec(ctx, i, params)
+}
+
+//Compile renders the builder's query into a Template, whose named
+//parameters are bound to new values on each execution.
+func (i *InsertBuilder) Compile() *Template {
+	return compile(i.sql(), i.dialect)
+}
+
 //Clear erases the builder's query
 func (i *InsertBuilder) Clear() {
-	i.query = ""
+	i.query.Reset()
+	i.err = nil
 }
 
+//String returns the builder's query, with the values of an Expr rendered as ?
 func (i *InsertBuilder) String() string {
-	return i.query + ";"
+	return exprPlaceholders(i.sql())
+}
+
+//sql returns the builder's query, as bound by Bind
+func (i *InsertBuilder) sql() string {
+	return i.query.String() + ";"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (i *InsertBuilder) Pretty() string {
+	return Format(i.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query
+func (i *InsertBuilder) Fingerprint() Fingerprint {
+	return FingerprintSQL(i.String())
 }
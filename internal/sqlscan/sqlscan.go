@@ -0,0 +1,145 @@
+// Package sqlscan splits SQL into tokens, so that the packages of the module
+// scanning SQL tell words, quoted strings and identifiers, comments and
+// punctuation apart alike.
+package sqlscan
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind is the kind of a Token
+type Kind int
+
+const (
+	// Word is a keyword, identifier, number or parameter such as :name, $1 or @p1
+	Word Kind = iota
+	// Quoted is a quoted string or identifier: 'x', "x", `x`, [x] or $tag$x$tag$
+	Quoted
+	// Comment is a -- or /* */ comment
+	Comment
+	// Punct is a single punctuation character
+	Punct
+)
+
+// Token is a token of SQL
+type Token struct {
+	Text  string
+	Kind  Kind
+	Space bool // whether whitespace precedes the token
+	Pos   int  // offset of the token in the SQL
+}
+
+// Tokenize splits sql into words, quoted strings and identifiers,
+// comments and punctuation, dropping whitespace.
+func Tokenize(sql string) []Token {
+	var toks []Token
+	space := false
+	for ix := 0; ix < len(sql); {
+		c := sql[ix]
+		start := ix
+		kind := Punct
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			ix++
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			ix = skipQuoted(sql, ix, c)
+			kind = Quoted
+		case c == '[':
+			ix = skipQuoted(sql, ix, ']')
+			kind = Quoted
+		case c == '-' && strings.HasPrefix(sql[ix:], "--"):
+			if end := strings.IndexByte(sql[ix:], '\n'); end >= 0 {
+				ix += end
+			} else {
+				ix = len(sql)
+			}
+			kind = Comment
+		case c == '/' && strings.HasPrefix(sql[ix:], "/*"):
+			if end := strings.Index(sql[ix+2:], "*/"); end >= 0 {
+				ix += end + 4
+			} else {
+				ix = len(sql)
+			}
+			kind = Comment
+		case c == '$' && dollarTag(sql[ix:]) != "":
+			tag := dollarTag(sql[ix:])
+			if end := strings.Index(sql[ix+len(tag):], tag); end >= 0 {
+				ix += end + 2*len(tag)
+			} else {
+				ix = len(sql)
+			}
+			kind = Quoted
+		case isNamePart(c) || c >= utf8.RuneSelf ||
+			(c == ':' || c == '@' || c == '$') && ix+1 < len(sql) && isNamePart(sql[ix+1]) && (ix == 0 || sql[ix-1] != ':'):
+			for ix++; ix < len(sql) && (isNamePart(sql[ix]) || sql[ix] >= utf8.RuneSelf || sql[ix] == '.' || sql[ix] == '$'); ix++ {
+			}
+			kind = Word
+		default:
+			ix++
+		}
+		toks = append(toks, Token{Text: sql[start:ix], Kind: kind, Space: space, Pos: start})
+		space = false
+	}
+	return toks
+}
+
+// Split splits sql on the punctuation sep found outside parentheses,
+// quotes and comments, trimming each part.
+func Split(sql string, sep byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for _, tok := range Tokenize(sql) {
+		if tok.Kind != Punct {
+			continue
+		}
+		switch tok.Text[0] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(sql[start:tok.Pos]))
+				start = tok.Pos + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(sql[start:]))
+}
+
+// skipQuoted returns the offset following the string quoted
+// with quote starting at ix, a doubled quote being part of it.
+func skipQuoted(sql string, ix int, quote byte) int {
+	for ix++; ix < len(sql); ix++ {
+		if sql[ix] != quote {
+			continue
+		}
+		if ix+1 < len(sql) && sql[ix+1] == quote {
+			ix++
+			continue
+		}
+		return ix + 1
+	}
+	return len(sql)
+}
+
+// dollarTag returns the tag of the Postgres dollar-quoted string
+// s starts with, e.g $$ or $body$, or "" if s doesn't start with one.
+func dollarTag(s string) string {
+	for ix := 1; ix < len(s); ix++ {
+		if s[ix] == '$' {
+			return s[:ix+1]
+		}
+		if !isNamePart(s[ix]) || ix == 1 && s[ix] >= '0' && s[ix] <= '9' {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isNamePart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,27 @@
+package sqlscan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	sql := "SELECT a.b, 'it''s' FROM [x]]y] -- c\nWHERE n::int=:n AND s=$t$ ; $t$;"
+	var got []string
+	for _, tok := range Tokenize(sql) {
+		got = append(got, tok.Text)
+	}
+	want := []string{"SELECT", "a.b", ",", "'it''s'", "FROM", "[x]]y]", "-- c", "WHERE", "n", ":", ":", "int",
+		"=", ":n", "AND", "s", "=", "$t$ ; $t$", ";"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %q \n want = %q", got, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	got := Split("a, f(b,c), 'd,e' /* f, g */, \"h,i\"", ',')
+	want := []string{"a", "f(b,c)", "'d,e' /* f, g */", `"h,i"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got = %q \n want = %q", got, want)
+	}
+}
@@ -1,5 +1,10 @@
 package query
 
+import (
+	"context"
+	"database/sql"
+)
+
 //JoinBuilder is a qury builder for JOIN clauses
 type JoinBuilder struct {
 	s *SelectBuilder
@@ -12,23 +17,30 @@ func NewJoinBuilder() *JoinBuilder {
 	return j
 }
 
+//WithDialect sets the dialect the builder's query is rendered for
+func (j *JoinBuilder) WithDialect(d Dialect) *JoinBuilder {
+	j.s.WithDialect(d)
+	return j
+}
+
 //Join adds a JOIN clause to the builder's query
 //table represents the name of the table
 //to join to.
 func (j *JoinBuilder) Join(table string) *JoinBuilder {
-	j.s.query += " JOIN " + table
+	j.s.query.WriteString(" JOIN ")
+	j.s.query.WriteString(j.s.name(table))
 	return j
 }
 
 // Using adds a using clause to the builder's query
 func (j *JoinBuilder) Using(fields ...string) *JoinBuilder {
-	j.s.query += addFields(" USING", true, fields...)
+	addFields(&j.s.query, " USING", true, j.s.names(fields)...)
 	return j
 }
 
 //On adds the matching colmuns in joined tables.
 func (j *JoinBuilder) On(column1 string, column2 string) *JoinBuilder {
-	j.s.query += " ON " + column1 + "=" + column2
+	j.s.query.WriteString(" ON " + j.s.name(column1) + "=" + j.s.name(column2))
 	return j
 }
 
@@ -36,7 +48,8 @@ func (j *JoinBuilder) On(column1 string, column2 string) *JoinBuilder {
 //Alternatively the alias could be set beside the table name while
 //adding the table to the builder's query
 func (j *JoinBuilder) As(alias string) *JoinBuilder {
-	j.s.query += " AS " + alias
+	j.s.query.WriteString(" AS ")
+	j.s.query.WriteString(j.s.name(alias))
 	return j
 }
 
@@ -46,6 +59,19 @@ func (j *JoinBuilder) FromSelectBuilder(s *SelectBuilder) *JoinBuilder {
 	return j
 }
 
+//WithIdentMode sets how the table, column and alias names given
+//to the builder are rendered, they are added verbatim by default.
+func (j *JoinBuilder) WithIdentMode(m IdentMode) *JoinBuilder {
+	j.s.WithIdentMode(m)
+	return j
+}
+
+//Err returns the first error since the query was started: a name rejected
+//by IdentStrict or a sort spec rejected by OrderByFromRequest.
+func (j *JoinBuilder) Err() error {
+	return j.s.Err()
+}
+
 //Select adds a select statement to the builder's query
 func (j *JoinBuilder) Select(fields ...string) *JoinBuilder {
 	j.s.Select(fields...)
@@ -93,12 +119,20 @@ func (j *JoinBuilder) WhereWithMap(ixToCond map[int]interface{}) *JoinBuilder {
 	return j
 }
 
-//WhereFieldIn adds a WHERE clause along with an IN operator
+//WhereFieldIn adds a WHERE clause along with an IN operator,
+//large lists are rendered with the strategy InAuto picks for the dialect.
 func (j *JoinBuilder) WhereFieldIn(field string, values ...interface{}) *JoinBuilder {
 	j.s.WhereFieldIn(field, values...)
 	return j
 }
 
+//WhereFieldInWith adds a WHERE clause matching field against values
+//with the specified strategy.
+func (j *JoinBuilder) WhereFieldInWith(strategy InStrategy, field string, values ...interface{}) *JoinBuilder {
+	j.s.WhereFieldInWith(strategy, field, values...)
+	return j
+}
+
 //And is an alternative to WhereWithMap
 //
 //it adds an AND along with the condition specified
@@ -133,9 +167,45 @@ func (j *JoinBuilder) Or(condition string) *JoinBuilder {
 	return j
 }
 
-//OrderBy adds an ORDER BY clause to the builder's query
-func (j *JoinBuilder) OrderBy(field string) *JoinBuilder {
-	j.s.OrderBy(field)
+//WhereIf adds condition like Where, only if cond is true
+func (j *JoinBuilder) WhereIf(cond bool, condition string) *JoinBuilder {
+	j.s.WhereIf(cond, condition)
+	return j
+}
+
+//AndIf adds condition along with an AND, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (j *JoinBuilder) AndIf(cond bool, condition string) *JoinBuilder {
+	j.s.AndIf(cond, condition)
+	return j
+}
+
+//OrIf adds condition along with an OR, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (j *JoinBuilder) OrIf(cond bool, condition string) *JoinBuilder {
+	j.s.OrIf(cond, condition)
+	return j
+}
+
+//WhereNonZero adds the condition built by op on field and v like Where,
+//only if v isn't a zero value.
+func (j *JoinBuilder) WhereNonZero(op Operator, field string, v interface{}) *JoinBuilder {
+	j.s.WhereNonZero(op, field, v)
+	return j
+}
+
+//OrderBy adds an ORDER BY clause on fields to the builder's query,
+//Asc and Desc apply to the last field.
+func (j *JoinBuilder) OrderBy(fields ...string) *JoinBuilder {
+	j.s.OrderBy(fields...)
+	return j
+}
+
+//OrderByFromRequest adds an ORDER BY clause parsed from spec, a client's
+//list of fields to sort by, allowed maps those fields to their column.
+//See SelectBuilder.OrderByFromRequest for the syntax of spec.
+func (j *JoinBuilder) OrderByFromRequest(spec string, allowed map[string]string) *JoinBuilder {
+	j.s.OrderByFromRequest(spec, allowed)
 	return j
 }
 
@@ -157,6 +227,51 @@ func (j *JoinBuilder) GroupBy(field string) *JoinBuilder {
 	return j
 }
 
+//Comment appends the sqlcommenter comment of ctx to the query: the tags
+//set by WithTag and AddCommentSource, and the file:line Comment is called
+//from, e.g /*caller='orders%2Fstore.go%3A42',route='%2Forders'*/.
+//It should be called once the query is complete.
+func (j *JoinBuilder) Comment(ctx context.Context) *JoinBuilder {
+	j.s.Comment(ctx)
+	return j
+}
+
+//Bind renders the builder's query with named parameters replaced by the
+//dialect's positional placeholders, returning the values of params in
+//the same order. Names without a value and values not used by any name
+//are reported by a *BindError.
+func (j *JoinBuilder) Bind(params map[string]interface{}) (string, []interface{}, error) {
+	return j.s.Bind(params)
+}
+
+//BindStruct is like Bind, taking values from the fields of v, a struct
+//or a pointer to one. Fields are named after their db tag if they have one.
+func (j *JoinBuilder) BindStruct(v interface{}) (string, []interface{}, error) {
+	return j.s.BindStruct(v)
+}
+
+//Debug binds params like Bind, then renders the query with the values
+//interpolated by Debug, for logs. It must never be executed.
+func (j *JoinBuilder) Debug(params map[string]interface{}, opts DebugOptions) (string, error) {
+	qry, args, err := j.Bind(params)
+	if err != nil {
+		return "", err
+	}
+	return Debug(qry, args, opts), nil
+}
+
+//Query binds params to the builder's query like Bind and runs it on ex,
+//a *sql.DB, *sql.Conn or *sql.Tx. The hooks registered by AddHook are called around it.
+func (j *JoinBuilder) Query(ctx context.Context, ex Executor, params map[string]interface{}) (*sql.Rows, error) {
+	return NewRunner(ex).Query(ctx, j, params)
+}
+
+//Compile renders the builder's query into a Template, whose named
+//parameters are bound to new values on each execution.
+func (j *JoinBuilder) Compile() *Template {
+	return compile(j.s.sql(), j.s.dialect)
+}
+
 //Clear erases the builder's query
 func (j *JoinBuilder) Clear() {
 	j.s.Clear()
@@ -165,3 +280,15 @@ func (j *JoinBuilder) Clear() {
 func (j *JoinBuilder) String() string {
 	return j.s.String()
 }
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (j *JoinBuilder) Pretty() string {
+	return Format(j.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query, whose
+//WhereFieldIn conditions have the same one whatever their InStrategy.
+func (j *JoinBuilder) Fingerprint() Fingerprint {
+	return j.s.Fingerprint()
+}
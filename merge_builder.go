@@ -0,0 +1,285 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+//MergeBuilder is a builder for MERGE statements, which Postgres 15+
+//and SQL Server support. MySQL has no MERGE, its queries are reported
+//by an *UnsupportedError.
+type MergeBuilder struct {
+	query   strings.Builder
+	dialect Dialect
+	idents  identifiers
+	err     error
+}
+
+//NewMergeBuilder returns a new *MergeBuilder
+func NewMergeBuilder() *MergeBuilder {
+	return new(MergeBuilder)
+}
+
+//WithDialect sets the dialect the builder's query is rendered for
+func (m *MergeBuilder) WithDialect(d Dialect) *MergeBuilder {
+	m.dialect = d
+	return m
+}
+
+//WithIdentMode sets how the table, column and alias names given
+//to the builder are rendered, they are added verbatim by default.
+//Conditions and the assignments of ThenUpdate are always verbatim.
+func (m *MergeBuilder) WithIdentMode(mode IdentMode) *MergeBuilder {
+	m.idents.mode = mode
+	return m
+}
+
+//Err returns the first error since the query was started: an
+//*UnsupportedError on MySQL, a name rejected by IdentStrict,
+//or the error of the source select.
+func (m *MergeBuilder) Err() error {
+	if m.dialect == MySQL {
+		return &UnsupportedError{Dialect: m.dialect, Clause: "MERGE"}
+	}
+	return m.err
+}
+
+//name renders name as set by the builder's IdentMode,
+//keeping the error of a name it rejects for Err
+func (m *MergeBuilder) name(name string) string {
+	rendered, err := m.idents.name(m.dialect, name)
+	m.fail(err)
+	return rendered
+}
+
+//names renders each of names like name
+func (m *MergeBuilder) names(names []string) []string {
+	rendered, err := m.idents.names(m.dialect, names)
+	m.fail(err)
+	return rendered
+}
+
+//fail keeps err for Err, unless the query already failed
+func (m *MergeBuilder) fail(err error) {
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+//Merge adds a MERGE statement to the builder's query,
+//target is the table rows are merged into.
+func (m *MergeBuilder) Merge(target string) *MergeBuilder {
+	m.Clear()
+	m.query.WriteString("MERGE INTO ")
+	m.query.WriteString(m.name(target))
+	return m
+}
+
+//As sets an alias for the target or the source,
+//depending on which was added last.
+func (m *MergeBuilder) As(alias string) *MergeBuilder {
+	m.query.WriteString(" AS ")
+	m.query.WriteString(m.name(alias))
+	return m
+}
+
+//Using sets table as the source of the rows to merge
+func (m *MergeBuilder) Using(table string) *MergeBuilder {
+	m.query.WriteString(" USING ")
+	m.query.WriteString(m.name(table))
+	return m
+}
+
+//UsingSelect sets the rows selected by s as the source of the rows
+//to merge, As should be called after UsingSelect to name them.
+func (m *MergeBuilder) UsingSelect(s *SelectBuilder) *MergeBuilder {
+	if s.err != nil {
+		m.fail(s.err)
+	}
+	m.query.WriteString(" USING (")
+	m.query.WriteString(s.query.String())
+	m.query.WriteByte(')')
+	return m
+}
+
+//UsingJoin sets the rows selected by j as the source of the rows
+//to merge, As should be called after UsingJoin to name them.
+func (m *MergeBuilder) UsingJoin(j *JoinBuilder) *MergeBuilder {
+	return m.UsingSelect(j.s)
+}
+
+//UsingValues sets an inline VALUES list as the source of the rows to merge,
+//alias names the list and fields its columns.
+//Each row maps integers(allows for proper ordering) to the values of fields.
+//
+//Usage example:
+//	UsingValues("src", []string{"ProductID", "Quantity"},
+//		map[int]interface{}{0: 1, 1: 40},
+//		map[int]interface{}{0: 2, 1: 15},
+//	)
+func (m *MergeBuilder) UsingValues(alias string, fields []string, rows ...map[int]interface{}) *MergeBuilder {
+	m.query.WriteString(" USING (VALUES")
+	for ix, row := range rows {
+		if ix > 0 {
+			m.query.WriteString(",")
+		}
+		m.query.WriteByte('(')
+		values(&m.query, row)
+		m.query.WriteByte(')')
+	}
+	m.query.WriteString(") AS ")
+	m.query.WriteString(m.name(alias))
+	addFields(&m.query, "", true, m.names(fields)...)
+	return m
+}
+
+//On adds the condition matching source rows to target rows
+func (m *MergeBuilder) On(condition string) *MergeBuilder {
+	m.query.WriteString(" ON ")
+	m.query.WriteString(condition)
+	return m
+}
+
+//WhenMatched adds a WHEN MATCHED clause to the builder's query,
+//ThenUpdate or ThenDelete MUST be called after WhenMatched.
+func (m *MergeBuilder) WhenMatched() *MergeBuilder {
+	m.query.WriteString(" WHEN MATCHED")
+	return m
+}
+
+//WhenMatchedAnd adds a WHEN MATCHED clause only applying
+//to rows that also meet condition
+func (m *MergeBuilder) WhenMatchedAnd(condition string) *MergeBuilder {
+	m.query.WriteString(" WHEN MATCHED")
+	and(&m.query, condition)
+	return m
+}
+
+//WhenNotMatched adds a WHEN NOT MATCHED clause to the builder's query,
+//ThenInsert MUST be called after WhenNotMatched.
+func (m *MergeBuilder) WhenNotMatched() *MergeBuilder {
+	m.query.WriteString(" WHEN NOT MATCHED")
+	return m
+}
+
+//WhenNotMatchedAnd adds a WHEN NOT MATCHED clause only applying
+//to rows that also meet condition
+func (m *MergeBuilder) WhenNotMatchedAnd(condition string) *MergeBuilder {
+	m.query.WriteString(" WHEN NOT MATCHED")
+	and(&m.query, condition)
+	return m
+}
+
+//ThenUpdate updates matched rows by setting fields to their new values,
+//e.g ThenUpdate("Quantity=src.Quantity", "DateModified=now()")
+func (m *MergeBuilder) ThenUpdate(fields ...string) *MergeBuilder {
+	m.query.WriteString(" THEN UPDATE")
+	addFields(&m.query, " SET", false, fields...)
+	return m
+}
+
+//ThenDelete deletes matched rows
+func (m *MergeBuilder) ThenDelete() *MergeBuilder {
+	m.query.WriteString(" THEN DELETE")
+	return m
+}
+
+//ThenInsert inserts rows that aren't matched,
+//Values MUST be called after ThenInsert.
+func (m *MergeBuilder) ThenInsert(fields ...string) *MergeBuilder {
+	m.query.WriteString(" THEN INSERT")
+	addFields(&m.query, "", true, m.names(fields)...)
+	return m
+}
+
+//Values adds the values inserted for each field passed to ThenInsert,
+//values are added verbatim, so string values MUST be quoted with single-quotes.
+func (m *MergeBuilder) Values(values ...string) *MergeBuilder {
+	addFields(&m.query, " VALUES", true, values...)
+	return m
+}
+
+//Comment appends the sqlcommenter comment of ctx to the query: the tags
+//set by WithTag and AddCommentSource, and the file:line Comment is called
+//from, e.g /*caller='orders%2Fstore.go%3A42',route='%2Forders'*/.
+//It should be called once the query is complete.
+func (m *MergeBuilder) Comment(ctx context.Context) *MergeBuilder {
+	m.query.WriteByte(' ')
+	m.query.WriteString(comment(ctx))
+	return m
+}
+
+//Bind renders the builder's query with named parameters replaced by the
+//dialect's positional placeholders, returning the values of params in
+//the same order. Names without a value and values not used by any name
+//are reported by a *BindError.
+func (m *MergeBuilder) Bind(params map[string]interface{}) (string, []interface{}, error) {
+	if err := m.Err(); err != nil {
+		return "", nil, err
+	}
+	return bindMap(m.sql(), m.dialect, params)
+}
+
+//BindStruct is like Bind, taking values from the fields of v, a struct
+//or a pointer to one. Fields are named after their db tag if they have one.
+func (m *MergeBuilder) BindStruct(v interface{}) (string, []interface{}, error) {
+	if err := m.Err(); err != nil {
+		return "", nil, err
+	}
+	return bindStruct(m.sql(), m.dialect, v)
+}
+
+//Debug binds params like Bind, then renders the query with the values
+//interpolated by Debug, for logs. It must never be executed.
+func (m *MergeBuilder) Debug(params map[string]interface{}, opts DebugOptions) (string, error) {
+	qry, args, err := m.Bind(params)
+	if err != nil {
+		return "", err
+	}
+	return Debug(qry, args, opts), nil
+}
+
+//Exec binds params to the builder's query like Bind and runs it on ex,
+//a *sql.DB, *sql.Conn or *sql.Tx. The hooks registered by AddHook are called around it.
+func (m *MergeBuilder) Exec(ctx context.Context, ex Executor, params map[string]interface{}) (sql.Result, error) {
+	return NewRunner(ex).Exec(ctx, m, params)
+}
+
+//Compile renders the builder's query into a Template, whose named
+//parameters are bound to new values on each execution.
+//The error Err would return is returned by the Template's Args, ArgsStruct and Stmt.
+func (m *MergeBuilder) Compile() *Template {
+	t := compile(m.sql(), m.dialect)
+	if err := m.Err(); err != nil {
+		t.err = err
+	}
+	return t
+}
+
+//Clear erases the builder's query
+func (m *MergeBuilder) Clear() {
+	m.query.Reset()
+	m.err = nil
+}
+
+//String returns the builder's query, with the values of an Expr rendered as ?
+func (m *MergeBuilder) String() string {
+	return exprPlaceholders(m.sql())
+}
+
+//sql returns the builder's query, as bound by Bind
+func (m *MergeBuilder) sql() string {
+	return m.query.String() + ";"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (m *MergeBuilder) Pretty() string {
+	return Format(m.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query
+func (m *MergeBuilder) Fingerprint() Fingerprint {
+	return FingerprintSQL(m.String())
+}
@@ -0,0 +1,90 @@
+package migrate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Command runs the migration command line described by args against m,
+// writing its output to out. It is meant to be called from a main package
+// which links in the database driver:
+//
+//	m := migrate.New(db, query.Postgres)
+//	m.LoadDir("migrations")
+//	if err := m.Command(ctx, os.Args[1:], os.Stdout); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// The supported commands are:
+//
+//	up [version]   apply pending migrations, up to version if given
+//	down [steps]   revert the last steps migrations, 1 by default
+//	status         list migrations and when they were applied
+//
+// Passing -dry-run before the command prints the SQL instead of executing it.
+func (m *Migrator) Command(ctx context.Context, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "print the SQL instead of executing it")
+	fs.Usage = func() {
+		fmt.Fprintln(out, "usage: migrate [-dry-run] up [version] | down [steps] | status")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *dryRun {
+		defer func(w io.Writer) { m.DryRun = w }(m.DryRun)
+		m.DryRun = out
+	}
+
+	cmd, rest := fs.Arg(0), fs.Args()
+	if len(rest) > 0 {
+		rest = rest[1:]
+	}
+	if len(rest) > 1 {
+		fs.Usage()
+		return fmt.Errorf("migrate: too many arguments to %s", cmd)
+	}
+
+	switch cmd {
+	case "up":
+		version := int64(-1)
+		if len(rest) == 1 {
+			v, err := strconv.ParseInt(rest[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("migrate: invalid version %q", rest[0])
+			}
+			version = v
+		}
+		return m.Up(ctx, version)
+	case "down":
+		steps := 1
+		if len(rest) == 1 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate: invalid number of steps %q", rest[0])
+			}
+			steps = n
+		}
+		return m.Down(ctx, steps)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	}
+
+	fs.Usage()
+	return fmt.Errorf("migrate: unknown command %q", cmd)
+}
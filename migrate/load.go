@@ -0,0 +1,92 @@
+package migrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LoadDir registers the .sql migrations found in dir.
+//
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// e.g 0003_add_store_index.up.sql. Each file is sent to the database as a
+// single statement, so drivers needing an option to accept several
+// statements at once (like MySQL's multiStatements) must have it enabled.
+func (m *Migrator) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	byVersion := map[int64]*Migration{}
+	var versions []int64
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".sql") {
+			continue
+		}
+		version, name, up, err := parseFileName(f.Name())
+		if err != nil {
+			return err
+		}
+
+		src, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: name}
+			byVersion[version] = mig
+			versions = append(versions, version)
+		} else if mig.Name != name {
+			return fmt.Errorf("migrate: version %d is used by both %s and %s", version, mig.Name, name)
+		}
+
+		step := sqlStep(SQL(src))
+		if up {
+			mig.Up = step
+		} else {
+			mig.Down = step
+		}
+	}
+
+	for _, v := range versions {
+		if err := m.Add(*byVersion[v]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFileName splits a migration file name into its parts
+func parseFileName(file string) (version int64, name string, up bool, err error) {
+	base := strings.TrimSuffix(file, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		up = true
+		base = strings.TrimSuffix(base, ".up")
+	case strings.HasSuffix(base, ".down"):
+		base = strings.TrimSuffix(base, ".down")
+	default:
+		return 0, "", false, fmt.Errorf("migrate: %s is neither an .up.sql nor a .down.sql file", file)
+	}
+
+	ix := strings.IndexByte(base, '_')
+	if ix < 0 {
+		return 0, "", false, fmt.Errorf("migrate: %s is not named <version>_<name>", file)
+	}
+	version, err = strconv.ParseInt(base[:ix], 10, 64)
+	if err != nil {
+		return 0, "", false, fmt.Errorf("migrate: %s has an invalid version: %v", file, err)
+	}
+	return version, base[ix+1:], up, nil
+}
+
+func sqlStep(stmt Statement) func(tx *Tx) error {
+	return func(tx *Tx) error {
+		return tx.Exec(stmt)
+	}
+}
@@ -0,0 +1,481 @@
+// Package migrate runs schema migrations written as Go functions using the
+// builders of package query or as .sql files.
+//
+// Applied versions are recorded in a bookkeeping table, and an advisory lock
+// is held for the duration of a run so that concurrent deployments don't
+// apply the same migration twice.
+//
+// The package ships no migrate binary, as it has to link in the database
+// driver: Migrator.Command implements the command line for a main package
+// of your own.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/danvixent/query"
+)
+
+// DefaultTable is the bookkeeping table used when Migrator.Table is empty
+const DefaultTable = "schema_migrations"
+
+// lockName identifies the advisory lock taken by a Migrator
+const lockName = "query.migrate"
+
+// Statement is anything rendering to SQL, every builder of package query is one
+type Statement interface {
+	String() string
+}
+
+// SQL is a raw SQL Statement
+type SQL string
+
+func (s SQL) String() string {
+	return string(s)
+}
+
+// Migration is a single schema change, identified by its version
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *Tx) error
+	Down    func(tx *Tx) error
+	// NoTx runs the migration outside a transaction,
+	// as needed by e.g CREATE INDEX CONCURRENTLY on Postgres
+	NoTx bool
+}
+
+// Status reports whether a migration has been applied
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Tx executes the statements of a migration through the hooks registered
+// by query.AddHook, in dry-run mode statements are written out instead.
+type Tx struct {
+	ctx context.Context
+	ex  query.Executor
+	dry io.Writer
+}
+
+// unbound is a Statement run as rendered by String, without parameters
+type unbound struct {
+	Statement
+}
+
+func (s unbound) Bind(map[string]interface{}) (string, []interface{}, error) {
+	return s.String(), nil, nil
+}
+
+// bound is a builder bound to params when executed
+type bound struct {
+	builder interface {
+		Statement
+		query.Binder
+	}
+	params map[string]interface{}
+}
+
+func (s bound) String() string {
+	return s.builder.String()
+}
+
+func (s bound) Bind(map[string]interface{}) (string, []interface{}, error) {
+	return s.builder.Bind(s.params)
+}
+
+// Exec executes stmts in order, stopping at the first error, which
+// includes the error reported by the Err method of builders.
+// Builders are executed as bound without parameters, in dry-run mode
+// their args are interpolated as by query.Debug.
+func (t *Tx) Exec(stmts ...Statement) error {
+	for _, s := range stmts {
+		if b, ok := s.(interface{ Err() error }); ok && b.Err() != nil {
+			return b.Err()
+		}
+		b, ok := s.(query.Binder)
+		if !ok {
+			b = unbound{s}
+		}
+		if t.dry != nil {
+			qry, args, err := b.Bind(nil)
+			if err != nil {
+				return err
+			}
+			if len(args) > 0 {
+				qry = query.Debug(qry, args, query.DebugOptions{})
+			}
+			if _, err := fmt.Fprintln(t.dry, qry); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := query.NewRunner(t.ex).Exec(t.ctx, b, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Context returns the context the migration runs with
+func (t *Tx) Context() context.Context {
+	return t.ctx
+}
+
+// DryRun reports whether statements are only being written out,
+// migrations doing anything besides calling Exec should check it.
+func (t *Tx) DryRun() bool {
+	return t.dry != nil
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	DB      *sql.DB
+	Dialect query.Dialect
+	// Table is the bookkeeping table, DefaultTable when empty
+	Table string
+	// DryRun, when set, receives the SQL that would be executed
+	// and nothing is executed nor recorded.
+	DryRun io.Writer
+
+	migrations []Migration
+}
+
+// New returns a Migrator applying migrations to db
+func New(db *sql.DB, d query.Dialect) *Migrator {
+	return &Migrator{DB: db, Dialect: d}
+}
+
+// Add registers migrations, versions must be unique
+func (m *Migrator) Add(migrations ...Migration) error {
+	for _, mig := range migrations {
+		for _, existing := range m.migrations {
+			if existing.Version == mig.Version {
+				return fmt.Errorf("migrate: duplicate version %d (%s and %s)", mig.Version, existing.Name, mig.Name)
+			}
+		}
+		m.migrations = append(m.migrations, mig)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+	return nil
+}
+
+// Migrations returns the registered migrations ordered by version
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return DefaultTable
+	}
+	return m.Table
+}
+
+// Up applies every pending migration up to and including version,
+// all pending migrations are applied when version is negative.
+func (m *Migrator) Up(ctx context.Context, version int64) error {
+	return m.run(ctx, func(s *session, applied map[int64]bool) error {
+		for _, mig := range m.migrations {
+			if applied[mig.Version] || (version >= 0 && mig.Version > version) {
+				continue
+			}
+			if mig.Up == nil {
+				return fmt.Errorf("migrate: %d_%s has no up migration", mig.Version, mig.Name)
+			}
+			record := query.NewInsertBuilder().WithDialect(m.Dialect).Insert(m.table()).Fields("version", "name", "applied_at").
+				Values(strconv.FormatInt(mig.Version, 10), query.Named("name").String(), "CURRENT_TIMESTAMP")
+			if err := s.apply(mig, mig.Up, bound{record, map[string]interface{}{"name": mig.Name}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.run(ctx, func(s *session, applied map[int64]bool) error {
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			mig := m.migrations[i]
+			if !applied[mig.Version] {
+				continue
+			}
+			if mig.Down == nil {
+				return fmt.Errorf("migrate: %d_%s has no down migration", mig.Version, mig.Name)
+			}
+			record := query.NewDeleteBuilder().WithDialect(m.Dialect).Delete(m.table()).Where(query.Eq("version", mig.Version))
+			if err := s.apply(mig, mig.Down, record); err != nil {
+				return err
+			}
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status lists every registered migration and when it was applied.
+// applied_at is read as returned by the driver, a time.Time or its text,
+// so MySQL doesn't need parseTime=true.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	appliedAt := map[int64]time.Time{}
+	if m.DB != nil {
+		rows, err := m.DB.QueryContext(ctx, query.NewSelectBuilder().Select("version", "applied_at").From(m.table()).String())
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var v int64
+			var raw interface{}
+			if err := rows.Scan(&v, &raw); err != nil {
+				return nil, err
+			}
+			at, err := appliedTime(raw)
+			if err != nil {
+				return nil, fmt.Errorf("migrate: version %d: %v", v, err)
+			}
+			appliedAt[v] = at
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := appliedAt[mig.Version]; ok {
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// timeLayouts are the layouts of timestamps returned as text by drivers
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	time.RFC3339Nano,
+}
+
+// appliedTime converts raw, an applied_at value scanned as is, to a time.Time
+func appliedTime(raw interface{}) (time.Time, error) {
+	var text string
+	switch v := raw.(type) {
+	case time.Time:
+		return v, nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return time.Time{}, fmt.Errorf("unsupported applied_at value %T", raw)
+	}
+	for _, layout := range timeLayouts {
+		if at, err := time.Parse(layout, text); err == nil {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid applied_at value %q", text)
+}
+
+// session is a single locked run of a Migrator
+type session struct {
+	ctx  context.Context
+	m    *Migrator
+	conn *sql.Conn
+}
+
+// run locks the database, makes sure the bookkeeping table exists
+// and hands the applied versions to fn.
+func (m *Migrator) run(ctx context.Context, fn func(s *session, applied map[int64]bool) error) error {
+	s := &session{ctx: ctx, m: m}
+	if m.DryRun != nil {
+		applied, err := s.dryRunApplied()
+		if err != nil {
+			return err
+		}
+		return fn(s, applied)
+	}
+	if m.DB == nil {
+		return errors.New("migrate: Migrator has no DB")
+	}
+
+	// advisory locks are held by a session, so everything
+	// runs on a single connection
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	s.conn = conn
+
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.unlock()
+
+	if _, err := conn.ExecContext(ctx, m.createTable().String()); err != nil {
+		return fmt.Errorf("migrate: creating %s: %v", m.table(), err)
+	}
+	applied, err := s.applied()
+	if err != nil {
+		return err
+	}
+	return fn(s, applied)
+}
+
+func (m *Migrator) createTable() *query.CreateTableBuilder {
+	timestamp := "timestamp"
+	if m.Dialect == query.SQLServer {
+		timestamp = "datetime2"
+	}
+	return query.NewCreateTableBuilder().WithDialect(m.Dialect).CreateTable(m.table()).IfNotExists().
+		Column("version", "bigint").NotNull().
+		Column("name", "varchar(255)").NotNull().
+		Column("applied_at", timestamp).NotNull().
+		PrimaryKey("version")
+}
+
+func (s *session) applied() (map[int64]bool, error) {
+	rows, err := s.conn.QueryContext(s.ctx, query.NewSelectBuilder().Select("version").From(s.m.table()).String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// dryRunApplied reads applied versions without creating the bookkeeping
+// table, a table that can't be read is treated as empty.
+func (s *session) dryRunApplied() (map[int64]bool, error) {
+	fmt.Fprintln(s.m.DryRun, s.m.createTable().String())
+	if s.m.DB == nil {
+		return map[int64]bool{}, nil
+	}
+
+	conn, err := s.m.DB.Conn(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	s.conn = conn
+
+	applied, err := s.applied()
+	if err != nil {
+		fmt.Fprintln(s.m.DryRun, "-- "+s.m.table()+" is unreadable, assuming no migration is applied: "+err.Error())
+		return map[int64]bool{}, nil
+	}
+	return applied, nil
+}
+
+// apply runs step for mig and record, in a single transaction unless mig.NoTx is set
+func (s *session) apply(mig Migration, step func(tx *Tx) error, record Statement) error {
+	wrap := func(err error) error {
+		return fmt.Errorf("migrate: %d_%s: %v", mig.Version, mig.Name, err)
+	}
+
+	if s.m.DryRun != nil {
+		fmt.Fprintln(s.m.DryRun, "-- "+strconv.FormatInt(mig.Version, 10)+"_"+mig.Name)
+		tx := &Tx{ctx: s.ctx, dry: s.m.DryRun}
+		if err := step(tx); err != nil {
+			return wrap(err)
+		}
+		return tx.Exec(record)
+	}
+
+	if mig.NoTx {
+		tx := &Tx{ctx: s.ctx, ex: s.conn}
+		if err := step(tx); err != nil {
+			return wrap(err)
+		}
+		if err := tx.Exec(record); err != nil {
+			return wrap(err)
+		}
+		return nil
+	}
+
+	sqlTx, err := s.conn.BeginTx(s.ctx, nil)
+	if err != nil {
+		return wrap(err)
+	}
+	tx := &Tx{ctx: s.ctx, ex: sqlTx}
+	if err := step(tx); err != nil {
+		sqlTx.Rollback()
+		return wrap(err)
+	}
+	if err := tx.Exec(record); err != nil {
+		sqlTx.Rollback()
+		return wrap(err)
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return wrap(err)
+	}
+	return nil
+}
+
+func (s *session) lock() error {
+	var stmt string
+	switch s.m.Dialect {
+	case query.MySQL:
+		// GET_LOCK returns 1 once the lock is held, waiting at most a day
+		var ok sql.NullInt64
+		if err := s.conn.QueryRowContext(s.ctx, "SELECT GET_LOCK('"+lockName+"',86400)").Scan(&ok); err != nil {
+			return fmt.Errorf("migrate: acquiring lock: %v", err)
+		}
+		if ok.Int64 != 1 {
+			return errors.New("migrate: timed out acquiring lock")
+		}
+		return nil
+	case query.SQLServer:
+		stmt = "EXEC sp_getapplock @Resource='" + lockName + "',@LockMode='Exclusive',@LockOwner='Session',@LockTimeout=-1"
+	default:
+		stmt = "SELECT pg_advisory_lock(" + lockKey() + ")"
+	}
+	if _, err := s.conn.ExecContext(s.ctx, stmt); err != nil {
+		return fmt.Errorf("migrate: acquiring lock: %v", err)
+	}
+	return nil
+}
+
+func (s *session) unlock() {
+	var stmt string
+	switch s.m.Dialect {
+	case query.MySQL:
+		stmt = "SELECT RELEASE_LOCK('" + lockName + "')"
+	case query.SQLServer:
+		stmt = "EXEC sp_releaseapplock @Resource='" + lockName + "',@LockOwner='Session'"
+	default:
+		stmt = "SELECT pg_advisory_unlock(" + lockKey() + ")"
+	}
+	// the lock is released with the session anyway, should this fail
+	s.conn.ExecContext(context.Background(), stmt)
+}
+
+// lockKey derives the numeric key Postgres advisory locks use from lockName
+func lockKey() string {
+	h := fnv.New64a()
+	h.Write([]byte(lockName))
+	return strconv.FormatInt(int64(h.Sum64()), 10)
+}
@@ -0,0 +1,145 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danvixent/query"
+	"github.com/danvixent/query/querytest"
+)
+
+func TestLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"0002_add_email.up.sql":      "ALTER TABLE Person.Contact ADD COLUMN Email varchar(100);",
+		"0002_add_email.down.sql":    "ALTER TABLE Person.Contact DROP COLUMN Email;",
+		"0001_create_contact.up.sql": "CREATE TABLE Person.Contact (ContactID int);",
+		"README.md":                  "not a migration",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := New(nil, query.Postgres)
+	if err := m.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	migs := m.Migrations()
+	if len(migs) != 2 || migs[0].Version != 1 || migs[1].Name != "add_email" {
+		t.Fatalf("got migrations %+v", migs)
+	}
+	if migs[0].Down != nil || migs[1].Down == nil {
+		t.Error("only 0002_add_email should have a down migration")
+	}
+
+	if err := m.LoadDir(dir); err == nil {
+		t.Error("loading the same versions twice should fail")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var out bytes.Buffer
+	m := New(nil, query.Postgres)
+	m.Add(Migration{
+		Version: 1,
+		Name:    "create_store",
+		Up: func(tx *Tx) error {
+			return tx.Exec(
+				query.NewCreateTableBuilder().CreateTable("Sales.Store").Column("StoreID", "int").PrimaryKey("StoreID"),
+				query.NewCreateIndexBuilder().CreateIndex("ix_store").On("Sales.Store", "StoreID"),
+			)
+		},
+	})
+	m.Add(Migration{
+		Version: 2,
+		Name:    "fix_o'neil",
+		Up: func(tx *Tx) error {
+			return tx.Exec(SQL("UPDATE Person.Contact SET LastName='O''Neil' WHERE ContactID=7"))
+		},
+	})
+
+	if err := m.Command(context.Background(), []string{"-dry-run", "up"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL,name varchar(255) NOT NULL,applied_at timestamp NOT NULL,PRIMARY KEY (version));\n" +
+		"-- 1_create_store\n" +
+		"CREATE TABLE Sales.Store (StoreID int,PRIMARY KEY (StoreID));\n" +
+		"CREATE INDEX ix_store ON Sales.Store (StoreID);\n" +
+		query.DebugMarker + "INSERT INTO schema_migrations (version,name,applied_at) VALUES (1,'create_store',CURRENT_TIMESTAMP);\n" +
+		"-- 2_fix_o'neil\n" +
+		"UPDATE Person.Contact SET LastName='O''Neil' WHERE ContactID=7\n" +
+		query.DebugMarker + "INSERT INTO schema_migrations (version,name,applied_at) VALUES (2,'fix_o''neil',CURRENT_TIMESTAMP);\n"
+	if got := out.String(); got != want {
+		t.Errorf("got = {%v} \n want = {%v}", got, want)
+	}
+	if m.DryRun != nil {
+		t.Error("-dry-run should only apply to a single command")
+	}
+
+	m = New(nil, query.MySQL)
+	m.Add(Migration{
+		Version: 1,
+		Name:    "partial_index",
+		Up: func(tx *Tx) error {
+			return tx.Exec(query.NewCreateIndexBuilder().WithDialect(query.MySQL).CreateIndex("ix_store").
+				On("Sales.Store", "StoreID").Where("StoreID>0"))
+		},
+	})
+	if err := m.Command(context.Background(), []string{"-dry-run", "up"}, &out); err == nil {
+		t.Error("a statement whose builder has an error should fail")
+	}
+}
+
+func TestUpAndStatus(t *testing.T) {
+	rec := querytest.New()
+	db := rec.DB()
+	defer db.Close()
+
+	m := New(db, query.MySQL)
+	m.Add(Migration{Version: 1, Name: "create_store", Up: func(tx *Tx) error { return nil }})
+	m.Add(Migration{
+		Version: 2,
+		Name:    "fix_o'neil",
+		Up: func(tx *Tx) error {
+			return tx.Exec(query.NewUpdateBuilder().WithDialect(query.MySQL).Update("Person.Contact").
+				SetFromMap(map[int]interface{}{0: query.Eq("LastName", query.Expr("?", "O'Neil"))}).Where(query.Eq("ContactID", 7)))
+		},
+	})
+
+	rec.Expect("SELECT GET_LOCK('query.migrate',86400)").WillReturnRows(querytest.NewRows("ok").AddRow(1))
+	rec.ExpectRegexp(`^CREATE TABLE IF NOT EXISTS schema_migrations `)
+	rec.Expect("SELECT version FROM schema_migrations;").WillReturnRows(querytest.NewRows("version").AddRow(1))
+	rec.Expect("UPDATE Person.Contact SET LastName=? WHERE ContactID=7;").WithArgs("O'Neil").WillReturnResult(0, 1)
+	rec.Expect("INSERT INTO schema_migrations (version,name,applied_at) VALUES (2,?,CURRENT_TIMESTAMP);").WithArgs("fix_o'neil")
+	rec.Expect("SELECT RELEASE_LOCK('query.migrate')")
+	if err := m.Up(context.Background(), -1); err != nil {
+		t.Fatal(err)
+	}
+
+	// MySQL returns DATETIME values as text unless parseTime=true
+	rec.Expect("SELECT version,applied_at FROM schema_migrations;").
+		WillReturnRows(querytest.NewRows("version", "applied_at").AddRow(1, []byte("2026-10-19 16:44:02")).AddRow(2, time.Date(2026, 10, 19, 16, 45, 0, 0, time.UTC)))
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].AppliedAt == nil || statuses[0].AppliedAt.Format("15:04:05") != "16:44:02" ||
+		statuses[1].AppliedAt == nil || statuses[1].AppliedAt.Minute() != 45 {
+		t.Errorf("got statuses %+v", statuses)
+	}
+	if err := rec.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
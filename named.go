@@ -0,0 +1,186 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//NamedParam is a named parameter, rendered as :name
+//until it is bound to a value by Bind or BindStruct.
+type NamedParam string
+
+//Named returns a named parameter to be used as a value,
+//e.g Eq("StoreID", Named("store")) renders StoreID=:store
+func Named(name string) NamedParam {
+	return NamedParam(name)
+}
+
+func (n NamedParam) String() string {
+	return ":" + string(n)
+}
+
+//BindError reports the named parameters of a query missing from
+//the values bound to it, and the values bound to no parameter.
+type BindError struct {
+	Missing []string
+	Unused  []string
+}
+
+func (e *BindError) Error() string {
+	var msg []string
+	if len(e.Missing) > 0 {
+		msg = append(msg, "missing values for "+strings.Join(e.Missing, ","))
+	}
+	if len(e.Unused) > 0 {
+		msg = append(msg, "unused values for "+strings.Join(e.Unused, ","))
+	}
+	return "query: " + strings.Join(msg, ", ")
+}
+
+//placeholder returns the n-th (starting from 1) positional placeholder of d
+func (d Dialect) placeholder(n int) string {
+	switch d {
+	case MySQL:
+		return "?"
+	case SQLServer:
+		return "@p" + strconv.Itoa(n)
+	}
+	return "$" + strconv.Itoa(n)
+}
+
+//bindMap replaces the named parameters of qry with positional placeholders
+//of d, both names params has no value for and values no name uses are reported.
+func bindMap(qry string, d Dialect, params map[string]interface{}) (string, []interface{}, error) {
+	used := map[string]bool{}
+	var failed error
+	qry, args, err := bindNamed(qry, d, withExprValues(func(name string) (interface{}, bool) {
+		v, ok := params[name]
+		used[name] = true
+		return v, ok
+	}, &failed))
+	if failed != nil {
+		return "", nil, failed
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	var unused []string
+	for name := range params {
+		if !used[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return "", nil, &BindError{Unused: unused}
+	}
+	return qry, args, nil
+}
+
+//bindStruct replaces the named parameters of qry with positional placeholders
+//of d, taking values from the fields of v, which must be a struct or a pointer to one.
+func bindStruct(qry string, d Dialect, v interface{}) (string, []interface{}, error) {
+	lookup, err := structLookup(v)
+	if err != nil {
+		return "", nil, err
+	}
+	var failed error
+	qry, args, err := bindNamed(qry, d, withExprValues(lookup, &failed))
+	if failed != nil {
+		return "", nil, failed
+	}
+	return qry, args, err
+}
+
+//structLookup returns a function looking up the fields of v by name,
+//v must be a struct or a pointer to one. A field is named after its db tag,
+//or its own name when it has none, fields no parameter uses aren't reported.
+func structLookup(v interface{}) (func(name string) (interface{}, bool), error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("query: BindStruct expects a struct, got " + rv.Kind().String())
+	}
+
+	fields := map[string]int{}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("db"), ",")[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		fields[name] = i
+	}
+
+	return func(name string) (interface{}, bool) {
+		i, ok := fields[name]
+		if !ok {
+			return nil, false
+		}
+		return rv.Field(i).Interface(), true
+	}, nil
+}
+
+//bindNamed replaces every :name in qry with the next positional placeholder
+//of d, ignoring quoted strings and identifiers, comments and Postgres' :: casts.
+func bindNamed(qry string, d Dialect, lookup func(name string) (interface{}, bool)) (string, []interface{}, error) {
+	var b strings.Builder
+	b.Grow(len(qry))
+	var args []interface{}
+	var missing []string
+
+	copied := 0
+	for _, tok := range tokenize(qry) {
+		if tok.kind != fmtWord || len(tok.text) < 2 || tok.text[0] != ':' || !isNameStart(tok.text[1]) {
+			continue
+		}
+		end := 1
+		for end < len(tok.text) && isNamePart(tok.text[end]) {
+			end++
+		}
+		name := tok.text[1:end]
+		v, ok := lookup(name)
+		if !ok && !contains(missing, name) {
+			missing = append(missing, name)
+		}
+		args = append(args, v)
+		b.WriteString(qry[copied:tok.pos])
+		b.WriteString(d.placeholder(len(args)))
+		copied = tok.pos + end
+	}
+	b.WriteString(qry[copied:])
+
+	if len(missing) > 0 {
+		return "", nil, &BindError{Missing: missing}
+	}
+	return b.String(), args, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
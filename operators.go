@@ -1,5 +1,11 @@
 package query
 
+import "strings"
+
+// Operator is a function building a condition on field f with value v,
+// like Eq or G
+type Operator func(f string, v interface{}) string
+
 // Eq equates a f to v
 func Eq(f string, v interface{}) string {
 	return f + "=" + stringifyQuote(v)
@@ -32,6 +38,10 @@ func LEq(f string, v interface{}) string {
 
 // SubQry equates f to a subquery
 func SubQry(f string, v interface{ String() string }) string {
+	if b, ok := v.(interface{ sql() string }); ok {
+		// keep the values of an Expr, for Bind
+		return f + "=(" + b.sql() + ")"
+	}
 	return f + "=(" + v.String() + ")"
 }
 
@@ -56,3 +66,10 @@ func IsNotNull(v string) string {
 func And(v string) string {
 	return "AND " + v
 }
+
+// In adds an IN operator in-between f and the values in v
+func In(f string, v ...interface{}) string {
+	var b strings.Builder
+	in(&b, f, v)
+	return b.String()
+}
@@ -0,0 +1,686 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/danvixent/query/internal/sqlscan"
+)
+
+//Statement is a SELECT, INSERT, UPDATE or DELETE statement returned by Parse:
+//a *SelectStatement, *InsertStatement, *UpdateStatement or *DeleteStatement.
+//Its fields may be modified before it is rendered again with String,
+//or turned into a builder for a dialect.
+type Statement interface {
+	String() string
+	statement()
+}
+
+//ParseError reports SQL Parse can't handle, Pos is the byte offset
+//in the SQL where the problem was found.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return "query: " + e.Msg + " at offset " + strconv.Itoa(e.Pos)
+}
+
+//Parse splits sql, a single SELECT, INSERT, UPDATE or DELETE statement,
+//into its clauses. Tables, conditions and expressions are kept as written,
+//only lists like the selected fields are split on their top-level commas.
+//
+//Statements combined with UNION and the like, CTEs, and clauses the builders
+//don't render, such as ON CONFLICT or FOR UPDATE, are reported by a *ParseError.
+//
+//Usage example:
+//	stmt, err := Parse(legacySQL)
+//	if sel, ok := stmt.(*SelectStatement); ok {
+//		sel.AddWhere(Eq("TenantID", Named("tenant")))
+//		sel.Limit = "50"
+//		qry, args, err := sel.Builder(MySQL).Bind(params)
+//	}
+func Parse(sql string) (Statement, error) {
+	clauses, err := splitClauses(sql)
+	if err != nil {
+		return nil, err
+	}
+	if len(clauses) == 0 {
+		return nil, &ParseError{Pos: 0, Msg: "empty statement"}
+	}
+
+	switch clauses[0].kw {
+	case "SELECT":
+		return parseSelect(clauses)
+	case "INSERT INTO":
+		return parseInsert(clauses)
+	case "UPDATE":
+		return parseUpdate(clauses)
+	case "DELETE FROM":
+		return parseDelete(clauses)
+	}
+	return nil, &ParseError{Pos: clauses[0].pos, Msg: "expected SELECT, INSERT, UPDATE or DELETE"}
+}
+
+//SelectStatement is a parsed SELECT statement
+type SelectStatement struct {
+	Distinct bool
+	Fields   []string
+	//From holds the tables selected from, along with their joins
+	From    string
+	Where   string
+	GroupBy []string
+	Having  string
+	//OrderBy holds the terms of the ORDER BY clause, e.g OrderDate DESC
+	OrderBy []string
+	Limit   string
+	Offset  string
+}
+
+func (*SelectStatement) statement() {}
+
+//AddWhere ANDs condition with the statement's WHERE clause, which is
+//parenthesized first if it has a top-level OR.
+func (s *SelectStatement) AddWhere(condition string) {
+	s.Where = addCondition(s.Where, condition)
+}
+
+//Builder returns a *SelectBuilder for d whose query is the statement,
+//to be bound, compiled or extended with further clauses.
+func (s *SelectStatement) Builder(d Dialect) *SelectBuilder {
+	b := NewSelectBuilder().WithDialect(d)
+	b.query.WriteString("SELECT ")
+	if s.Distinct {
+		b.query.WriteString("DISTINCT ")
+	}
+	//SQL Server has no LIMIT, TOP limits the rows of an unordered query
+	top := d == SQLServer && s.Limit != "" && s.Offset == "" && len(s.OrderBy) == 0
+	if top {
+		b.query.WriteString("TOP (" + s.Limit + ") ")
+	}
+	b.query.WriteString(strings.Join(s.Fields, ","))
+	if s.From != "" {
+		b.From(s.From)
+	}
+	if s.Where != "" {
+		b.Where(s.Where)
+	}
+	if len(s.GroupBy) > 0 {
+		b.GroupBy(strings.Join(s.GroupBy, ","))
+	}
+	if s.Having != "" {
+		b.query.WriteString(" HAVING ")
+		b.query.WriteString(s.Having)
+	}
+	b.OrderBy(s.OrderBy...)
+	if !top {
+		paginate(&b.query, d, s.Limit, s.Offset, len(s.OrderBy) > 0)
+	}
+	return b
+}
+
+//paginate writes the clauses of d limiting a query to limit rows from offset,
+//either of which may be "". On SQL Server they are OFFSET ... ROWS FETCH NEXT
+//... ROWS ONLY, which need an ORDER BY clause, added if ordered is false.
+func paginate(b *strings.Builder, d Dialect, limit, offset string, ordered bool) {
+	switch {
+	case limit == "" && offset == "":
+	case d == SQLServer:
+		if !ordered {
+			b.WriteString(" ORDER BY (SELECT NULL)")
+		}
+		if offset == "" {
+			offset = "0"
+		}
+		b.WriteString(" OFFSET " + offset + " ROWS")
+		if limit != "" {
+			b.WriteString(" FETCH NEXT " + limit + " ROWS ONLY")
+		}
+	default:
+		if limit == "" && d == MySQL {
+			//MySQL has no OFFSET without LIMIT
+			limit = "18446744073709551615"
+		}
+		if limit != "" {
+			b.WriteString(" LIMIT " + limit)
+		}
+		if offset != "" {
+			b.WriteString(" OFFSET " + offset)
+		}
+	}
+}
+
+func (s *SelectStatement) String() string {
+	return s.Builder(Postgres).String()
+}
+
+//InsertStatement is a parsed INSERT statement, inserting either
+//the rows of Values or the rows selected by Select.
+type InsertStatement struct {
+	Table   string
+	Columns []string
+	//Values holds each row inserted, e.g ('Mr','George')
+	Values    []string
+	Select    *SelectStatement
+	Returning []string
+}
+
+func (*InsertStatement) statement() {}
+
+//Builder returns an *InsertBuilder for d whose query is the statement,
+//to be bound, compiled or extended with further clauses.
+func (s *InsertStatement) Builder(d Dialect) *InsertBuilder {
+	b := NewInsertBuilder().WithDialect(d).Insert(s.Table)
+	if len(s.Columns) > 0 {
+		b.Fields(s.Columns...)
+	}
+	if len(s.Values) > 0 {
+		b.query.WriteString(" VALUES")
+		b.query.WriteString(strings.Join(s.Values, ","))
+	}
+	if s.Select != nil {
+		b.FromSelect(s.Select.Builder(d))
+	}
+	if len(s.Returning) > 0 {
+		b.Returning(s.Returning...)
+	}
+	return b
+}
+
+func (s *InsertStatement) String() string {
+	return s.Builder(Postgres).String()
+}
+
+//UpdateStatement is a parsed UPDATE statement
+type UpdateStatement struct {
+	//Table is the updated table, e.g Sales.OrderHeader AS soh
+	Table string
+	//Joins holds the tables joined to Table by the JOIN of MySQL
+	Joins []UpdateJoin
+	//Set holds the assignments of the SET clause, e.g Quantity=Quantity+1
+	Set []string
+	//From holds the tables updated from, along with their joins.
+	//It is parsed from FROM, or from the tables following Table on MySQL.
+	From      string
+	Where     string
+	Returning []string
+
+	//dialect renders Joins and From as they were parsed
+	dialect Dialect
+}
+
+//UpdateJoin is a table joined to the updated one, rendered
+//in the form of each dialect like the UpdateBuilder's Join.
+type UpdateJoin struct {
+	//Table is the joined table, e.g Sales.OrderDetail AS sod
+	Table string
+	//On is the condition joining it, e.g soh.OrderID=sod.OrderID
+	On string
+}
+
+func (*UpdateStatement) statement() {}
+
+//AddWhere ANDs condition with the statement's WHERE clause, which is
+//parenthesized first if it has a top-level OR.
+func (s *UpdateStatement) AddWhere(condition string) {
+	s.Where = addCondition(s.Where, condition)
+}
+
+//Builder returns an *UpdateBuilder for d whose query is the statement,
+//to be bound, compiled or extended with further clauses. On MySQL,
+//the tables of From are rendered as a multi-table UPDATE.
+func (s *UpdateStatement) Builder(d Dialect) *UpdateBuilder {
+	b := NewUpdateBuilder().WithDialect(d)
+	alias := ""
+	if d == SQLServer && len(s.Joins) > 0 {
+		alias = tableAlias(s.Table)
+	}
+	if alias != "" {
+		//SQL Server updates the alias, its table is repeated in FROM
+		b.Update(alias).Set(strings.Join(s.Set, ",")).From(s.Table)
+	} else {
+		b.Update(s.Table).Set(strings.Join(s.Set, ","))
+	}
+	if s.From != "" {
+		b.From(splitList(s.From)...)
+	}
+	for _, j := range s.Joins {
+		b.Join(j.Table).on(j.On)
+	}
+	if s.Where != "" {
+		b.Where(s.Where)
+	}
+	if len(s.Returning) > 0 {
+		b.Returning(s.Returning...)
+	}
+	return b
+}
+
+func (s *UpdateStatement) String() string {
+	return s.Builder(s.dialect).String()
+}
+
+//DeleteStatement is a parsed DELETE statement
+type DeleteStatement struct {
+	Table string
+	//Using holds the other tables used by the statement, along with their joins.
+	//It is parsed from USING, or from the second FROM clause of SQL Server.
+	Using     string
+	Where     string
+	Returning []string
+
+	//dialect renders Using as it was parsed
+	dialect Dialect
+}
+
+func (*DeleteStatement) statement() {}
+
+//AddWhere ANDs condition with the statement's WHERE clause, which is
+//parenthesized first if it has a top-level OR.
+func (s *DeleteStatement) AddWhere(condition string) {
+	s.Where = addCondition(s.Where, condition)
+}
+
+//Builder returns a *DeleteBuilder for d whose query is the statement,
+//to be bound, compiled or extended with further clauses.
+func (s *DeleteStatement) Builder(d Dialect) *DeleteBuilder {
+	b := NewDeleteBuilder().WithDialect(d).Delete(s.Table)
+	if s.Using != "" {
+		b.Using(splitList(s.Using)...)
+	}
+	if s.Where != "" {
+		b.Where(s.Where)
+	}
+	if len(s.Returning) > 0 {
+		b.Returning(s.Returning...)
+	}
+	return b
+}
+
+func (s *DeleteStatement) String() string {
+	return s.Builder(s.dialect).String()
+}
+
+func parseSelect(clauses []clause) (*SelectStatement, error) {
+	s := new(SelectStatement)
+	fields := clauses[0].body
+	if w, rest := firstWord(fields); strings.EqualFold(w, "DISTINCT") {
+		s.Distinct = true
+		fields = rest
+	}
+	s.Fields = splitList(fields)
+
+	for _, c := range clauses[1:] {
+		var dst *string
+		switch c.kw {
+		case "FROM":
+			dst = &s.From
+		case "USING":
+			// JOIN ... USING (field)
+			if s.From == "" || s.Where != "" {
+				return nil, c.unexpected()
+			}
+			s.From += " USING " + c.body
+			continue
+		case "WHERE":
+			dst = &s.Where
+		case "GROUP BY":
+			if s.GroupBy != nil {
+				return nil, c.unexpected()
+			}
+			s.GroupBy = splitList(c.body)
+			continue
+		case "HAVING":
+			dst = &s.Having
+		case "ORDER BY":
+			if s.OrderBy != nil {
+				return nil, c.unexpected()
+			}
+			s.OrderBy = splitList(c.body)
+			continue
+		case "LIMIT":
+			//MySQL's LIMIT offset,count
+			if items := splitList(c.body); len(items) == 2 {
+				if s.Limit != "" || s.Offset != "" {
+					return nil, c.unexpected()
+				}
+				s.Offset, s.Limit = items[0], items[1]
+				continue
+			}
+			dst = &s.Limit
+		case "OFFSET":
+			dst = &s.Offset
+		default:
+			return nil, c.unexpected()
+		}
+		if *dst != "" {
+			return nil, c.unexpected()
+		}
+		*dst = c.body
+	}
+	return s, nil
+}
+
+func parseInsert(clauses []clause) (*InsertStatement, error) {
+	s := new(InsertStatement)
+	table := clauses[0].body
+	for _, tok := range tokenize(table) {
+		if tok.kind != fmtPunct || tok.text != "(" {
+			continue
+		}
+		cols := strings.TrimSpace(table[tok.pos:])
+		if !strings.HasSuffix(cols, ")") {
+			return nil, &ParseError{Pos: clauses[0].at + tok.pos, Msg: "malformed column list"}
+		}
+		s.Columns = splitList(cols[1 : len(cols)-1])
+		table = strings.TrimSpace(table[:tok.pos])
+		break
+	}
+	s.Table = table
+
+	for ix := 1; ix < len(clauses); ix++ {
+		c := clauses[ix]
+		switch {
+		case c.kw == "VALUES" && s.Values == nil && s.Select == nil:
+			s.Values = splitList(c.body)
+		case c.kw == "SELECT" && s.Values == nil && s.Select == nil:
+			end := ix + 1
+			for end < len(clauses) && clauses[end].kw != "RETURNING" {
+				end++
+			}
+			sel, err := parseSelect(clauses[ix:end])
+			if err != nil {
+				return nil, err
+			}
+			s.Select = sel
+			ix = end - 1
+		case c.kw == "RETURNING" && s.Returning == nil:
+			s.Returning = splitList(c.body)
+		default:
+			return nil, c.unexpected()
+		}
+	}
+	return s, nil
+}
+
+func parseUpdate(clauses []clause) (*UpdateStatement, error) {
+	s := new(UpdateStatement)
+	if err := parseUpdateTables(s, clauses[0]); err != nil {
+		return nil, err
+	}
+	for _, c := range clauses[1:] {
+		switch {
+		case c.kw == "SET" && s.Set == nil:
+			s.Set = splitList(c.body)
+		case c.kw == "FROM" && s.From == "" && s.Where == "" && s.dialect != MySQL:
+			s.From = c.body
+		case c.kw == "WHERE" && s.Where == "":
+			s.Where = c.body
+		case c.kw == "RETURNING" && s.Returning == nil:
+			s.Returning = splitList(c.body)
+		default:
+			return nil, c.unexpected()
+		}
+	}
+	if s.Set == nil {
+		return nil, &ParseError{Pos: clauses[0].pos, Msg: "UPDATE without SET"}
+	}
+	return s, nil
+}
+
+//parseUpdateTables parses the tables of the UPDATE clause c: the updated
+//table, followed on MySQL by the tables joined to it or by other tables
+//separated by commas, which are the tables updated from.
+func parseUpdateTables(s *UpdateStatement, c clause) error {
+	tables := splitList(c.body)
+	if len(tables) > 1 {
+		s.From = strings.Join(tables[1:], ",")
+		s.dialect = MySQL
+	}
+	table := tables[0]
+
+	depth, start := 0, 0
+	for ix, toks := 0, tokenize(table); ix < len(toks); ix++ {
+		tok := toks[ix]
+		switch {
+		case tok.kind == fmtPunct && tok.text == "(":
+			depth++
+		case tok.kind == fmtPunct && tok.text == ")":
+			depth--
+		}
+		if depth > 0 || tok.kind != fmtWord {
+			continue
+		}
+		pos := c.at + tok.pos
+		word := strings.ToUpper(tok.text)
+		if word == "INNER" && ix+1 < len(toks) && strings.EqualFold(toks[ix+1].text, "JOIN") {
+			ix++
+			word = "JOIN"
+		}
+		switch word {
+		case "JOIN":
+			switch {
+			case len(tables) > 1:
+				return &ParseError{Pos: pos, Msg: "unsupported JOIN along with other tables"}
+			case s.Joins == nil:
+				s.Table = strings.TrimSpace(table[:tok.pos])
+			case s.Joins[len(s.Joins)-1].Table == "":
+				return &ParseError{Pos: pos, Msg: "JOIN without ON"}
+			default:
+				s.Joins[len(s.Joins)-1].On = strings.TrimSpace(table[start:tok.pos])
+			}
+			s.Joins = append(s.Joins, UpdateJoin{})
+			start = toks[ix].pos + len(toks[ix].text)
+		case "ON":
+			if s.Joins == nil || s.Joins[len(s.Joins)-1].Table != "" {
+				return &ParseError{Pos: pos, Msg: "unexpected ON"}
+			}
+			s.Joins[len(s.Joins)-1].Table = strings.TrimSpace(table[start:tok.pos])
+			start = tok.pos + len(tok.text)
+		case "LEFT", "RIGHT", "FULL", "CROSS", "NATURAL", "STRAIGHT_JOIN":
+			return &ParseError{Pos: pos, Msg: "unsupported " + word + " in UPDATE"}
+		}
+	}
+
+	if s.Joins == nil {
+		s.Table = table
+		return nil
+	}
+	last := &s.Joins[len(s.Joins)-1]
+	if last.Table == "" {
+		return &ParseError{Pos: c.at + len(table), Msg: "JOIN without ON"}
+	}
+	last.On = strings.TrimSpace(table[start:])
+	s.dialect = MySQL
+	return nil
+}
+
+//tableAlias returns the alias of table, e.g soh for Sales.OrderHeader AS soh,
+//or "" if it has none
+func tableAlias(table string) string {
+	var toks []fmtToken
+	for _, tok := range tokenize(table) {
+		if tok.kind != fmtComment {
+			toks = append(toks, tok)
+		}
+	}
+	n := len(toks)
+	switch {
+	case n < 2 || !toks[n-1].space || toks[n-1].kind != fmtWord && toks[n-1].kind != fmtQuoted:
+		return ""
+	case n > 2 && strings.EqualFold(toks[n-2].text, "AS"), toks[n-2].kind != fmtPunct:
+		return toks[n-1].text
+	}
+	return ""
+}
+
+func parseDelete(clauses []clause) (*DeleteStatement, error) {
+	s := &DeleteStatement{Table: clauses[0].body}
+	for _, c := range clauses[1:] {
+		switch {
+		case (c.kw == "USING" || c.kw == "FROM") && s.Using == "" && s.Where == "":
+			s.Using = c.body
+			if c.kw == "FROM" {
+				s.dialect = SQLServer
+			}
+		case c.kw == "WHERE" && s.Where == "":
+			s.Where = c.body
+		case c.kw == "RETURNING" && s.Returning == nil:
+			s.Returning = splitList(c.body)
+		default:
+			return nil, c.unexpected()
+		}
+	}
+	return s, nil
+}
+
+//addCondition ANDs condition with where, parenthesizing
+//them as the builders' Where does
+func addCondition(where string, condition string) string {
+	if where == "" {
+		return condition
+	}
+	return parenthesizeOr(where) + " AND " + parenthesizeOr(condition)
+}
+
+//clause is a top-level clause of a statement: its keyword, normalized
+//to upper case and single spaces, and the text following it, which
+//starts at offset at of the statement
+type clause struct {
+	kw   string
+	body string
+	pos  int
+	at   int
+}
+
+func (c clause) unexpected() error {
+	return &ParseError{Pos: c.pos, Msg: "unexpected " + c.kw}
+}
+
+//clauseKeywords are the keywords starting a clause,
+//multi-word ones before the single words they start with
+var clauseKeywords = [][]string{
+	{"INSERT", "INTO"}, {"DELETE", "FROM"}, {"GROUP", "BY"}, {"ORDER", "BY"},
+	{"SELECT"}, {"FROM"}, {"WHERE"}, {"HAVING"}, {"LIMIT"}, {"OFFSET"},
+	{"VALUES"}, {"RETURNING"}, {"UPDATE"}, {"SET"}, {"USING"},
+}
+
+//unsupportedKeywords start clauses Parse rejects
+var unsupportedKeywords = [][]string{
+	{"ON", "CONFLICT"}, {"ON", "DUPLICATE"}, {"UNION"}, {"INTERSECT"}, {"EXCEPT"},
+	{"WITH"}, {"WINDOW"}, {"FETCH"}, {"FOR"}, {"OUTPUT"},
+}
+
+//splitClauses splits sql into its top-level clauses, comments
+//and a trailing semicolon are dropped.
+func splitClauses(sql string) ([]clause, error) {
+	sql, toks := blankComments(sql)
+	if n := len(toks); n > 0 && toks[n-1].kind == fmtPunct && toks[n-1].text == ";" {
+		sql, toks = sql[:toks[n-1].pos], toks[:n-1]
+	}
+
+	var clauses []clause
+	depth := 0
+	for ix := 0; ix < len(toks); ix++ {
+		tok := toks[ix]
+		switch {
+		case tok.kind == fmtPunct && tok.text == "(":
+			depth++
+		case tok.kind == fmtPunct && tok.text == ")":
+			depth--
+		}
+		if depth > 0 || tok.kind != fmtWord {
+			continue
+		}
+		if kw, _ := matchKeyword(toks[ix:], unsupportedKeywords); kw != "" {
+			return nil, &ParseError{Pos: tok.pos, Msg: "unsupported " + kw}
+		}
+		kw, n := matchKeyword(toks[ix:], clauseKeywords)
+		if kw == "" {
+			continue
+		}
+		if len(clauses) > 0 {
+			last := &clauses[len(clauses)-1]
+			last.body = strings.TrimSpace(sql[last.at:tok.pos])
+		} else if ix > 0 {
+			break
+		}
+		ix += n - 1
+		clauses = append(clauses, clause{kw: kw, pos: tok.pos, at: toks[ix].pos + len(toks[ix].text)})
+	}
+	if len(clauses) == 0 {
+		if len(toks) == 0 {
+			return nil, nil
+		}
+		return nil, &ParseError{Pos: 0, Msg: "expected SELECT, INSERT, UPDATE or DELETE"}
+	}
+	last := &clauses[len(clauses)-1]
+	last.body = strings.TrimSpace(sql[last.at:])
+	for ix := range clauses {
+		//offsets into the body are offsets into sql
+		clauses[ix].at += len(sql[clauses[ix].at:]) - len(strings.TrimLeft(sql[clauses[ix].at:], " \t\n\r"))
+	}
+	return clauses, nil
+}
+
+//matchKeyword returns the first of keywords the words of toks start with,
+//along with the number of tokens it spans, or "" if none is found.
+func matchKeyword(toks []fmtToken, keywords [][]string) (string, int) {
+	for _, words := range keywords {
+		if len(words) > len(toks) {
+			continue
+		}
+		matched := true
+		for ix, w := range words {
+			if toks[ix].kind != fmtWord || !strings.EqualFold(toks[ix].text, w) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return strings.Join(words, " "), len(words)
+		}
+	}
+	return "", 0
+}
+
+//blankComments replaces the comments of sql with spaces, so offsets
+//into the result are offsets into sql, returning its other tokens.
+func blankComments(sql string) (string, []fmtToken) {
+	var b []byte
+	var toks []fmtToken
+	for _, tok := range tokenize(sql) {
+		if tok.kind != fmtComment {
+			toks = append(toks, tok)
+			continue
+		}
+		if b == nil {
+			b = []byte(sql)
+		}
+		for ix := tok.pos; ix < tok.pos+len(tok.text); ix++ {
+			if b[ix] != '\n' {
+				b[ix] = ' '
+			}
+		}
+	}
+	if b == nil {
+		return sql, toks
+	}
+	return string(b), toks
+}
+
+//splitList splits list on its top-level commas, trimming each item
+func splitList(list string) []string {
+	return sqlscan.Split(list, ',')
+}
+
+//firstWord returns the first word of s and what follows it
+func firstWord(s string) (string, string) {
+	toks := tokenize(s)
+	if len(toks) == 0 || toks[0].kind != fmtWord {
+		return "", s
+	}
+	end := toks[0].pos + len(toks[0].text)
+	return s[toks[0].pos:end], strings.TrimSpace(s[end:])
+}
@@ -0,0 +1,50 @@
+package query
+
+import "strings"
+
+//preview returns a *SelectBuilder selecting cols from from,
+//followed by where, the WHERE clause of the query previewed if it has one.
+func preview(d Dialect, cols string, from string, where string) *SelectBuilder {
+	s := NewSelectBuilder().WithDialect(d)
+	s.query.WriteString("SELECT ")
+	s.query.WriteString(cols)
+	s.query.WriteString(" FROM ")
+	s.query.WriteString(from)
+	if where != "" {
+		s.markWhere()
+	}
+	s.query.WriteString(where)
+	return s
+}
+
+//previewFrom returns the tables of a preview of a query on table,
+//sources are the other tables the query uses as rendered in it:
+//a FROM or USING list, JOINs or a comma-separated list.
+//repeated is true when table is expected to be repeated in the list.
+func previewFrom(table string, sources string, repeated bool) string {
+	for _, kw := range []string{" FROM ", " USING "} {
+		if strings.HasPrefix(sources, kw) {
+			if repeated {
+				return sources[len(kw):]
+			}
+			return table + "," + sources[len(kw):]
+		}
+	}
+	return table + sources
+}
+
+//previewColumns returns the columns selected by a preview of a query on table
+func previewColumns(table string, sources string, count bool) string {
+	if count {
+		return "COUNT(*)"
+	}
+	if sources == "" {
+		return "*"
+	}
+	// qualify with the alias of table if it has one
+	f := strings.Fields(table)
+	if len(f) == 0 {
+		return "*"
+	}
+	return f[len(f)-1] + ".*"
+}
@@ -1,8 +1,21 @@
 package query
 
 import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/danvixent/query/querytest"
 )
 
 func TestUpdateBuilder_Update(t *testing.T) {
@@ -12,83 +25,1526 @@ func TestUpdateBuilder_Update(t *testing.T) {
 		exec func() string
 	}{
 		{
-			"update1",
-			"UPDATE Stock.Product SET ProductName='Powersuper Battery' WHERE ProductID=99;",
-			NewUpdateBuilder().Update("Stock.Product").Set(Eq("ProductName", "Powersuper Battery")).Where(Eq("ProductID", 99)).String,
+			"update1",
+			"UPDATE Stock.Product SET ProductName='Powersuper Battery' WHERE ProductID=99;",
+			NewUpdateBuilder().Update("Stock.Product").Set(Eq("ProductName", "Powersuper Battery")).Where(Eq("ProductID", 99)).String,
+		},
+		{
+			"update2",
+			"UPDATE Stock.ProductBarCodes SET Barcode='4353532242' WHERE BarcodeID=2;",
+			NewUpdateBuilder().Update("Stock.ProductBarCodes").Set(Eq("Barcode", "4353532242")).Where(Eq("BarcodeID", 2)).String,
+		},
+		{
+			"update3",
+			"UPDATE Stock.Product SET ProductName='Pakgen Bulbs' WHERE CategoryID=3 OR BarcodeID=22;",
+			NewUpdateBuilder().Update("Stock.Product").Set(Eq("ProductName", "Pakgen Bulbs")).
+				WhereWithMap(map[int]interface{}{
+					0: Eq("CategoryID", 3),
+					1: Or(Eq("BarcodeID", 22)),
+				}).String,
+		},
+		{
+			"update4",
+			"UPDATE Person.Contact SET FirstName='Daniel',LastName='Jamie' WHERE ContactID=1;",
+			NewUpdateBuilder().Update("Person.Contact").SetFromMap(map[int]interface{}{
+				0: Eq("FirstName", "Daniel"),
+				1: Eq("LastName", "Jamie"),
+			}).Where(Eq("ContactID", 1)).String,
+		},
+		{
+			"update5",
+			"UPDATE Sales.OrderDetail SET OrderDetailID=33 WHERE ProductID=3 AND Quantity=400 OR UnitPrice=300;",
+			NewUpdateBuilder().Update("Sales.OrderDetail").Set(Eq("OrderDetailID", 33)).Where(Eq("ProductID", 3)).And(Eq("Quantity", 400)).Or(Eq("UnitPrice", 300)).String,
+		},
+		{
+			"update6",
+			"UPDATE Sales.OrderHeader SET TotalAmountDue=sod.Total FROM Sales.OrderTotals AS sod WHERE Sales.OrderHeader.OrderID=sod.OrderID;",
+			NewUpdateBuilder().Update("Sales.OrderHeader").Set("TotalAmountDue=sod.Total").From("Sales.OrderTotals AS sod").
+				Where("Sales.OrderHeader.OrderID=sod.OrderID").String,
+		},
+		{
+			"update7",
+			"UPDATE Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID SET soh.Quantity=sod.Quantity WHERE sod.ProductID=3;",
+			NewUpdateBuilder().WithDialect(MySQL).Update("Sales.OrderHeader AS soh").Set("soh.Quantity=sod.Quantity").
+				Join("Sales.OrderDetail").As("sod").On("soh.OrderID", "sod.OrderID").Where(Eq("sod.ProductID", 3)).String,
+		},
+		{
+			"update8",
+			"UPDATE soh SET soh.Quantity=sod.Quantity FROM Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID WHERE sod.ProductID=3;",
+			NewUpdateBuilder().WithDialect(SQLServer).Update("soh").Set("soh.Quantity=sod.Quantity").From("Sales.OrderHeader AS soh").
+				Join("Sales.OrderDetail").As("sod").On("soh.OrderID", "sod.OrderID").Where(Eq("sod.ProductID", 3)).String,
+		},
+		{
+			"update9",
+			"UPDATE Sales.OrderHeader AS soh SET Quantity=sod.Quantity FROM Sales.OrderDetail AS sod JOIN Stock.Product AS sp ON sod.ProductID=sp.ProductID WHERE soh.OrderID=sod.OrderID AND sp.CategoryID=3 RETURNING soh.OrderID;",
+			NewUpdateBuilder().Update("Sales.OrderHeader AS soh").Set("Quantity=sod.Quantity").
+				Join("Sales.OrderDetail").As("sod").On("soh.OrderID", "sod.OrderID").Where(Eq("sp.CategoryID", 3)).
+				Returning("soh.OrderID").Join("Stock.Product").As("sp").On("sod.ProductID", "sp.ProductID").String,
+		},
+		{
+			"update10",
+			"UPDATE Sales.OrderHeader SET Quantity=sod.Quantity FROM Sales.OrderHeader JOIN Sales.OrderDetail AS sod ON Sales.OrderHeader.OrderID=sod.OrderID WHERE sod.ProductID=3;",
+			NewUpdateBuilder().WithDialect(SQLServer).Update("Sales.OrderHeader").Set("Quantity=sod.Quantity").
+				Join("Sales.OrderDetail").As("sod").On("Sales.OrderHeader.OrderID", "sod.OrderID").Where(Eq("sod.ProductID", 3)).String,
+		},
+		{
+			"update11",
+			"UPDATE Sales.OrderHeader JOIN Sales.OrderDetail AS sod ON Sales.OrderHeader.OrderID=sod.OrderID SET Quantity=sod.Quantity WHERE sod.ProductID=3;",
+			NewUpdateBuilder().WithDialect(MySQL).Update("Sales.OrderHeader").Set("Quantity=sod.Quantity").
+				Join("Sales.OrderDetail").As("sod").On("Sales.OrderHeader.OrderID", "sod.OrderID").Where(Eq("sod.ProductID", 3)).String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectBuilder_Select(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"select1",
+			"SELECT * FROM Person.Address;",
+			NewSelectBuilder().SelectAll("Person.Address").String,
+		},
+		{
+			"select2",
+			"SELECT ContactID,Title,FirstName,LastName,PhoneNumber FROM Person.Contact WHERE ContactID>100 ORDER BY FirstName;",
+			NewSelectBuilder().Select("ContactID", "Title", "FirstName", "LastName", "PhoneNumber").From("Person.Contact").
+				Where("ContactID>100").OrderBy("FirstName").String,
+		},
+		{
+			"select3",
+			"SELECT OrderID,StoreID,OrderDate,DueDate,TotalAmountDue,PaymentDate,PaymentMethodID FROM Sales.OrderHeader WHERE OrderID>2 AND StoreID<=100 AND DueDate!='11/02/2020';",
+			NewSelectBuilder().Select("OrderID", "StoreID", "OrderDate", "DueDate", "TotalAmountDue", "PaymentDate", "PaymentMethodID").
+				From("Sales.OrderHeader").WhereWithMap(map[int]interface{}{
+				0: G("OrderID", 2),
+				1: And(LEq("StoreID", 100)),
+				2: And(NEq("DueDate", "11/02/2020")),
+			}).String,
+		},
+		{
+			"select4",
+			"SELECT * FROM Person.Contact WHERE ContactID>3 AND AddressID=33 OR FirstName='Kelly';",
+			NewSelectBuilder().SelectAll("Person.Contact").Where(G("ContactID", 3)).And(Eq("AddressID", 33)).Or(Eq("FirstName", "Kelly")).String,
+		},
+		{
+			"select5",
+			"SELECT * FROM Stock.Product WHERE ProductID IN(2,44,22,11,42,53);",
+			NewSelectBuilder().SelectAll("Stock.Product").WhereFieldIn("ProductID", 2, 44, 22, 11, 42, 53).String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+}
+
+var ui uint64 = 90000
+
+func TestJoinBuilder_Join(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"join1",
+			"SELECT soh.OrderID,ss.StoreName,soh.OrderDate,soh.TotalAmountDue,soh.DeliveryDate,soh.PaymentDate,mpm.PaymentMethod FROM Sales.OrderHeader AS soh JOIN Sales.Store AS ss ON soh.StoreID=ss.StoreID JOIN Management.PaymentMethods AS mpm ON soh.PaymentMethodID=mpm.PaymentMethodID WHERE soh.TotalAmountDue>10000 AND soh.OrderID>22 AND mpm.PaymentMethod='Cash' ORDER BY soh.OrderID;",
+			NewJoinBuilder().
+				Select("soh.OrderID", "ss.StoreName", "soh.OrderDate", "soh.TotalAmountDue", "soh.DeliveryDate", "soh.PaymentDate", "mpm.PaymentMethod").
+				From("Sales.OrderHeader").As("soh").Join("Sales.Store").As("ss").On("soh.StoreID", "ss.StoreID").
+				Join("Management.PaymentMethods").As("mpm").On("soh.PaymentMethodID", "mpm.PaymentMethodID").
+				WhereWithMap(map[int]interface{}{
+					0: G("soh.TotalAmountDue", 10000),
+					1: And(G("soh.OrderID", 22)),
+					2: And(Eq("mpm.PaymentMethod", "Cash")),
+				}).OrderBy("soh.OrderID").String,
+		},
+		{
+			"join2",
+			"SELECT pc.FirstName,pc.LastName,pc.PhoneNumber,pc.Email FROM Purchasing.Supplier AS ps JOIN Person.Contact AS pc ON ps.ContactID=pc.ContactID WHERE pc.FirstName='Boluwatife' AND pc.LastName='Oyeniran' ORDER BY pc.FirstName;",
+			NewJoinBuilder().
+				Select("pc.FirstName", "pc.LastName", "pc.PhoneNumber", "pc.Email").From("Purchasing.Supplier").As("ps").
+				Join("Person.Contact").As("pc").On("ps.ContactID", "pc.ContactID").Where(Eq("pc.FirstName", "Boluwatife")).And(Eq("pc.LastName", "Oyeniran")).OrderBy("pc.FirstName").String,
+		},
+		{
+			"join3",
+			"SELECT * FROM Sales.OrderDetail AS sod JOIN Sales.OrderHeader AS soh ON sod.OrderID=soh.OrderID GROUP BY soh.OrderID;",
+			NewJoinBuilder().FromSelectBuilder(NewSelectBuilder()).SelectAll("Sales.OrderDetail").As("sod").Join("Sales.OrderHeader").As("soh").On("sod.OrderID", "soh.OrderID").GroupBy("soh.OrderID").String,
+		},
+		{
+			"join4",
+			"SELECT * FROM Sales.OrderDetail WHERE OrderID>100 OR TotalAmountDue>90000;",
+			NewJoinBuilder().SelectAll("Sales.OrderDetail").Where(G("OrderID", 100)).Or(G("TotalAmountDue", &ui)).String,
+		},
+		{
+			"join5",
+			"SELECT * FROM Sales.OrderDetail WHERE OrderID IN(32,76,33,44);",
+			NewJoinBuilder().SelectAll("Sales.OrderDetail").WhereFieldIn("OrderID", 32, 76, 33, 44).String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsertBuilder_Insert(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"insert1",
+			"INSERT INTO Person.Contact (Title,FirstName,LastName,PhoneNumber) VALUES('Mrs','Susan','Jerome','+2319057573110'),('Mr','George','Thane','+1222922843994');",
+			NewInsertBuilder().Insert("Person.Contact").Fields("Title", "FirstName", "LastName", "PhoneNumber").
+				ValuesFromMap(map[int]interface{}{
+					0: "Mrs",
+					1: "Susan",
+					2: "Jerome",
+					3: "+2319057573110",
+				}).ValuesSet(map[int]interface{}{
+				0: "Mr",
+				1: "George",
+				2: "Thane",
+				3: "+1222922843994",
+			}).String,
+		},
+		{
+			"insert1b",
+			"INSERT INTO Person.Contact (Title,FirstName) VALUES ('Mrs','Susan');",
+			NewInsertBuilder().Insert("Person.Contact").Fields("Title", "FirstName").Values("'Mrs'", "'Susan'").String,
+		},
+		{
+			"insert2",
+			"INSERT INTO Sales.OrderArchive (OrderID,StoreID,TotalAmountDue) SELECT OrderID,StoreID,TotalAmountDue FROM Sales.OrderHeader WHERE OrderDate<'01/01/2019' RETURNING OrderID;",
+			NewInsertBuilder().Insert("Sales.OrderArchive").Fields("OrderID", "StoreID", "TotalAmountDue").
+				FromSelect(NewSelectBuilder().Select("OrderID", "StoreID", "TotalAmountDue").From("Sales.OrderHeader").
+					Where(L("OrderDate", "01/01/2019"))).Returning("OrderID").String,
+		},
+		{
+			"insert3",
+			"INSERT INTO Sales.StoreOrders (StoreName,OrderID) SELECT ss.StoreName,soh.OrderID FROM Sales.OrderHeader AS soh JOIN Sales.Store AS ss ON soh.StoreID=ss.StoreID;",
+			NewInsertBuilder().Insert("Sales.StoreOrders").Fields("StoreName", "OrderID").
+				FromJoin(NewJoinBuilder().Select("ss.StoreName", "soh.OrderID").From("Sales.OrderHeader").As("soh").
+					Join("Sales.Store").As("ss").On("soh.StoreID", "ss.StoreID")).String,
+		},
+		{
+			"insert4",
+			"INSERT INTO Sales.OrderArchive (OrderID,StoreID) SELECT OrderID,StoreID FROM Sales.OrderHeader ON CONFLICT (OrderID) DO NOTHING RETURNING OrderID;",
+			NewInsertBuilder().Insert("Sales.OrderArchive").Fields("OrderID", "StoreID").
+				FromSelect(NewSelectBuilder().Select("OrderID", "StoreID").From("Sales.OrderHeader")).
+				OnConflictDoNothing("OrderID").Returning("OrderID").String,
+		},
+		{
+			"insert5",
+			"INSERT INTO Sales.OrderArchive (OrderID,StoreID,TotalAmountDue) SELECT OrderID,StoreID,TotalAmountDue FROM Sales.OrderHeader ON CONFLICT (OrderID) DO UPDATE SET StoreID=EXCLUDED.StoreID,TotalAmountDue=EXCLUDED.TotalAmountDue;",
+			NewInsertBuilder().Insert("Sales.OrderArchive").Fields("OrderID", "StoreID", "TotalAmountDue").
+				FromSelect(NewSelectBuilder().Select("OrderID", "StoreID", "TotalAmountDue").From("Sales.OrderHeader")).
+				OnConflictUpdate([]string{"OrderID"}, "StoreID", "TotalAmountDue").String,
+		},
+		{
+			"insert6",
+			"INSERT INTO Sales.OrderArchive (OrderID,StoreID) SELECT OrderID,StoreID FROM Sales.OrderHeader ON DUPLICATE KEY UPDATE StoreID=VALUES(StoreID);",
+			NewInsertBuilder().WithDialect(MySQL).Insert("Sales.OrderArchive").Fields("OrderID", "StoreID").
+				FromSelect(NewSelectBuilder().Select("OrderID", "StoreID").From("Sales.OrderHeader")).
+				OnConflictUpdate(nil, "StoreID").String,
+		},
+		{
+			"insert7",
+			"INSERT INTO Sales.OrderArchive (OrderID) VALUES(7) ON DUPLICATE KEY UPDATE OrderID=OrderID;",
+			NewInsertBuilder().WithDialect(MySQL).Insert("Sales.OrderArchive").Fields("OrderID").
+				ValuesFromMap(map[int]interface{}{0: 7}).OnConflictDoNothing("OrderID").String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInsertBuilder_Err(t *testing.T) {
+	b := NewInsertBuilder().WithIdentMode(IdentStrict).Insert("Sales.OrderArchive").Fields("OrderID").
+		FromSelect(NewSelectBuilder().WithIdentMode(IdentStrict).Select("OrderID;--").From("Sales.OrderHeader"))
+	if b.Err() == nil {
+		t.Error("FromSelect dropped the select's error")
+	}
+	var unsupported *UnsupportedError
+	b = NewInsertBuilder().WithDialect(SQLServer).Insert("Sales.OrderArchive").Fields("OrderID").
+		ValuesFromMap(map[int]interface{}{0: 7}).OnConflictDoNothing("OrderID")
+	if !errors.As(b.Err(), &unsupported) {
+		t.Errorf("got %v, want an *UnsupportedError", b.Err())
+	}
+	b = NewInsertBuilder().Insert("Sales.OrderArchive").Fields("OrderID").
+		ValuesFromMap(map[int]interface{}{0: 7}).OnConflictUpdate(nil, "OrderID")
+	if b.Err() != ErrConflictTarget {
+		t.Errorf("got %v, want ErrConflictTarget", b.Err())
+	}
+}
+
+func TestDeleteBuilder_Delete(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"delete1",
+			"DELETE FROM Stock.Product WHERE ProductID=20;",
+			NewDeleteBuilder().Delete("Stock.Product").Where("ProductID=20").String,
+		},
+		{
+			"delete2",
+			"DELETE FROM Stock.ProductPrice WHERE ProductID>2 AND PacketUnitPrice>=2000 OR CartonUnitPrice>40000;",
+			NewDeleteBuilder().Delete("Stock.ProductPrice").WhereWithMap(map[int]interface{}{
+				0: "ProductID>2 AND",
+				1: "PacketUnitPrice>=2000 OR",
+				2: "CartonUnitPrice>40000",
+			}).String,
+		},
+		{
+			"delete3",
+			"DELETE FROM Sales.OrderDetail WHERE OrderID>100 OR TotalAmountDue>90000 AND DueDate='10/11/2020';",
+			NewDeleteBuilder().Delete("Sales.OrderDetail").Where(G("OrderID", 100)).Or(G("TotalAmountDue", 90000)).And(Eq("DueDate", "10/11/2020")).String,
+		},
+		{
+			"delete4",
+			"DELETE FROM Sales.OrderDetail USING Sales.OrderHeader AS soh WHERE Sales.OrderDetail.OrderID=soh.OrderID AND soh.StoreID=3;",
+			NewDeleteBuilder().Delete("Sales.OrderDetail").Using("Sales.OrderHeader AS soh").
+				Where("Sales.OrderDetail.OrderID=soh.OrderID").And(Eq("soh.StoreID", 3)).String,
+		},
+		{
+			"delete5",
+			"DELETE FROM Sales.OrderDetail FROM Sales.OrderDetail JOIN Sales.OrderHeader AS soh ON Sales.OrderDetail.OrderID=soh.OrderID WHERE soh.StoreID=3;",
+			NewDeleteBuilder().WithDialect(SQLServer).Delete("Sales.OrderDetail").Using("Sales.OrderDetail").
+				Join("Sales.OrderHeader").As("soh").On("Sales.OrderDetail.OrderID", "soh.OrderID").Where(Eq("soh.StoreID", 3)).String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeBuilder_Merge(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"merge1",
+			"MERGE INTO Stock.Product AS sp USING Stock.ProductStaging AS st ON sp.ProductID=st.ProductID WHEN MATCHED AND st.Discontinued=1 THEN DELETE WHEN MATCHED THEN UPDATE SET ProductName=st.ProductName,Quantity=st.Quantity WHEN NOT MATCHED THEN INSERT (ProductID,ProductName,Quantity) VALUES (st.ProductID,st.ProductName,st.Quantity);",
+			NewMergeBuilder().Merge("Stock.Product").As("sp").Using("Stock.ProductStaging").As("st").On("sp.ProductID=st.ProductID").
+				WhenMatchedAnd(Eq("st.Discontinued", 1)).ThenDelete().
+				WhenMatched().ThenUpdate("ProductName=st.ProductName", "Quantity=st.Quantity").
+				WhenNotMatched().ThenInsert("ProductID", "ProductName", "Quantity").Values("st.ProductID", "st.ProductName", "st.Quantity").String,
+		},
+		{
+			"merge2",
+			"MERGE INTO Sales.StoreTotals AS t USING (SELECT StoreID,TotalAmountDue FROM Sales.OrderHeader WHERE OrderDate>'01/01/2020') AS s ON t.StoreID=s.StoreID WHEN MATCHED THEN UPDATE SET Total=t.Total+s.TotalAmountDue WHEN NOT MATCHED THEN INSERT (StoreID,Total) VALUES (s.StoreID,s.TotalAmountDue);",
+			NewMergeBuilder().Merge("Sales.StoreTotals").As("t").
+				UsingSelect(NewSelectBuilder().Select("StoreID", "TotalAmountDue").From("Sales.OrderHeader").Where(G("OrderDate", "01/01/2020"))).As("s").
+				On("t.StoreID=s.StoreID").WhenMatched().ThenUpdate("Total=t.Total+s.TotalAmountDue").
+				WhenNotMatched().ThenInsert("StoreID", "Total").Values("s.StoreID", "s.TotalAmountDue").String,
+		},
+		{
+			"merge3",
+			"MERGE INTO Stock.Product AS sp USING (VALUES(1,40),(2,15)) AS src (ProductID,Quantity) ON sp.ProductID=src.ProductID WHEN MATCHED THEN UPDATE SET Quantity=src.Quantity;",
+			NewMergeBuilder().Merge("Stock.Product").As("sp").UsingValues("src", []string{"ProductID", "Quantity"},
+				map[int]interface{}{0: 1, 1: 40},
+				map[int]interface{}{0: 2, 1: 15},
+			).On("sp.ProductID=src.ProductID").WhenMatched().ThenUpdate("Quantity=src.Quantity").String,
+		},
+		{
+			"merge4",
+			"MERGE INTO [Stock].[Product] AS [sp] USING (VALUES(1,40)) AS [src] ([ProductID],[Quantity]) ON sp.ProductID=src.ProductID WHEN NOT MATCHED THEN INSERT ([ProductID],[Quantity]) VALUES (src.ProductID,src.Quantity);",
+			NewMergeBuilder().WithDialect(SQLServer).WithIdentMode(IdentQuote).Merge("Stock.Product").As("sp").
+				UsingValues("src", []string{"ProductID", "Quantity"}, map[int]interface{}{0: 1, 1: 40}).On("sp.ProductID=src.ProductID").
+				WhenNotMatched().ThenInsert("ProductID", "Quantity").Values("src.ProductID", "src.Quantity").String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+
+	var unsupported *UnsupportedError
+	m := NewMergeBuilder().WithDialect(MySQL).Merge("Stock.Product").Using("Stock.ProductStaging").
+		On("Stock.Product.ProductID=Stock.ProductStaging.ProductID").WhenMatched().ThenDelete()
+	if _, _, err := m.Bind(nil); !errors.As(err, &unsupported) {
+		t.Errorf("merge on mysql: got err = %v, want an *UnsupportedError", err)
+	}
+	if _, err := m.Compile().Args(nil); !errors.As(err, &unsupported) {
+		t.Errorf("compiled merge on mysql: got err = %v, want an *UnsupportedError", err)
+	}
+	m = NewMergeBuilder().Merge("Stock.Product").UsingSelect(NewSelectBuilder().WithIdentMode(IdentStrict).Select("ProductID;--"))
+	if m.Err() == nil {
+		t.Error("UsingSelect dropped the select's error")
+	}
+	if err := m.WithIdentMode(IdentStrict).Merge("Stock.Product;--").Err(); err == nil {
+		t.Error("a name rejected by IdentStrict should be reported")
+	}
+
+	rec := querytest.New()
+	db := rec.DB()
+	defer db.Close()
+	m = NewMergeBuilder().Merge("Stock.Product").As("sp").Using("Stock.ProductStaging").As("st").
+		On("sp.ProductID=st.ProductID").WhenMatchedAnd(Eq("st.Quantity", Named("min"))).ThenDelete()
+	rec.Expect("MERGE INTO Stock.Product AS sp USING Stock.ProductStaging AS st ON sp.ProductID=st.ProductID WHEN MATCHED AND st.Quantity=$1 THEN DELETE;").
+		WithArgs(0).WillReturnResult(0, 3)
+	res, err := m.Exec(context.Background(), db, map[string]interface{}{"min": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 3 {
+		t.Errorf("got %d rows affected, want 3", n)
+	}
+	if err := rec.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	type order struct {
+		Store   int    `db:"store"`
+		Method  string `db:"method"`
+		Ignored bool
+	}
+
+	tests := []struct {
+		name     string
+		wantSQL  string
+		wantArgs []interface{}
+		wantErr  string
+		exec     func() (string, []interface{}, error)
+	}{
+		{
+			"bind1",
+			"SELECT * FROM Sales.OrderHeader WHERE StoreID=$1 AND OrderDate>'10:30' AND TotalAmountDue::int>$2;",
+			[]interface{}{3, 100},
+			"",
+			func() (string, []interface{}, error) {
+				return NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(Eq("StoreID", Named("store"))).
+					And(G("OrderDate", "10:30")).And("TotalAmountDue::int>:min").
+					Bind(map[string]interface{}{"store": 3, "min": 100})
+			},
+		},
+		{
+			"bind2",
+			"UPDATE Sales.OrderHeader SET PaymentMethod=? WHERE StoreID=? OR PreviousStoreID=?;",
+			[]interface{}{"Cash", 3, 3},
+			"",
+			func() (string, []interface{}, error) {
+				return NewUpdateBuilder().WithDialect(MySQL).Update("Sales.OrderHeader").Set(Eq("PaymentMethod", Named("method"))).
+					Where(Eq("StoreID", Named("store"))).Or("PreviousStoreID=:store").
+					BindStruct(&order{Store: 3, Method: "Cash"})
+			},
+		},
+		{
+			"bind3",
+			"DELETE FROM Sales.OrderHeader WHERE StoreID=@p1;",
+			[]interface{}{3},
+			"",
+			func() (string, []interface{}, error) {
+				return NewDeleteBuilder().WithDialect(SQLServer).Delete("Sales.OrderHeader").Where(Eq("StoreID", Named("store"))).
+					BindStruct(order{Store: 3})
+			},
+		},
+		{
+			"bind4",
+			"",
+			nil,
+			"query: missing values for store",
+			func() (string, []interface{}, error) {
+				return NewJoinBuilder().SelectAll("Sales.OrderHeader").Where(Eq("StoreID", Named("store"))).
+					Bind(map[string]interface{}{})
+			},
+		},
+		{
+			"bind5",
+			"",
+			nil,
+			"query: unused values for method",
+			func() (string, []interface{}, error) {
+				return NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(Eq("StoreID", Named("store"))).
+					Bind(map[string]interface{}{"store": 3, "method": "Cash"})
+			},
+		},
+		{
+			"bind6",
+			"SELECT * FROM Sales.OrderHeader WHERE Notes='it''s :foo' AND StoreID=$1 AND Code IN('a''b',':c');",
+			[]interface{}{3},
+			"",
+			func() (string, []interface{}, error) {
+				return NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(Eq("Notes", "it's :foo")).
+					And(Eq("StoreID", Named("store"))).WhereFieldIn("Code", "a'b", ":c").
+					Bind(map[string]interface{}{"store": 3})
+			},
+		},
+		{
+			"bind7",
+			"SELECT * FROM Sales.OrderHeader WHERE StoreID=$1 /* :skip */ AND [a:b]=$2 AND Note=$body$ :x $body$;",
+			[]interface{}{3, 3},
+			"",
+			func() (string, []interface{}, error) {
+				return NewSelectBuilder().SelectAll("Sales.OrderHeader").Where("StoreID=:store /* :skip */").
+					And("[a:b]=:store").And("Note=$body$ :x $body$").
+					Bind(map[string]interface{}{"store": 3})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := tt.exec()
+			if err != nil {
+				if err.Error() != tt.wantErr {
+					t.Errorf("got error = {%v} \n want = {%v}", err, tt.wantErr)
+				}
+				return
+			}
+			if got != tt.wantSQL || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("got = {%v} %v \n want = {%v} %v", got, args, tt.wantSQL, tt.wantArgs)
+			}
+		})
+	}
+}
+
+type failingValuer struct{}
+
+func (failingValuer) Value() (driver.Value, error) {
+	return nil, errors.New("no code")
+}
+
+func TestExpr(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"expr1",
+			"UPDATE Stock.Product SET Quantity=Quantity+?,UpdatedAt=now() WHERE ProductID=99;",
+			NewUpdateBuilder().Update("Stock.Product").SetFromMap(map[int]interface{}{
+				0: Eq("Quantity", Expr("Quantity+?", 1)),
+				1: Eq("UpdatedAt", Expr("now()")),
+			}).Where(Eq("ProductID", 99)).String,
+		},
+		{
+			"expr2",
+			"SELECT * FROM Sales.OrderHeader WHERE DueDate>now()-interval ? AND Notes!='?' AND Code=coalesce(:code,'?');",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(G("DueDate", Expr("now()-interval ?", "7 days"))).
+				And(NEq("Notes", "?")).And(Eq("Code", Expr("coalesce(?,'?')", Named("code")))).String,
+		},
+		{
+			"expr3",
+			"INSERT INTO Stock.Product (ProductName,DateAdded) VALUES('Bulb',CURRENT_TIMESTAMP);",
+			NewInsertBuilder().Insert("Stock.Product").Fields("ProductName", "DateAdded").
+				ValuesFromMap(map[int]interface{}{0: "Bulb", 1: Expr("CURRENT_TIMESTAMP")}).String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+
+	due := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	binds := []struct {
+		name     string
+		b        Binder
+		params   map[string]interface{}
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{
+			"injection",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(Eq("x", Expr("?", "a' OR '1'='1"))),
+			nil,
+			"SELECT * FROM Sales.OrderHeader WHERE x=$1;",
+			[]interface{}{"a' OR '1'='1"},
+		},
+		{
+			"mysql",
+			NewUpdateBuilder().WithDialect(MySQL).Update("Stock.Product").Set(Eq("Quantity", Expr("Quantity+?", 1))).
+				Where(Eq("StoreID", Expr("coalesce(?,?)", Named("store"), 0))),
+			map[string]interface{}{"store": 7},
+			"UPDATE Stock.Product SET Quantity=Quantity+? WHERE StoreID=coalesce(?,?);",
+			[]interface{}{1, 7, 0},
+		},
+		{
+			"sqlserver",
+			NewSelectBuilder().WithDialect(SQLServer).SelectAll("Sales.OrderHeader").
+				Where(L("DueDate", Expr("?", due))).And(Eq("Paid", Expr("?", true))).And(Eq("Notes", Expr("?", nil))).
+				And(Eq("Rate", Expr("?", 1.5))).And(Eq("Hash", Expr("?", []byte{0, 1}))),
+			nil,
+			"SELECT * FROM Sales.OrderHeader WHERE DueDate<@p1 AND Paid=@p2 AND Notes=@p3 AND Rate=@p4 AND Hash=@p5;",
+			[]interface{}{due, true, nil, 1.5, []byte{0, 1}},
+		},
+		{
+			"quoted",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(Eq("Notes", Expr("coalesce(?,'it''s ?') /* ? */", "x"))).
+				And(Expr("Tags??'k' AND [a?]=?", 2).String()),
+			nil,
+			"SELECT * FROM Sales.OrderHeader WHERE Notes=coalesce($1,'it''s ?') /* ? */ AND Tags?'k' AND [a?]=$2;",
+			[]interface{}{"x", 2},
+		},
+	}
+	for _, tt := range binds {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotArgs, err := tt.b.Bind(tt.params)
+			if err != nil {
+				t.Fatalf("got err = %v", err)
+			}
+			if gotSQL != tt.wantSQL || !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("got = {%v} %#v \n want = {%v} %#v", gotSQL, gotArgs, tt.wantSQL, tt.wantArgs)
+			}
+		})
+	}
+
+	typed := NewSelectBuilder().SelectAll("Stock.Product").Where(Eq("Quantity", Expr("?", int32(7)))).
+		And(Eq("Weight", Expr("?", float32(1.5)))).And(Eq("Stock", Expr("?", uint8(3))))
+	if _, args, err := typed.Bind(nil); err != nil || !reflect.DeepEqual(args, []interface{}{int32(7), float32(1.5), uint8(3)}) {
+		t.Errorf("typed values: got = %#v, %v", args, err)
+	}
+	if got := NewUpdateBuilder().Update("Stock.Product").Set(Eq("UpdatedAt", Expr("now() - ?", "1 day"))).
+		Where(Eq("ProductID", 2)).Pretty(); strings.Contains(got, exprPrefix) {
+		t.Errorf("Pretty shows the parameters of Expr values: %s", got)
+	}
+	var exprError *ExprError
+	unsupported := NewSelectBuilder().SelectAll("Stock.Product").Where(Eq("ProductID", Expr("ANY(?)", []int64{1, 2})))
+	if _, _, err := unsupported.Bind(nil); !errors.As(err, &exprError) {
+		t.Errorf("unsupported value: got err = %v, want an *ExprError", err)
+	}
+	if _, err := unsupported.Compile().Args(nil); !errors.As(err, &exprError) {
+		t.Errorf("compiled unsupported value: got err = %v, want an *ExprError", err)
+	}
+	failing := NewSelectBuilder().SelectAll("Stock.Product").Where(Eq("Code", Expr("?", failingValuer{})))
+	if _, _, err := failing.Bind(nil); !errors.As(err, &exprError) || !strings.Contains(err.Error(), "no code") {
+		t.Errorf("failing Valuer: got err = %v, want an *ExprError", err)
+	}
+
+	tpl := NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(Eq("StoreID", Named("store"))).
+		And(G("DueDate", Expr("now()-interval ?", "7 days"))).Compile()
+	args, err := tpl.Args(map[string]interface{}{"store": 3})
+	if err != nil || !reflect.DeepEqual(args, []interface{}{3, "7 days"}) {
+		t.Errorf("template: got = %#v, %v", args, err)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tpl := NewSelectBuilder().WithDialect(SQLServer).SelectAll("Sales.OrderHeader").
+		Where(Eq("StoreID", Named("store"))).Or(Eq("PreviousStoreID", Named("store"))).And(G("TotalAmountDue", Named("min"))).Compile()
+
+	want := "SELECT * FROM Sales.OrderHeader WHERE StoreID=@p1 OR PreviousStoreID=@p2 AND TotalAmountDue>@p3;"
+	if tpl.SQL() != want {
+		t.Errorf("got = {%v} \n want = {%v}", tpl.SQL(), want)
+	}
+
+	args, err := tpl.Args(map[string]interface{}{"store": 3, "min": 100})
+	if err != nil || !reflect.DeepEqual(args, []interface{}{3, 3, 100}) {
+		t.Errorf("got args %v, %v", args, err)
+	}
+	args, err = tpl.ArgsStruct(struct {
+		Store int `db:"store"`
+		Min   int `db:"min"`
+	}{4, 200})
+	if err != nil || !reflect.DeepEqual(args, []interface{}{4, 4, 200}) {
+		t.Errorf("got args %v, %v", args, err)
+	}
+
+	if _, err := tpl.Args(map[string]interface{}{"store": 3}); err == nil || err.Error() != "query: missing values for min" {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := tpl.Args(map[string]interface{}{"store": 3, "min": 1, "max": 2}); err == nil || err.Error() != "query: unused values for max" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestInStrategy(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"in1",
+			"SELECT * FROM Stock.Product WHERE ProductID=ANY(?);",
+			NewSelectBuilder().SelectAll("Stock.Product").WhereFieldInWith(InArray, "ProductID", 2, 44, 22).String,
+		},
+		{
+			"in2",
+			"SELECT * FROM Stock.Product WHERE ProductID=ANY(:ids);",
+			NewSelectBuilder().SelectAll("Stock.Product").WhereFieldIn("ProductID", Named("ids")).String,
+		},
+		{
+			"in3",
+			"SELECT * FROM Stock.Product WHERE ProductID IN(2,44,22);",
+			NewSelectBuilder().WithDialect(MySQL).SelectAll("Stock.Product").WhereFieldInWith(InArray, "ProductID", 2, 44, 22).String,
+		},
+		{
+			"in4",
+			"DELETE FROM Stock.Product WHERE ProductCode IN(SELECT v FROM (VALUES ('a'),('b')) AS t(v));",
+			NewDeleteBuilder().Delete("Stock.Product").WhereFieldInWith(InValues, "ProductCode", "a", "b").String,
+		},
+		{
+			"in5",
+			"SELECT * FROM Stock.Product WHERE ProductID IN(SELECT column_0 FROM (VALUES ROW(2),ROW(44)) AS t);",
+			NewJoinBuilder().WithDialect(MySQL).SelectAll("Stock.Product").WhereFieldInWith(InValues, "ProductID", 2, 44).String,
+		},
+		{
+			"in6",
+			"SELECT * FROM Stock.Product WHERE ProductID=ANY(ARRAY[2,:id]);",
+			NewSelectBuilder().SelectAll("Stock.Product").WhereFieldInWith(InArray, "ProductID", 2, Named("id")).String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+
+	ids := make([]interface{}, 2500)
+	for i := range ids {
+		ids[i] = i
+	}
+	auto := map[Dialect]string{
+		Postgres:  "SELECT * FROM Stock.Product WHERE ProductID=ANY(?);",
+		MySQL:     "SELECT * FROM Stock.Product WHERE (ProductID IN(0,1,",
+		SQLServer: "SELECT * FROM Stock.Product WHERE ProductID IN(SELECT v FROM (VALUES (0),(1),",
+	}
+	for d, want := range auto {
+		got := NewSelectBuilder().WithDialect(d).SelectAll("Stock.Product").WhereFieldIn("ProductID", ids...).String()
+		if !strings.HasPrefix(got, want) {
+			t.Errorf("%v: got = {%.80v...} \n want = {%v...}", d, got, want)
+		}
+		if d == MySQL && strings.Count(got, " IN(") != 3 {
+			t.Errorf("got %d chunks, want 3", strings.Count(got, " IN("))
+		}
+	}
+
+	qry, args, err := NewSelectBuilder().SelectAll("Stock.Product").WhereFieldInWith(InArray, "ProductID", Named("ids")).
+		Bind(map[string]interface{}{"ids": []int{2, 44}})
+	if err != nil || qry != "SELECT * FROM Stock.Product WHERE ProductID=ANY($1);" || len(args) != 1 {
+		t.Errorf("got %v %v %v", qry, args, err)
+	}
+	qry, args, err = NewSelectBuilder().SelectAll("Stock.Product").Where(Eq("CategoryID", Named("category"))).
+		WhereFieldInWith(InArray, "ProductCode", "a", "b'c").Bind(map[string]interface{}{"category": 3})
+	if err != nil || qry != "SELECT * FROM Stock.Product WHERE CategoryID=$1 AND ProductCode=ANY($2);" ||
+		!reflect.DeepEqual(args, []interface{}{3, []string{"a", "b'c"}}) {
+		t.Errorf("literal array: got %v %#v %v", qry, args, err)
+	}
+	qry, args, err = NewSelectBuilder().SelectAll("Stock.Product").WhereFieldIn("ProductID", ids...).Bind(nil)
+	if err != nil || qry != "SELECT * FROM Stock.Product WHERE ProductID=ANY($1);" || len(args) != 1 {
+		t.Errorf("large list: got %v %v", qry, err)
+	} else if got, ok := args[0].([]int); !ok || len(got) != len(ids) || got[2499] != 2499 {
+		t.Errorf("large list: got args %T", args[0])
+	}
+	mixed := append([]interface{}{"a"}, ids[1:]...)
+	if got := NewSelectBuilder().SelectAll("Stock.Product").WhereFieldIn("ProductID", mixed...).String(); !strings.HasPrefix(got, "SELECT * FROM Stock.Product WHERE ProductID IN('a',1,") {
+		t.Errorf("mixed large list: got = {%.80v...}", got)
+	}
+
+	for _, d := range []Dialect{MySQL, SQLServer} {
+		s := NewSelectBuilder().WithDialect(d).SelectAll("Stock.Product").WhereFieldIn("ProductID", Named("ids"))
+		if _, _, err := s.Bind(map[string]interface{}{"ids": []int{2, 44}}); err != ErrNamedList {
+			t.Errorf("%v: named list: got err = %v, want ErrNamedList", d, err)
+		}
+	}
+	if err := NewDeleteBuilder().WithDialect(MySQL).Delete("Stock.Product").WhereFieldInWith(InArray, "ProductID", Named("ids")).Err(); err != ErrNamedList {
+		t.Errorf("delete named list: got err = %v, want ErrNamedList", err)
+	}
+	if got := NewSelectBuilder().WithDialect(MySQL).SelectAll("Stock.Product").WhereFieldInWith(InList, "ProductID", Named("id")).String(); got != "SELECT * FROM Stock.Product WHERE ProductID IN(:id);" {
+		t.Errorf("named scalar: got = {%v}", got)
+	}
+}
+
+func TestConditionalWhere(t *testing.T) {
+	name, minAmount := "", 100
+	var deleted *bool
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"where1",
+			"SELECT * FROM Sales.OrderHeader WHERE Status='open' AND TotalAmountDue>100;",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").Where("Status='open'").Where("TotalAmountDue>100").String,
+		},
+		{
+			"where2",
+			"SELECT * FROM Sales.OrderHeader WHERE TotalAmountDue>100;",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").
+				WhereNonZero(Eq, "CustomerName", name).
+				WhereNonZero(G, "TotalAmountDue", minAmount).
+				WhereNonZero(Eq, "Deleted", deleted).String,
+		},
+		{
+			"where3",
+			"SELECT * FROM Sales.OrderHeader WHERE Status='open' OR Status='new';",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").
+				WhereIf(name != "", "CustomerName='"+name+"'").
+				AndIf(true, "Status='open'").
+				OrIf(true, "Status='new'").String,
+		},
+		{
+			"where4",
+			"SELECT * FROM Sales.OrderHeader WHERE Status='new';",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").OrIf(true, "Status='new'").AndIf(false, "Status='open'").String,
+		},
+		{
+			"where5",
+			"SELECT * FROM Sales.OrderHeader WHERE Status='open' AND OrderID IN(1,2);",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").Where("Status='open'").WhereFieldIn("OrderID", 1, 2).String,
+		},
+		{
+			"where6",
+			"SELECT * FROM Sales.OrderHeader JOIN Sales.OrderDetail ON Sales.OrderHeader.OrderID=Sales.OrderDetail.OrderID WHERE Quantity>2;",
+			NewJoinBuilder().SelectAll("Sales.OrderHeader").Join("Sales.OrderDetail").
+				On("Sales.OrderHeader.OrderID", "Sales.OrderDetail.OrderID").
+				WhereIf(false, "Price>0").WhereNonZero(G, "Quantity", 2).String,
+		},
+		{
+			"where7",
+			"UPDATE Sales.OrderHeader SET Status='closed' WHERE OrderID=3 AND Status='open';",
+			NewUpdateBuilder().Update("Sales.OrderHeader").Set("Status='closed'").
+				Where("OrderID=3").AndIf(true, "Status='open'").String,
+		},
+		{
+			"where8",
+			"DELETE FROM Sales.OrderHeader WHERE OrderID=3;",
+			NewDeleteBuilder().Delete("Sales.OrderHeader").WhereNonZero(Eq, "CustomerName", name).WhereNonZero(Eq, "OrderID", 3).String,
+		},
+		{
+			"where9",
+			"SELECT * FROM Sales.OrderHeader WHERE (a=1 OR b=2) AND tenant=3;",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").WhereWithMap(map[int]interface{}{0: "a=1", 1: Or("b=2")}).Where("tenant=3").String,
+		},
+		{
+			"where10",
+			"UPDATE Sales.OrderHeader SET Status='closed' WHERE (Status='open' OR Status='new') AND (StoreID=1 OR StoreID=2) AND tenant=3;",
+			NewUpdateBuilder().Update("Sales.OrderHeader").Set("Status='closed'").Where("Status='open'").OrIf(true, "Status='new'").
+				AndIf(true, "StoreID=1 OR StoreID=2").WhereWithMap(map[int]interface{}{0: "tenant=3"}).String,
+		},
+		{
+			"where11",
+			"DELETE FROM Sales.OrderHeader WHERE (Status='open' or Notes='a OR b') AND OrderID IN(1,2);",
+			NewDeleteBuilder().Delete("Sales.OrderHeader").Where("Status='open' or Notes='a OR b'").WhereFieldIn("OrderID", 1, 2).String,
+		},
+		{
+			"where12",
+			"SELECT * FROM Sales.OrderHeader JOIN Sales.Store ON StoreID=Sales.Store.ID WHERE (a=1 OR b=2) AND (tenant=3);",
+			NewJoinBuilder().SelectAll("Sales.OrderHeader").Join("Sales.Store").On("StoreID", "Sales.Store.ID").
+				Where("a=1").Or("b=2").Where("(tenant=3)").String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	if _, err := NewDeleteBuilder().Delete("Stock.Product").ToSQL(); err != ErrNoWhere {
+		t.Errorf("delete without where: got err = %v, want ErrNoWhere", err)
+	}
+	if _, _, err := NewUpdateBuilder().Update("Stock.Product").Set(Eq("Price", 0)).Bind(nil); err != ErrNoWhere {
+		t.Errorf("update without where: got err = %v, want ErrNoWhere", err)
+	}
+	if got, err := NewDeleteBuilder().Delete("Stock.Product").AllRows().ToSQL(); err != nil || got != "DELETE FROM Stock.Product;" {
+		t.Errorf("delete all rows: got = {%v}, err = %v", got, err)
+	}
+	if got, err := NewUpdateBuilder().Update("Stock.Product").Set(Eq("Price", 0)).WhereIf(false, "ProductID=2").ToSQL(); err != ErrNoWhere {
+		t.Errorf("update with skipped where: got = {%v}, err = %v, want ErrNoWhere", got, err)
+	}
+	if got, err := NewDeleteBuilder().Delete("Stock.Product").WhereWithMap(nil).ToSQL(); err != ErrNoWhere {
+		t.Errorf("delete with empty where map: got = {%v}, err = %v, want ErrNoWhere", got, err)
+	}
+	if got, err := NewUpdateBuilder().AllRows().Update("Stock.Product").Set(Eq("Price", 0)).ToSQL(); err != ErrNoWhere {
+		t.Errorf("update all rows set before Update: got = {%v}, err = %v, want ErrNoWhere", got, err)
+	}
+	if got, err := NewUpdateBuilder().Update("Sales.OrderHeader").Set("Quantity=sod.Quantity").
+		Join("Sales.OrderDetail").As("sod").On("Sales.OrderHeader.OrderID", "sod.OrderID").ToSQL(); err != ErrNoWhere {
+		t.Errorf("update with only a join condition: got = {%v}, err = %v, want ErrNoWhere", got, err)
+	}
+	reused := NewDeleteBuilder().MaxAffected(10)
+	if _, err := reused.Delete("Tmp").AllRows().ToSQL(); err != nil {
+		t.Errorf("delete all rows of Tmp: %v", err)
+	}
+	if got, err := reused.Delete("Stock.Product").ToSQL(); err != ErrNoWhere {
+		t.Errorf("reused delete builder: got = {%v}, err = %v, want ErrNoWhere", got, err)
+	}
+	if g, _ := reused.guarded(); g.maxAffected != 10 {
+		t.Errorf("reused delete builder: got MaxAffected %d, want 10", g.maxAffected)
+	}
+	reusedUpdate := NewUpdateBuilder()
+	reusedUpdate.Update("Tmp").Set(Eq("Price", 0)).AllRows()
+	if got, err := reusedUpdate.Update("Stock.Product").Set(Eq("Price", 0)).ToSQL(); err != ErrNoWhere {
+		t.Errorf("reused update builder: got = {%v}, err = %v, want ErrNoWhere", got, err)
+	}
+	tpl := NewUpdateBuilder().Update("Stock.Product").Set(Eq("Price", Named("price"))).Compile()
+	if _, err := tpl.Args(map[string]interface{}{"price": 5}); err != ErrNoWhere {
+		t.Errorf("compiled update without where: got err = %v, want ErrNoWhere", err)
+	}
+	if _, err := NewDeleteBuilder().Delete("Stock.Product").Compile().Stmt(context.Background(), nil); err != ErrNoWhere {
+		t.Errorf("compiled delete without where: got err = %v, want ErrNoWhere", err)
+	}
+
+	rec := querytest.New()
+	db := rec.DB()
+	defer db.Close()
+	ctx := context.Background()
+
+	u := NewUpdateBuilder().Update("Stock.Product").Set(Eq("Price", Named("price"))).Where("CategoryID=3").MaxAffected(10)
+	rec.Expect("UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;").WithArgs(5).WillReturnResult(0, 10)
+	if _, err := u.Exec(ctx, db, map[string]interface{}{"price": 5}); err != nil {
+		t.Fatalf("update within maximum: %v", err)
+	}
+	rec.Expect("UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;").WithArgs(5).WillReturnResult(0, 11)
+	_, err := u.Exec(ctx, db, map[string]interface{}{"price": 5})
+	if e, ok := err.(*MaxAffectedError); !ok || e.Max != 10 || e.Affected != 11 {
+		t.Fatalf("update over maximum: got err = %v, want *MaxAffectedError", err)
+	}
+	if _, err := NewDeleteBuilder().Delete("Stock.Product").Exec(ctx, db, nil); err != ErrNoWhere {
+		t.Errorf("exec delete without where: got err = %v, want ErrNoWhere", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Expect("SAVEPOINT query_guard")
+	rec.Expect("UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;").WithArgs(5).WillReturnResult(0, 11)
+	rec.Expect("ROLLBACK TO SAVEPOINT query_guard")
+	_, err = u.Exec(ctx, tx, map[string]interface{}{"price": 5})
+	if e, ok := err.(*MaxAffectedError); !ok || e.Affected != 11 {
+		t.Fatalf("update over maximum in a transaction: got err = %v, want *MaxAffectedError", err)
+	}
+	rec.Expect("SAVEPOINT query_guard")
+	rec.Expect("UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;").WithArgs(5).WillReturnResult(0, 2)
+	rec.Expect("RELEASE SAVEPOINT query_guard")
+	if _, err := u.Exec(ctx, tx, map[string]interface{}{"price": 5}); err != nil {
+		t.Fatalf("update within maximum in a transaction: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range rec.Calls() {
+		got = append(got, c.Query)
+	}
+	want := []string{
+		"BEGIN", "UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;", "COMMIT",
+		"BEGIN", "UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;", "ROLLBACK",
+		"BEGIN", "SAVEPOINT query_guard", "UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;", "ROLLBACK TO SAVEPOINT query_guard",
+		"SAVEPOINT query_guard", "UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;", "RELEASE SAVEPOINT query_guard", "COMMIT",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got calls = %v \n want = %v", got, want)
+	}
+	if err := rec.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"preview1",
+			"SELECT * FROM Stock.Product WHERE ProductID=99;",
+			NewUpdateBuilder().Update("Stock.Product").Set(Eq("ProductName", "Powersuper Battery")).Where(Eq("ProductID", 99)).
+				Returning("ProductID").Preview().String,
+		},
+		{
+			"preview2",
+			"SELECT COUNT(*) FROM Sales.OrderDetail WHERE ProductID=3 AND Quantity=400 OR UnitPrice=300;",
+			NewUpdateBuilder().Update("Sales.OrderDetail").Set(Eq("OrderDetailID", 33)).Where(Eq("ProductID", 3)).
+				And(Eq("Quantity", 400)).Or(Eq("UnitPrice", 300)).PreviewCount().String,
+		},
+		{
+			"preview3",
+			"SELECT Sales.OrderHeader.* FROM Sales.OrderHeader,Sales.OrderTotals AS sod WHERE Sales.OrderHeader.OrderID=sod.OrderID;",
+			NewUpdateBuilder().Update("Sales.OrderHeader").Set("TotalAmountDue=sod.Total").From("Sales.OrderTotals AS sod").
+				Where("Sales.OrderHeader.OrderID=sod.OrderID").Preview().String,
+		},
+		{
+			"preview4",
+			"SELECT soh.* FROM Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID WHERE sod.ProductID=3;",
+			NewUpdateBuilder().WithDialect(MySQL).Update("Sales.OrderHeader AS soh").Set("soh.Quantity=sod.Quantity").
+				Join("Sales.OrderDetail").As("sod").On("soh.OrderID", "sod.OrderID").Where(Eq("sod.ProductID", 3)).Preview().String,
+		},
+		{
+			"preview5",
+			"SELECT soh.* FROM Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID WHERE sod.ProductID=3;",
+			NewUpdateBuilder().WithDialect(SQLServer).Update("soh").Set("soh.Quantity=sod.Quantity").From("Sales.OrderHeader AS soh").
+				Join("Sales.OrderDetail").As("sod").On("soh.OrderID", "sod.OrderID").Where(Eq("sod.ProductID", 3)).Preview().String,
+		},
+		{
+			"preview6",
+			"SELECT * FROM Stock.Product WHERE ProductID IN(2,3) AND Price>10;",
+			NewDeleteBuilder().Delete("Stock.Product").WhereFieldIn("ProductID", 2, 3).ReturningAll().Preview().Where(G("Price", 10)).String,
+		},
+		{
+			"preview7",
+			"SELECT Sales.OrderDetail.* FROM Sales.OrderDetail,Sales.OrderHeader AS soh WHERE Sales.OrderDetail.OrderID=soh.OrderID AND soh.StoreID=3;",
+			NewDeleteBuilder().Delete("Sales.OrderDetail").Using("Sales.OrderHeader AS soh").
+				Where("Sales.OrderDetail.OrderID=soh.OrderID").And(Eq("soh.StoreID", 3)).Preview().String,
+		},
+		{
+			"preview8",
+			"SELECT COUNT(*) FROM Sales.OrderDetail JOIN Sales.OrderHeader AS soh ON Sales.OrderDetail.OrderID=soh.OrderID WHERE soh.StoreID=3;",
+			NewDeleteBuilder().WithDialect(SQLServer).Delete("Sales.OrderDetail").Using("Sales.OrderDetail").
+				Join("Sales.OrderHeader").As("soh").On("Sales.OrderDetail.OrderID", "soh.OrderID").Where(Eq("soh.StoreID", 3)).PreviewCount().String,
+		},
+		{
+			"preview9",
+			"SELECT * FROM Stock.Product;",
+			NewDeleteBuilder().Delete("Stock.Product").AllRows().Preview().String,
+		},
+		{
+			"preview12",
+			"SELECT soh.* FROM Sales.OrderHeader AS soh,Sales.OrderDetail AS sod WHERE soh.OrderID=sod.OrderID AND sod.ProductID=3;",
+			NewUpdateBuilder().Update("Sales.OrderHeader AS soh").Set("Quantity=sod.Quantity").
+				Join("Sales.OrderDetail").As("sod").On("soh.OrderID", "sod.OrderID").Where(Eq("sod.ProductID", 3)).Preview().String,
+		},
+		{
+			"preview10",
+			"SELECT * FROM Sales.OrderHeader WHERE (StoreID=1 OR StoreID=2) AND TenantID=7;",
+			NewUpdateBuilder().Update("Sales.OrderHeader").Set(Eq("Paid", true)).Where("StoreID=1").Or("StoreID=2").
+				Preview().Where("TenantID=7").String,
+		},
+		{
+			"preview11",
+			"SELECT COUNT(*) FROM Sales.OrderHeader WHERE (StoreID=1 OR StoreID=2) AND TenantID=7;",
+			NewDeleteBuilder().Delete("Sales.OrderHeader").Where("StoreID=1").Or("StoreID=2").
+				PreviewCount().Where("TenantID=7").String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"ident1",
+			`SELECT "OrderID","order","user" FROM "Sales"."OrderHeader" AS "soh" ORDER BY "soh"."order";`,
+			NewSelectBuilder().WithIdentMode(IdentQuote).Select("OrderID", "order", "user").From("Sales.OrderHeader AS soh").OrderBy("soh.order").String,
+		},
+		{
+			"ident2",
+			"SELECT `soh`.* FROM `Sales`.`OrderHeader` AS `soh` JOIN `Sales`.`OrderDetail` AS `sod` ON `soh`.`OrderID`=`sod`.`OrderID`;",
+			NewJoinBuilder().WithDialect(MySQL).WithIdentMode(IdentQuote).Select("soh.*").From("Sales.OrderHeader soh").
+				Join("Sales.OrderDetail").As("sod").On("soh.OrderID", "sod.OrderID").String,
+		},
+		{
+			"ident3",
+			"INSERT INTO [Person].[Contact] ([Title],[First]]Name]) VALUES ('Mr','Daniel');",
+			NewInsertBuilder().WithDialect(SQLServer).WithIdentMode(IdentQuote).Insert("Person.Contact").Fields("Title", "First]Name").
+				Values("'Mr'", "'Daniel'").String,
+		},
+		{
+			"ident4",
+			`SELECT * FROM "Stock"."Product" ORDER BY "Price; DROP TABLE Stock";`,
+			NewSelectBuilder().WithIdentMode(IdentQuote).SelectAll("Stock.Product").OrderBy("Price; DROP TABLE Stock").String,
+		},
+		{
+			"ident5",
+			`UPDATE "Stock"."Product" SET Price=0 WHERE ProductID=2 RETURNING "ProductID";`,
+			NewUpdateBuilder().WithIdentMode(IdentQuote|IdentStrict).Update("Stock.Product").Set("Price=0").Where("ProductID=2").Returning("ProductID").String,
+		},
+		{
+			"ident6",
+			`DELETE FROM "Sales"."OrderDetail" USING "Sales"."OrderHeader" AS "soh" WHERE OrderID=2;`,
+			NewDeleteBuilder().WithIdentMode(IdentQuote).Delete("Sales.OrderDetail").Using("Sales.OrderHeader AS soh").Where("OrderID=2").String,
+		},
+		{
+			"ident7",
+			`SELECT * FROM "Stock"."Product" ORDER BY """id"";DELETE/**/FROM/**/t;--""","Price","a""b";`,
+			NewSelectBuilder().WithIdentMode(IdentQuote).SelectAll("Stock.Product").OrderBy(`"id";DELETE/**/FROM/**/t;--"`, `"Price"`, `"a""b"`).String,
+		},
+		{
+			"ident8",
+			"SELECT * FROM [Stock].[Product] ORDER BY [[id]];DROP TABLE t;--]]],[Price];",
+			NewSelectBuilder().WithDialect(SQLServer).WithIdentMode(IdentQuote).SelectAll("Stock.Product").OrderBy("[id];DROP TABLE t;--]", "[Price]").String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+
+	s := NewSelectBuilder().WithIdentMode(IdentStrict).Select("OrderID", "soh.*").From("Sales.OrderHeader AS soh").OrderBy("OrderID")
+	if err := s.Err(); err != nil {
+		t.Errorf("strict valid names: got err = %v", err)
+	}
+	s.Select("OrderID").From("Sales.OrderHeader").OrderBy("OrderDate;DELETE FROM Sales.OrderHeader")
+	if _, _, err := s.Bind(nil); err == nil || err.Error() != `query: invalid identifier "OrderDate;DELETE FROM Sales.OrderHeader"` {
+		t.Errorf("strict invalid name: got err = %v", err)
+	}
+	if err := s.Select("OrderID").Err(); err != nil {
+		t.Errorf("strict after restart: got err = %v", err)
+	}
+	s = NewSelectBuilder().WithIdentMode(IdentQuote | IdentStrict).SelectAll("Stock.Product").OrderBy(`"id";DELETE/**/FROM/**/t;--"`)
+	if err := s.Err(); err == nil {
+		t.Error("strict quoted payload: got no error")
+	}
+	d := NewDeleteBuilder().WithIdentMode(IdentStrict).Delete("Stock.Product").Where("ProductID=2").Returning("1abc")
+	if _, err := d.ToSQL(); err == nil {
+		t.Error("strict delete: got no error")
+	}
+	sel := NewSelectBuilder().WithIdentMode(IdentStrict).Select("1abc").From("Sales.OrderHeader")
+	ins := NewInsertBuilder().Insert("Sales.OrderArchive").Fields("OrderID").FromSelect(sel)
+	if sel.Select("OrderID"); sel.Err() != nil || ins.Err() == nil {
+		t.Errorf("strict inner select: got err = %v, insert err = %v", sel.Err(), ins.Err())
+	}
+
+	for _, tt := range []struct {
+		d    Dialect
+		name string
+		want string
+	}{
+		{Postgres, `"a.b".c.*`, `"a.b"."c".*`},
+		{MySQL, "`a.b`.c", "`a.b`.`c`"},
+		{SQLServer, "[a.b].c", "[a.b].[c]"},
+		{Postgres, `"a.b`, `"""a.b"`},
+	} {
+		if got := tt.d.QuoteIdent(tt.name); got != tt.want {
+			t.Errorf("QuoteIdent(%v): got = {%v} \n want = {%v}", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOrderByFromRequest(t *testing.T) {
+	allowed := map[string]string{
+		"created_at": "soh.OrderDate",
+		"name":       "pc.LastName",
+		"total":      "soh.TotalAmountDue",
+	}
+	tests := []struct {
+		name string
+		want string
+		exec func() string
+	}{
+		{
+			"sort1",
+			"SELECT * FROM Sales.OrderHeader ORDER BY soh.OrderDate DESC,pc.LastName ASC;",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").OrderByFromRequest("-created_at,name", allowed).String,
+		},
+		{
+			"sort2",
+			"SELECT * FROM Sales.OrderHeader ORDER BY soh.TotalAmountDue ASC NULLS LAST;",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").OrderByFromRequest(" +total:nullslast ", allowed).String,
+		},
+		{
+			"sort3",
+			"SELECT * FROM Sales.OrderHeader ORDER BY CASE WHEN soh.TotalAmountDue IS NULL THEN 0 ELSE 1 END,soh.TotalAmountDue DESC,soh.OrderDate ASC;",
+			NewSelectBuilder().WithDialect(MySQL).SelectAll("Sales.OrderHeader").OrderByFromRequest("-total:NullsFirst,created_at", allowed).String,
+		},
+		{
+			"sort4",
+			"SELECT * FROM Sales.OrderHeader;",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").OrderByFromRequest("", allowed).String,
+		},
+		{
+			"sort5",
+			"SELECT * FROM Sales.OrderHeader ORDER BY soh.OrderDate,soh.StoreID DESC;",
+			NewJoinBuilder().SelectAll("Sales.OrderHeader").OrderBy("soh.OrderDate", "soh.StoreID").Desc().String,
+		},
+		{
+			"sort6",
+			`SELECT * FROM "Sales"."OrderHeader" ORDER BY "soh"."OrderDate" DESC;`,
+			NewSelectBuilder().WithIdentMode(IdentQuote).SelectAll("Sales.OrderHeader").OrderByFromRequest("-created_at", allowed).String,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.exec(); got != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			}
+		})
+	}
+
+	for _, spec := range []string{"password", "-name,(SELECT 1)", "name:sideways", "name,,total"} {
+		s := NewSelectBuilder().SelectAll("Sales.OrderHeader").OrderByFromRequest(spec, allowed)
+		if _, ok := s.Err().(*SortError); !ok {
+			t.Errorf("spec %q: got err = %v, want *SortError", spec, s.Err())
+		}
+		if got := s.String(); got != "SELECT * FROM Sales.OrderHeader;" {
+			t.Errorf("spec %q: got = {%v}", spec, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	// expectations of the builder tests, which should render back as they were
+	roundTrip := []string{
+		"UPDATE Stock.Product SET ProductName='Powersuper Battery' WHERE ProductID=99;",
+		"UPDATE Stock.ProductBarCodes SET Barcode='4353532242' WHERE BarcodeID=2;",
+		"UPDATE Stock.Product SET ProductName='Pakgen Bulbs' WHERE CategoryID=3 OR BarcodeID=22;",
+		"UPDATE Person.Contact SET FirstName='Daniel',LastName='Jamie' WHERE ContactID=1;",
+		"UPDATE Sales.OrderDetail SET OrderDetailID=33 WHERE ProductID=3 AND Quantity=400 OR UnitPrice=300;",
+		"UPDATE Sales.OrderHeader SET TotalAmountDue=sod.Total FROM Sales.OrderTotals AS sod WHERE Sales.OrderHeader.OrderID=sod.OrderID;",
+		"UPDATE Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID SET soh.Quantity=sod.Quantity WHERE sod.ProductID=3;",
+		"UPDATE soh SET soh.Quantity=sod.Quantity FROM Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID WHERE sod.ProductID=3;",
+		"SELECT * FROM Person.Address;",
+		"SELECT ContactID,Title,FirstName,LastName,PhoneNumber FROM Person.Contact WHERE ContactID>100 ORDER BY FirstName;",
+		"SELECT OrderID,StoreID,OrderDate,DueDate,TotalAmountDue,PaymentDate,PaymentMethodID FROM Sales.OrderHeader WHERE OrderID>2 AND StoreID<=100 AND DueDate!='11/02/2020';",
+		"SELECT * FROM Person.Contact WHERE ContactID>3 AND AddressID=33 OR FirstName='Kelly';",
+		"SELECT * FROM Stock.Product WHERE ProductID IN(2,44,22,11,42,53);",
+		"SELECT soh.OrderID,ss.StoreName,soh.OrderDate,soh.TotalAmountDue,soh.DeliveryDate,soh.PaymentDate,mpm.PaymentMethod FROM Sales.OrderHeader AS soh JOIN Sales.Store AS ss ON soh.StoreID=ss.StoreID JOIN Management.PaymentMethods AS mpm ON soh.PaymentMethodID=mpm.PaymentMethodID WHERE soh.TotalAmountDue>10000 AND soh.OrderID>22 AND mpm.PaymentMethod='Cash' ORDER BY soh.OrderID;",
+		"SELECT pc.FirstName,pc.LastName,pc.PhoneNumber,pc.Email FROM Purchasing.Supplier AS ps JOIN Person.Contact AS pc ON ps.ContactID=pc.ContactID WHERE pc.FirstName='Boluwatife' AND pc.LastName='Oyeniran' ORDER BY pc.FirstName;",
+		"SELECT * FROM Sales.OrderDetail AS sod JOIN Sales.OrderHeader AS soh ON sod.OrderID=soh.OrderID GROUP BY soh.OrderID;",
+		"SELECT * FROM Sales.OrderDetail WHERE OrderID>100 OR TotalAmountDue>90000;",
+		"SELECT * FROM Sales.OrderDetail WHERE OrderID IN(32,76,33,44);",
+		"INSERT INTO Person.Contact (Title,FirstName,LastName,PhoneNumber) VALUES('Mrs','Susan','Jerome','+2319057573110'),('Mr','George','Thane','+1222922843994');",
+		"INSERT INTO Sales.OrderArchive (OrderID,StoreID,TotalAmountDue) SELECT OrderID,StoreID,TotalAmountDue FROM Sales.OrderHeader WHERE OrderDate<'01/01/2019' RETURNING OrderID;",
+		"INSERT INTO Sales.StoreOrders (StoreName,OrderID) SELECT ss.StoreName,soh.OrderID FROM Sales.OrderHeader AS soh JOIN Sales.Store AS ss ON soh.StoreID=ss.StoreID;",
+		"DELETE FROM Stock.Product WHERE ProductID=20;",
+		"DELETE FROM Stock.ProductPrice WHERE ProductID>2 AND PacketUnitPrice>=2000 OR CartonUnitPrice>40000;",
+		"DELETE FROM Sales.OrderDetail WHERE OrderID>100 OR TotalAmountDue>90000 AND DueDate='10/11/2020';",
+		"DELETE FROM Sales.OrderDetail USING Sales.OrderHeader AS soh WHERE Sales.OrderDetail.OrderID=soh.OrderID AND soh.StoreID=3;",
+		"DELETE FROM Sales.OrderDetail FROM Sales.OrderDetail JOIN Sales.OrderHeader AS soh ON Sales.OrderDetail.OrderID=soh.OrderID WHERE soh.StoreID=3;",
+		"SELECT * FROM Sales.OrderHeader WHERE StoreID=$1 AND OrderDate>'10:30' AND TotalAmountDue::int>$2;",
+		"UPDATE Sales.OrderHeader SET PaymentMethod=? WHERE StoreID=? OR PreviousStoreID=?;",
+		"DELETE FROM Sales.OrderHeader WHERE StoreID=@p1;",
+		"UPDATE Stock.Product SET Quantity=Quantity+1,UpdatedAt=now() WHERE ProductID=99;",
+		"SELECT * FROM Sales.OrderHeader WHERE DueDate>now()-interval '7 days' AND Notes!='?' AND Code=coalesce(:code,'?');",
+		"INSERT INTO Stock.Product (ProductName,DateAdded) VALUES('Bulb',CURRENT_TIMESTAMP);",
+		"SELECT * FROM Stock.Product WHERE ProductID=ANY(ARRAY[2,44,22]);",
+		"SELECT * FROM Stock.Product WHERE ProductID=ANY(:ids);",
+		"SELECT * FROM Stock.Product WHERE ProductID IN(2,44,22);",
+		"DELETE FROM Stock.Product WHERE ProductCode IN(SELECT v FROM (VALUES ('a'),('b')) AS t(v));",
+		"SELECT * FROM Stock.Product WHERE ProductID IN(SELECT column_0 FROM (VALUES ROW(2),ROW(44)) AS t);",
+		"SELECT * FROM Sales.OrderHeader WHERE Status='open' AND TotalAmountDue>100;",
+		"SELECT * FROM Sales.OrderHeader WHERE TotalAmountDue>100;",
+		"SELECT * FROM Sales.OrderHeader WHERE Status='open' OR Status='new';",
+		"SELECT * FROM Sales.OrderHeader WHERE Status='new';",
+		"SELECT * FROM Sales.OrderHeader WHERE Status='open' AND OrderID IN(1,2);",
+		"SELECT * FROM Sales.OrderHeader JOIN Sales.OrderDetail ON Sales.OrderHeader.OrderID=Sales.OrderDetail.OrderID WHERE Quantity>2;",
+		"UPDATE Sales.OrderHeader SET Status='closed' WHERE OrderID=3 AND Status='open';",
+		"DELETE FROM Sales.OrderHeader WHERE OrderID=3;",
+		"SELECT * FROM Stock.Product WHERE ProductID=99;",
+		"SELECT COUNT(*) FROM Sales.OrderDetail WHERE ProductID=3 AND Quantity=400 OR UnitPrice=300;",
+		"SELECT Sales.OrderHeader.* FROM Sales.OrderHeader,Sales.OrderTotals AS sod WHERE Sales.OrderHeader.OrderID=sod.OrderID;",
+		"SELECT soh.* FROM Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID WHERE sod.ProductID=3;",
+		"SELECT * FROM Stock.Product WHERE ProductID IN(2,3) AND Price>10;",
+		"SELECT Sales.OrderDetail.* FROM Sales.OrderDetail,Sales.OrderHeader AS soh WHERE Sales.OrderDetail.OrderID=soh.OrderID AND soh.StoreID=3;",
+		"SELECT COUNT(*) FROM Sales.OrderDetail JOIN Sales.OrderHeader AS soh ON Sales.OrderDetail.OrderID=soh.OrderID WHERE soh.StoreID=3;",
+		"SELECT * FROM Stock.Product;",
+		"SELECT `soh`.* FROM `Sales`.`OrderHeader` AS `soh` JOIN `Sales`.`OrderDetail` AS `sod` ON `soh`.`OrderID`=`sod`.`OrderID`;",
+		"SELECT * FROM Sales.OrderHeader ORDER BY soh.OrderDate DESC,pc.LastName ASC;",
+		"SELECT * FROM Sales.OrderHeader ORDER BY soh.TotalAmountDue ASC NULLS LAST;",
+		"SELECT * FROM Sales.OrderHeader ORDER BY CASE WHEN soh.TotalAmountDue IS NULL THEN 0 ELSE 1 END,soh.TotalAmountDue DESC,soh.OrderDate ASC;",
+		"SELECT * FROM Sales.OrderHeader;",
+		"SELECT * FROM Sales.OrderHeader ORDER BY soh.OrderDate,soh.StoreID DESC;",
+	}
+	for _, sql := range roundTrip {
+		stmt, err := Parse(sql)
+		if err != nil {
+			t.Errorf("%v: %v", sql, err)
+			continue
+		}
+		if got := stmt.String(); got != sql {
+			t.Errorf("got = {%v} \n want = {%v}", got, sql)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want string
+		exec func() (string, error)
+	}{
+		{
+			"parse1",
+			"SELECT DISTINCT soh.StoreID,COUNT(*) AS n FROM Sales.OrderHeader AS soh WHERE (soh.Status='open' or soh.Status='new') AND TenantID=:tenant GROUP BY soh.StoreID HAVING COUNT(*)>1 ORDER BY n DESC LIMIT 50 OFFSET 100;",
+			func() (string, error) {
+				stmt, err := Parse("select distinct soh.StoreID, COUNT(*) AS n\nfrom Sales.OrderHeader AS soh\nwhere soh.Status='open' or soh.Status='new'\ngroup by soh.StoreID having COUNT(*)>1 order by n DESC limit 10 offset 100")
+				if err != nil {
+					return "", err
+				}
+				sel := stmt.(*SelectStatement)
+				sel.AddWhere(Eq("TenantID", Named("tenant")))
+				sel.Limit = "50"
+				return sel.String(), nil
+			},
 		},
 		{
-			"update2",
-			"UPDATE Stock.ProductBarCodes SET Barcode='4353532242' WHERE BarcodeID=2;",
-			NewUpdateBuilder().Update("Stock.ProductBarCodes").Set(Eq("Barcode", "4353532242")).Where(Eq("BarcodeID", 2)).String,
+			"parse2",
+			"UPDATE Sales.OrderHeader,Sales.OrderTotals AS sod SET TotalAmountDue=sod.Total WHERE Sales.OrderHeader.OrderID=sod.OrderID AND TenantID=?;",
+			func() (string, error) {
+				stmt, err := Parse("UPDATE Sales.OrderHeader SET TotalAmountDue=sod.Total FROM Sales.OrderTotals AS sod WHERE Sales.OrderHeader.OrderID=sod.OrderID")
+				if err != nil {
+					return "", err
+				}
+				upd := stmt.(*UpdateStatement)
+				upd.AddWhere("TenantID=:tenant")
+				qry, _, err := upd.Builder(MySQL).Bind(map[string]interface{}{"tenant": 3})
+				return qry, err
+			},
 		},
 		{
-			"update3",
-			"UPDATE Stock.Product SET ProductName='Pakgen Bulbs' WHERE CategoryID=3 OR BarcodeID=22;",
-			NewUpdateBuilder().Update("Stock.Product").Set(Eq("ProductName", "Pakgen Bulbs")).
-				WhereWithMap(map[int]interface{}{
-					0: Eq("CategoryID", 3),
-					1: Or(Eq("BarcodeID", 22)),
-				}).String,
+			"parse3",
+			"DELETE FROM Sales.OrderDetail USING Sales.OrderDetail JOIN Sales.OrderHeader AS soh ON Sales.OrderDetail.OrderID=soh.OrderID WHERE soh.StoreID=3;",
+			func() (string, error) {
+				stmt, err := Parse("DELETE FROM Sales.OrderDetail FROM Sales.OrderDetail JOIN Sales.OrderHeader AS soh ON Sales.OrderDetail.OrderID=soh.OrderID WHERE soh.StoreID=3;")
+				if err != nil {
+					return "", err
+				}
+				return stmt.(*DeleteStatement).Builder(MySQL).ToSQL()
+			},
 		},
 		{
-			"update4",
-			"UPDATE Person.Contact SET FirstName='Daniel',LastName='Jamie' WHERE ContactID=1;",
-			NewUpdateBuilder().Update("Person.Contact").SetFromMap(map[int]interface{}{
-				0: Eq("FirstName", "Daniel"),
-				1: Eq("LastName", "Jamie"),
-			}).Where(Eq("ContactID", 1)).String,
+			"parse4",
+			"INSERT INTO [Person].[Contact] ([Title],[First]]Name]) VALUES('Mr','Daniel') RETURNING ContactID;",
+			func() (string, error) {
+				stmt, err := Parse("INSERT INTO [Person].[Contact] ([Title], [First]]Name]) VALUES ('Mr','Daniel') RETURNING ContactID")
+				if err != nil {
+					return "", err
+				}
+				return stmt.String(), nil
+			},
 		},
 		{
-			"update5",
-			"UPDATE Sales.OrderDetail SET OrderDetailID=33 WHERE ProductID=3 AND Quantity=400 OR UnitPrice=300;",
-			NewUpdateBuilder().Update("Sales.OrderDetail").Set(Eq("OrderDetailID", 33)).Where(Eq("ProductID", 3)).And(Eq("Quantity", 400)).Or(Eq("UnitPrice", 300)).String,
+			"parse5",
+			"SELECT a.ID FROM a JOIN b USING (ID) WHERE a.Note='where -- or' AND a.[From]=1;",
+			func() (string, error) {
+				stmt, err := Parse("SELECT a.ID FROM a JOIN b USING (ID) WHERE a.Note='where -- or' AND a.[From]=1 -- comment")
+				if err != nil {
+					return "", err
+				}
+				return stmt.String(), nil
+			},
+		},
+		{
+			"parse6",
+			"SELECT soh.StoreID,COUNT(*) AS n FROM Sales.OrderHeader AS soh GROUP BY soh.StoreID ORDER BY n DESC OFFSET 100 ROWS FETCH NEXT 10 ROWS ONLY;",
+			func() (string, error) {
+				stmt, err := Parse("SELECT soh.StoreID, COUNT(*) AS n FROM Sales.OrderHeader AS soh GROUP BY soh.StoreID ORDER BY n DESC LIMIT 10 OFFSET 100")
+				if err != nil {
+					return "", err
+				}
+				return stmt.(*SelectStatement).Builder(SQLServer).String(), nil
+			},
+		},
+		{
+			"parse7",
+			"SELECT TOP (10) * FROM Sales.OrderHeader WHERE StoreID=3;",
+			func() (string, error) {
+				stmt, err := Parse("SELECT * FROM Sales.OrderHeader WHERE StoreID=3 LIMIT 10")
+				if err != nil {
+					return "", err
+				}
+				return stmt.(*SelectStatement).Builder(SQLServer).String(), nil
+			},
+		},
+		{
+			"parse8",
+			"SELECT * FROM Sales.OrderHeader ORDER BY (SELECT NULL) OFFSET 20 ROWS;",
+			func() (string, error) {
+				stmt, err := Parse("SELECT * FROM Sales.OrderHeader OFFSET 20")
+				if err != nil {
+					return "", err
+				}
+				return stmt.(*SelectStatement).Builder(SQLServer).String(), nil
+			},
+		},
+		{
+			"parse9",
+			"SELECT * FROM Sales.OrderHeader LIMIT 10 OFFSET 100;",
+			func() (string, error) {
+				stmt, err := Parse("SELECT * FROM Sales.OrderHeader LIMIT 100, 10")
+				if err != nil {
+					return "", err
+				}
+				return stmt.String(), nil
+			},
+		},
+		{
+			"parse10",
+			"UPDATE Sales.OrderHeader AS soh SET Quantity=sod.Quantity FROM Sales.OrderDetail AS sod WHERE soh.OrderID=sod.OrderID AND sod.ProductID=3;",
+			func() (string, error) {
+				stmt, err := Parse("UPDATE Sales.OrderHeader AS soh INNER JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID SET Quantity=sod.Quantity WHERE sod.ProductID=3")
+				if err != nil {
+					return "", err
+				}
+				return stmt.(*UpdateStatement).Builder(Postgres).String(), nil
+			},
+		},
+		{
+			"parse11",
+			"UPDATE soh SET Quantity=sod.Quantity FROM Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID JOIN Stock.Product AS sp ON sod.ProductID=sp.ProductID WHERE sp.CategoryID=3;",
+			func() (string, error) {
+				stmt, err := Parse("UPDATE Sales.OrderHeader AS soh JOIN Sales.OrderDetail AS sod ON soh.OrderID=sod.OrderID JOIN Stock.Product AS sp ON sod.ProductID=sp.ProductID SET Quantity=sod.Quantity WHERE sp.CategoryID=3")
+				if err != nil {
+					return "", err
+				}
+				return stmt.(*UpdateStatement).Builder(SQLServer).String(), nil
+			},
+		},
+		{
+			"parse12",
+			"UPDATE Sales.OrderHeader SET TotalAmountDue=sod.Total FROM Sales.OrderTotals AS sod WHERE Sales.OrderHeader.OrderID=sod.OrderID;",
+			func() (string, error) {
+				stmt, err := Parse("UPDATE Sales.OrderHeader, Sales.OrderTotals AS sod SET TotalAmountDue=sod.Total WHERE Sales.OrderHeader.OrderID=sod.OrderID")
+				if err != nil {
+					return "", err
+				}
+				return stmt.(*UpdateStatement).Builder(Postgres).String(), nil
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.exec(); got != tt.want {
+			got, err := tt.exec()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
 				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
 			}
 		})
 	}
+
+	for sql, want := range map[string]string{
+		"":                                  "query: empty statement at offset 0",
+		"MERGE INTO a USING b ON a.ID=b.ID": "query: expected SELECT, INSERT, UPDATE or DELETE at offset 0",
+		"SELECT 1 UNION SELECT 2":           "query: unsupported UNION at offset 9",
+		"INSERT INTO a VALUES(1) ON CONFLICT DO NOTHING": "query: unsupported ON CONFLICT at offset 24",
+		"SELECT * FROM a WHERE x=1 WHERE y=2":            "query: unexpected WHERE at offset 26",
+		"UPDATE a WHERE x=1":                             "query: UPDATE without SET at offset 0",
+		"UPDATE a LEFT JOIN b ON a.x=b.x SET a.y=1":      "query: unsupported LEFT in UPDATE at offset 9",
+		"UPDATE a JOIN b SET y=1":                        "query: JOIN without ON at offset 15",
+		"SELECT * FROM a OFFSET 5 LIMIT 5, 10":           "query: unexpected LIMIT at offset 25",
+	} {
+		_, err := Parse(sql)
+		if err == nil || err.Error() != want {
+			t.Errorf("%q: got err = %v \n want = %v", sql, err, want)
+		}
+	}
 }
 
-func TestSelectBuilder_Select(t *testing.T) {
+func TestFormat(t *testing.T) {
 	tests := []struct {
 		name string
 		want string
 		exec func() string
 	}{
 		{
-			"select1",
-			"SELECT * FROM Person.Address;",
-			NewSelectBuilder().SelectAll("Person.Address").String,
-		},
-		{
-			"select2",
-			"SELECT ContactID,Title,FirstName,LastName,PhoneNumber FROM Person.Contact WHERE ContactID>100 ORDER BY FirstName;",
-			NewSelectBuilder().Select("ContactID", "Title", "FirstName", "LastName", "PhoneNumber").From("Person.Contact").
-				Where("ContactID>100").OrderBy("FirstName").String,
-		},
-		{
-			"select3",
-			"SELECT OrderID,StoreID,OrderDate,DueDate,TotalAmountDue,PaymentDate,PaymentMethodID FROM Sales.OrderHeader WHERE OrderID>2 AND StoreID<=100 AND DueDate!='11/02/2020';",
-			NewSelectBuilder().Select("OrderID", "StoreID", "OrderDate", "DueDate", "TotalAmountDue", "PaymentDate", "PaymentMethodID").
-				From("Sales.OrderHeader").WhereWithMap(map[int]interface{}{
-				0: G("OrderID", 2),
-				1: And(LEq("StoreID", 100)),
-				2: And(NEq("DueDate", "11/02/2020")),
-			}).String,
+			"format1",
+			"SELECT soh.OrderID,\n" +
+				"       ss.StoreName,\n" +
+				"       mpm.PaymentMethod\n" +
+				"FROM Sales.OrderHeader AS soh\n" +
+				"JOIN Sales.Store AS ss ON soh.StoreID=ss.StoreID\n" +
+				"JOIN Management.PaymentMethods AS mpm ON soh.PaymentMethodID=mpm.PaymentMethodID\n" +
+				"WHERE soh.TotalAmountDue>10000\n" +
+				"  AND soh.OrderDate BETWEEN '01/01/2020' AND '12/31/2020'\n" +
+				"  AND (mpm.PaymentMethod='Cash' OR mpm.PaymentMethod='Card')\n" +
+				"ORDER BY soh.OrderID;",
+			NewJoinBuilder().
+				Select("soh.OrderID", "ss.StoreName", "mpm.PaymentMethod").
+				From("Sales.OrderHeader").As("soh").Join("Sales.Store").As("ss").On("soh.StoreID", "ss.StoreID").
+				Join("Management.PaymentMethods").As("mpm").On("soh.PaymentMethodID", "mpm.PaymentMethodID").
+				Where(G("soh.TotalAmountDue", 10000)).And("soh.OrderDate BETWEEN '01/01/2020' AND '12/31/2020'").
+				And("(mpm.PaymentMethod='Cash' OR mpm.PaymentMethod='Card')").OrderBy("soh.OrderID").Pretty,
 		},
 		{
-			"select4",
-			"SELECT * FROM Person.Contact WHERE ContactID>3 AND AddressID=33 OR FirstName='Kelly';",
-			NewSelectBuilder().SelectAll("Person.Contact").Where(G("ContactID", 3)).And(Eq("AddressID", 33)).Or(Eq("FirstName", "Kelly")).String,
+			"format2",
+			"MERGE INTO Sales.StoreTotals AS t\n" +
+				"USING (\n" +
+				"  SELECT StoreID,\n" +
+				"         TotalAmountDue\n" +
+				"  FROM Sales.OrderHeader\n" +
+				"  WHERE OrderDate>'01/01/2020'\n" +
+				") AS s ON t.StoreID=s.StoreID\n" +
+				"WHEN MATCHED THEN UPDATE\n" +
+				"SET Total=t.Total+s.TotalAmountDue\n" +
+				"WHEN NOT MATCHED THEN INSERT (StoreID,Total)\n" +
+				"VALUES (s.StoreID,s.TotalAmountDue);",
+			NewMergeBuilder().Merge("Sales.StoreTotals").As("t").
+				UsingSelect(NewSelectBuilder().Select("StoreID", "TotalAmountDue").From("Sales.OrderHeader").Where(G("OrderDate", "01/01/2020"))).As("s").
+				On("t.StoreID=s.StoreID").WhenMatched().ThenUpdate("Total=t.Total+s.TotalAmountDue").
+				WhenNotMatched().ThenInsert("StoreID", "Total").Values("s.StoreID", "s.TotalAmountDue").Pretty,
 		},
 		{
-			"select5",
-			"SELECT * FROM Stock.Product WHERE ProductID IN(2,44,22,11,42,53);",
-			NewSelectBuilder().SelectAll("Stock.Product").WhereFieldIn("ProductID", 2, 44, 22, 11, 42, 53).String,
+			"format3",
+			"with recursive stores as (\n" +
+				"\tselect StoreID,\n" +
+				"\t       ParentID\n" +
+				"\tfrom Sales.Store\n" +
+				"\twhere StoreID in (\n" +
+				"\t\tselect StoreID\n" +
+				"\t\tfrom Sales.OrderHeader\n" +
+				"\t)\n" +
+				"),\n" +
+				"totals as (\n" +
+				"\tselect 1\n" +
+				")\n" +
+				"select s.StoreID as Order,\n" +
+				"       case when s.ParentID is null then 'root' else 'branch, leaf' end\n" +
+				"from stores as s\n" +
+				"left join totals using (StoreID) -- keep me\n" +
+				"order by left(s.Name,1) desc\n" +
+				"limit 5;\n" +
+				"delete from Sales.Store\n" +
+				"where StoreID=$1\n" +
+				"\tor Note::text='a  b'",
+			func() string {
+				return Format("WITH RECURSIVE stores AS (SELECT StoreID, ParentID FROM Sales.Store WHERE StoreID IN (select StoreID from Sales.OrderHeader)), totals AS (SELECT 1) "+
+					"SELECT s.StoreID AS Order, CASE WHEN s.ParentID IS NULL THEN 'root' ELSE 'branch, leaf' END FROM stores AS s LEFT JOIN totals USING (StoreID) -- keep me\n"+
+					"ORDER BY LEFT(s.Name,1) DESC LIMIT 5; DELETE FROM Sales.Store WHERE StoreID=$1 OR Note::text='a  b'",
+					FormatOptions{Indent: "\t", Keywords: KeywordsLower})
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -98,117 +1554,454 @@ func TestSelectBuilder_Select(t *testing.T) {
 			}
 		})
 	}
+
+	// only whitespace changes with KeywordsAsIs
+	strip := func(s string) string { return strings.Join(strings.Fields(s), "") }
+	for _, sql := range []string{
+		"INSERT INTO Sales.OrderDetail (OrderID,ProductID) VALUES(1,2),(1,3) RETURNING OrderDetailID;",
+		"UPDATE Stock.Product SET Price=Price*1.1,Quantity=0 FROM Stock.ProductStaging AS st WHERE Stock.Product.ProductID=st.ProductID;",
+		"SELECT * FROM Sales.OrderHeader WHERE StoreID IN(SELECT v FROM (VALUES (1),(2)) AS t(v)) /* a, b */;",
+	} {
+		if got := Format(sql, FormatOptions{Keywords: KeywordsAsIs}); strip(got) != strip(sql) {
+			t.Errorf("got = {%v} \n want = {%v}", got, sql)
+		}
+	}
 }
 
-var ui uint64 = 90000
+type secret string
 
-func TestJoinBuilder_Join(t *testing.T) {
+func TestDebug(t *testing.T) {
+	opts := DebugOptions{
+		Redact:      []RedactRule{RedactColumns("password", "ssn"), RedactTypes(secret(""))},
+		MaxValueLen: 8,
+		MaxListLen:  3,
+	}
 	tests := []struct {
 		name string
 		want string
-		exec func() string
+		exec func() (string, error)
 	}{
 		{
-			"join1",
-			"SELECT soh.OrderID,ss.StoreName,soh.OrderDate,soh.TotalAmountDue,soh.DeliveryDate,soh.PaymentDate,mpm.PaymentMethod FROM Sales.OrderHeader AS soh JOIN Sales.Store AS ss ON soh.StoreID=ss.StoreID JOIN Management.PaymentMethods AS mpm ON soh.PaymentMethodID=mpm.PaymentMethodID WHERE soh.TotalAmountDue>10000 AND soh.OrderID>22 AND mpm.PaymentMethod='Cash' ORDER BY soh.OrderID;",
-			NewJoinBuilder().
-				Select("soh.OrderID", "ss.StoreName", "soh.OrderDate", "soh.TotalAmountDue", "soh.DeliveryDate", "soh.PaymentDate", "mpm.PaymentMethod").
-				From("Sales.OrderHeader").As("soh").Join("Sales.Store").As("ss").On("soh.StoreID", "ss.StoreID").
-				Join("Management.PaymentMethods").As("mpm").On("soh.PaymentMethodID", "mpm.PaymentMethodID").
-				WhereWithMap(map[int]interface{}{
-					0: G("soh.TotalAmountDue", 10000),
-					1: And(G("soh.OrderID", 22)),
-					2: And(Eq("mpm.PaymentMethod", "Cash")),
-				}).OrderBy("soh.OrderID").String,
+			"debug1",
+			DebugMarker + "SELECT * FROM Person.Contact WHERE pc.Email='o''neil@example.com' AND pc.[Password]=<redacted> AND StoreID IN(1,2,3,…(+2)) AND Token=<redacted>;",
+			func() (string, error) {
+				return NewSelectBuilder().SelectAll("Person.Contact").Where(Eq("pc.Email", Named("email"))).
+					And(Eq("pc.[Password]", Named("pw"))).WhereFieldInWith(InList, "StoreID", Named("a"), Named("b"), Named("c"), Named("d"), Named("e")).
+					And(Eq("Token", Named("token"))).Debug(map[string]interface{}{
+					"email": "o'neil@example.com", "pw": "hunter2", "a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "token": secret("t0k3n"),
+				}, DebugOptions{Redact: opts.Redact, MaxListLen: opts.MaxListLen})
+			},
 		},
 		{
-			"join2",
-			"SELECT pc.FirstName,pc.LastName,pc.PhoneNumber,pc.Email FROM Purchasing.Supplier AS ps JOIN Person.Contact AS pc ON ps.ContactID=pc.ContactID WHERE pc.FirstName='Boluwatife' AND pc.LastName='Oyeniran' ORDER BY pc.FirstName;",
-			NewJoinBuilder().
-				Select("pc.FirstName", "pc.LastName", "pc.PhoneNumber", "pc.Email").From("Purchasing.Supplier").As("ps").
-				Join("Person.Contact").As("pc").On("ps.ContactID", "pc.ContactID").Where(Eq("pc.FirstName", "Boluwatife")).And(Eq("pc.LastName", "Oyeniran")).OrderBy("pc.FirstName").String,
+			"debug2",
+			DebugMarker + "INSERT INTO Person.Contact (FirstName,SSN,Notes,DeletedAt) VALUES ('Daniel',<redacted>,'a long n'…(+8),NULL);",
+			func() (string, error) {
+				return NewInsertBuilder().WithDialect(MySQL).Insert("Person.Contact").Fields("FirstName", "SSN", "Notes", "DeletedAt").
+					Values(":first", ":ssn", ":notes", ":deleted").Debug(map[string]interface{}{
+					"first": "Daniel", "ssn": "078-05-1120", "notes": "a long note here", "deleted": (*string)(nil),
+				}, opts)
+			},
 		},
 		{
-			"join3",
-			"SELECT * FROM Sales.OrderDetail AS sod JOIN Sales.OrderHeader AS soh ON sod.OrderID=soh.OrderID GROUP BY soh.OrderID;",
-			NewJoinBuilder().FromSelectBuilder(NewSelectBuilder()).SelectAll("Sales.OrderDetail").As("sod").Join("Sales.OrderHeader").As("soh").On("sod.OrderID", "soh.OrderID").GroupBy("soh.OrderID").String,
+			"debug3",
+			DebugMarker + "UPDATE Person.Contact SET Password=crypt(<redacted>,gen_salt('bf')),Active=TRUE WHERE ContactID=7 AND Amount><missing>;",
+			func() (string, error) {
+				return Debug("UPDATE Person.Contact SET Password=crypt(@p1,gen_salt('bf')),Active=@p2 WHERE ContactID=@p3 AND Amount>@p4;",
+					[]interface{}{"hunter2", true, 7}, opts), nil
+			},
 		},
 		{
-			"join4",
-			"SELECT * FROM Sales.OrderDetail WHERE OrderID>100 OR TotalAmountDue>90000;",
-			NewJoinBuilder().SelectAll("Sales.OrderDetail").Where(G("OrderID", 100)).Or(G("TotalAmountDue", &ui)).String,
+			"debug4",
+			DebugMarker + "SELECT * FROM Person.Contact WHERE <redacted>=Password AND <redacted><>pc.\"SSN\" AND 'Daniel'=FirstName;",
+			func() (string, error) {
+				return Debug(`SELECT * FROM Person.Contact WHERE $1=Password AND $2<>pc."SSN" AND $3=FirstName;`,
+					[]interface{}{"hunter2", "078-05-1120", "Daniel"}, opts), nil
+			},
 		},
 		{
-			"join5",
-			"SELECT * FROM Sales.OrderDetail WHERE OrderID IN(32,76,33,44);",
-			NewJoinBuilder().SelectAll("Sales.OrderDetail").WhereFieldIn("OrderID", 32, 76, 33, 44).String,
+			"debug4b",
+			DebugMarker + "SELECT * FROM Stock.Product WHERE ProductID=ANY(ARRAY[2,44,22,…(+1)]);",
+			func() (string, error) {
+				return NewSelectBuilder().SelectAll("Stock.Product").WhereFieldInWith(InArray, "ProductID", 2, 44, 22, 7).Debug(nil, opts)
+			},
+		},
+		{
+			"debug5",
+			DebugMarker + "SELECT * FROM Person.Contact WHERE FirstName='Daniel' AND Password=<redacted> AND <redacted>=pc.SSN AND SSN IN(<redacted>,<redacted>) AND Notes=crypt('bf');",
+			func() (string, error) {
+				return NewSelectBuilder().SelectAll("Person.Contact").Where(Eq("FirstName", "Daniel")).And(Eq("Password", "hunter2")).
+					And("'078-05-1120'=pc.SSN").WhereFieldInWith(InList, "SSN", "1", "2").And("Notes=crypt('bf')").Debug(nil, opts)
+			},
+		},
+		{
+			"debug6",
+			DebugMarker + "UPDATE Person.Contact SET Password=<redacted>,Notes='o''neil' WHERE ContactID=7;",
+			func() (string, error) {
+				return Debug("UPDATE Person.Contact SET Password=$$hunter2$$,Notes='o''neil' WHERE ContactID=7;", nil, opts), nil
+			},
+		},
+		{
+			"debug7",
+			DebugMarker + "INSERT INTO Person.Contact (FirstName,SSN) VALUES ('Daniel',<redacted>);",
+			func() (string, error) {
+				return Debug("INSERT INTO Person.Contact (FirstName,SSN) VALUES ('Daniel','078-05-1120');", nil, opts), nil
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.exec(); got != tt.want {
+			got, err := tt.exec()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
 				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestInsertBuilder_Insert(t *testing.T) {
+type hookKey struct{}
+
+// recordingHook records the queries it sees, tagging the context
+type recordingHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h recordingHook) BeforeQuery(ctx context.Context, sql string, args []interface{}) context.Context {
+	*h.calls = append(*h.calls, h.name+" before "+sql)
+	return context.WithValue(ctx, hookKey{}, h.name)
+}
+
+func (h recordingHook) AfterQuery(ctx context.Context, sql string, args []interface{}, d time.Duration, rowsAffected int64, err error) {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s after %s rows=%d err=%v ctx=%v", h.name, sql, rowsAffected, err, ctx.Value(hookKey{})))
+}
+
+type testLogger struct{ records []string }
+
+func (l *testLogger) log(level, msg string, args ...interface{}) {
+	for ix := 0; ix < len(args); ix += 2 {
+		if args[ix] == "duration" {
+			args[ix+1] = "-"
+		}
+	}
+	l.records = append(l.records, strings.TrimSuffix(fmt.Sprintln(append([]interface{}{level, msg}, args...)...), "\n"))
+}
+
+func (l *testLogger) DebugContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log("DEBUG", msg, args...)
+}
+
+func (l *testLogger) WarnContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log("WARN", msg, args...)
+}
+
+func (l *testLogger) ErrorContext(ctx context.Context, msg string, args ...interface{}) {
+	l.log("ERROR", msg, args...)
+}
+
+type testMetrics struct {
+	observed []string
+	rows     map[string]int64
+}
+
+func (m *testMetrics) ObserveQuery(statement, status string, seconds float64) {
+	m.observed = append(m.observed, statement+" "+status)
+}
+
+func (m *testMetrics) AddRows(statement string, rows int64) {
+	m.rows[statement] += rows
+}
+
+func TestHooks(t *testing.T) {
+	saved := hooks
+	defer func() { hooks = saved }()
+	hooks = nil
+
+	var calls []string
+	AddHook(recordingHook{name: "global", calls: &calls})
+	logger := &testLogger{}
+	metrics := &testMetrics{rows: map[string]int64{}}
+
+	rec := querytest.New()
+	db := rec.DB()
+	defer db.Close()
+	ctx := context.Background()
+	r := NewRunner(db, recordingHook{name: "runner", calls: &calls},
+		&LogHook{Logger: logger, Options: DebugOptions{Redact: []RedactRule{RedactColumns("Price")}}}, &MetricsHook{Metrics: metrics})
+
+	rec.Expect("UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;").WithArgs(5).WillReturnResult(0, 4)
+	if _, err := r.Exec(ctx, NewUpdateBuilder().Update("Stock.Product").Set(Eq("Price", Named("price"))).Where("CategoryID=3"),
+		map[string]interface{}{"price": 5}); err != nil {
+		t.Fatal(err)
+	}
+	rec.Expect("SELECT ProductID FROM Stock.Product WHERE CategoryID=$1;").WithArgs(3).
+		WillReturnRows(querytest.NewRows("ProductID").AddRow(1))
+	rows, err := r.Query(ctx, NewSelectBuilder().Select("ProductID").From("Stock.Product").Where(Eq("CategoryID", Named("category"))),
+		map[string]interface{}{"category": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	rec.Expect("DELETE FROM Stock.Product WHERE CategoryID=3;").WillReturnError(errors.New("locked"))
+	if _, err := NewDeleteBuilder().Delete("Stock.Product").Where("CategoryID=3").Exec(ctx, db, nil); err == nil || err.Error() != "locked" {
+		t.Errorf("got err = %v, want locked", err)
+	}
+	if _, err := r.Exec(ctx, NewDeleteBuilder().Delete("Stock.Product"), nil); err != ErrNoWhere {
+		t.Errorf("got err = %v, want ErrNoWhere", err)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Expect("INSERT INTO Stock.Product (ProductID) VALUES(9);").WillReturnResult(9, 1)
+	if _, err := NewInsertBuilder().Insert("Stock.Product").Fields("ProductID").ValuesFromMap(map[int]interface{}{0: 9}).Exec(ctx, tx, nil); err != nil {
+		t.Fatal(err)
+	}
+	rec.Expect("SELECT ProductID FROM Stock.Product;").WillReturnRows(querytest.NewRows("ProductID").AddRow(9))
+	if rows, err = NewSelectBuilder().Select("ProductID").From("Stock.Product").Query(ctx, tx, nil); err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	wantCalls := []string{
+		"global before UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;",
+		"runner before UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3;",
+		"runner after UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3; rows=4 err=<nil> ctx=runner",
+		"global after UPDATE Stock.Product SET Price=$1 WHERE CategoryID=3; rows=4 err=<nil> ctx=runner",
+		"global before SELECT ProductID FROM Stock.Product WHERE CategoryID=$1;",
+		"runner before SELECT ProductID FROM Stock.Product WHERE CategoryID=$1;",
+		"runner after SELECT ProductID FROM Stock.Product WHERE CategoryID=$1; rows=-1 err=<nil> ctx=runner",
+		"global after SELECT ProductID FROM Stock.Product WHERE CategoryID=$1; rows=-1 err=<nil> ctx=runner",
+		"global before DELETE FROM Stock.Product WHERE CategoryID=3;",
+		"global after DELETE FROM Stock.Product WHERE CategoryID=3; rows=-1 err=locked ctx=global",
+		"global before INSERT INTO Stock.Product (ProductID) VALUES(9);",
+		"global after INSERT INTO Stock.Product (ProductID) VALUES(9); rows=1 err=<nil> ctx=global",
+		"global before SELECT ProductID FROM Stock.Product;",
+		"global after SELECT ProductID FROM Stock.Product; rows=-1 err=<nil> ctx=global",
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("got calls = %q \n want = %q", calls, wantCalls)
+	}
+	wantRecords := []string{
+		"DEBUG query statement update query " + DebugMarker + "UPDATE Stock.Product SET Price=<redacted> WHERE CategoryID=3; duration - rows 4",
+		"DEBUG query statement select query " + DebugMarker + "SELECT ProductID FROM Stock.Product WHERE CategoryID=3; duration - rows -1",
+	}
+	if !reflect.DeepEqual(logger.records, wantRecords) {
+		t.Errorf("got records = %q \n want = %q", logger.records, wantRecords)
+	}
+	if want := []string{"update ok", "select ok"}; !reflect.DeepEqual(metrics.observed, want) {
+		t.Errorf("got observed = %v \n want = %v", metrics.observed, want)
+	}
+	if want := map[string]int64{"update": 4}; !reflect.DeepEqual(metrics.rows, want) {
+		t.Errorf("got rows = %v \n want = %v", metrics.rows, want)
+	}
+
+	logger.records = nil
+	(&LogHook{Logger: logger, Slow: time.Millisecond}).AfterQuery(ctx, "SELECT 1;", nil, time.Second, -1, nil)
+	(&LogHook{Logger: logger}).AfterQuery(ctx, "SELECT 1;", nil, time.Second, -1, errors.New("timeout"))
+	wantRecords = []string{
+		"WARN slow query statement select query " + DebugMarker + "SELECT 1; duration - rows -1",
+		"ERROR query failed statement select query " + DebugMarker + "SELECT 1; duration - rows -1 error timeout",
+	}
+	if !reflect.DeepEqual(logger.records, wantRecords) {
+		t.Errorf("got records = %q \n want = %q", logger.records, wantRecords)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
 	tests := []struct {
 		name string
 		want string
-		exec func() string
+		exec func() Fingerprint
 	}{
 		{
-			"insert1",
-			"INSERT INTO Person.Contact (Title,FirstName,LastName,PhoneNumber) VALUES('Mrs','Susan','Jerome','+2319057573110'),('Mr','George','Thane','+1222922843994');",
-			NewInsertBuilder().Insert("Person.Contact").Fields("Title", "FirstName", "LastName", "PhoneNumber").
-				ValuesFromMap(map[int]interface{}{
-					0: "Mrs",
-					1: "Susan",
-					2: "Jerome",
-					3: "+2319057573110",
-				}).ValuesSet(map[int]interface{}{
-				0: "Mr",
-				1: "George",
-				2: "Thane",
-				3: "+1222922843994",
-			}).String,
+			"fingerprint1",
+			"SELECT * FROM sales.orderheader WHERE storeid=? AND soh.storeid IN(...) AND note=? AND paid=? AND price>?",
+			NewSelectBuilder().SelectAll("Sales.OrderHeader").Where(Eq("StoreID", 3)).WhereFieldIn("soh.StoreID", 1, 2).
+				And("Note='a, b' AND Paid=true AND Price>-2.5").Fingerprint,
+		},
+		{
+			"fingerprint2",
+			`INSERT INTO "Person"."Contact"("Title","FirstName") VALUES(...) RETURNING contactid`,
+			func() Fingerprint {
+				return FingerprintSQL(`insert into "Person"."Contact" ("Title", [FirstName]) values ('Mr', 'x'), (@p1, :name) -- c
+					returning ContactID;`)
+			},
+		},
+		{
+			"fingerprint3",
+			"SELECT count(*) AS n,x::text FROM t WHERE a=? AND b=ANY(ARRAY[...]) LIMIT ? OFFSET ?",
+			func() Fingerprint {
+				return FingerprintSQL("SELECT COUNT(*) AS N, x::TEXT FROM T WHERE a = $1 AND b=ANY(ARRAY[1,2]) LIMIT 10 OFFSET ?")
+			},
+		},
+		{
+			"fingerprint4",
+			`SELECT * FROM "Order" WHERE(note=? OR storeid=?) AND storeid IN(...) AND note=? AND "Note"=?`,
+			NewSelectBuilder().SelectAll(`"Order"`).Where("Note='StoreID IN(1,2)' OR StoreID=1").WhereFieldInWith(InList, "StoreID", 1, 2).
+				And(`"note"=1 AND "Note"=2`).Fingerprint,
+		},
+		{
+			"fingerprint5",
+			"DELETE FROM sales.orderheader WHERE(storeid IN(...) OR paid=?) AND storeid IN(...)",
+			NewDeleteBuilder().Delete("Sales.OrderHeader").WhereFieldIn("StoreID", 1, 2).Or("Paid=true").
+				WhereFieldIn("StoreID", 3, 4).Fingerprint,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.exec(); got != tt.want {
-				t.Errorf("got = {%v} \n want = {%v}", got, tt.want)
+			got := tt.exec()
+			if got.Normalized != tt.want {
+				t.Errorf("got = {%v} \n want = {%v}", got.Normalized, tt.want)
+			}
+			if want := FingerprintSQL(tt.want).Hash; got.Hash != want || len(got.Hash) != 16 {
+				t.Errorf("got hash = %v, want %v", got.Hash, want)
 			}
 		})
 	}
+
+	values := make([]interface{}, 2*InChunkSize+1)
+	for ix := range values {
+		values[ix] = ix
+	}
+	want := NewSelectBuilder().SelectAll("Sales.OrderHeader").WhereFieldIn("StoreID", Named("ids")).And(Eq("Paid", true)).Fingerprint()
+	for _, d := range []Dialect{Postgres, MySQL, SQLServer} {
+		for _, st := range []InStrategy{InAuto, InList, InArray, InChunks, InValues} {
+			got := NewSelectBuilder().WithDialect(d).SelectAll("Sales.OrderHeader").
+				WhereFieldInWith(st, "StoreID", values...).And(Eq("Paid", false)).Fingerprint()
+			if got != want {
+				t.Errorf("%v, strategy %v: got = {%v} \n want = {%v}", d, st, got.Normalized, want.Normalized)
+			}
+		}
+	}
+	got := NewDeleteBuilder().WithDialect(SQLServer).Delete("Sales.OrderHeader").WhereFieldIn("StoreID", values...).Fingerprint()
+	if want := "DELETE FROM sales.orderheader WHERE storeid IN(...)"; got.Normalized != want {
+		t.Errorf("delete: got = {%v} \n want = {%v}", got.Normalized, want)
+	}
 }
 
-func TestDeleteBuilder_Delete(t *testing.T) {
+func TestComment(t *testing.T) {
+	saved := sources
+	defer func() { sources = saved }()
+	sources = nil
+
+	AddCommentSource(func(ctx context.Context) map[string]string {
+		return map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "route": "overridden"}
+	})
+	ctx := WithTag(context.Background(), "route", "/orders/{id}")
+	ctx = WithTag(ctx, "controller", "it's orders")
+
+	_, file, line, _ := runtime.Caller(0)
+	got := NewSelectBuilder().SelectAll("Sales.OrderHeader").Where("StoreID=3").Comment(ctx).String()
+	caller := url.QueryEscape(filepath.Base(filepath.Dir(file)) + "/" + filepath.Base(file) + ":" + strconv.Itoa(line+1))
+	want := "SELECT * FROM Sales.OrderHeader WHERE StoreID=3 /*caller='" + caller +
+		"',controller='it%27s%20orders',route='%2Forders%2F%7Bid%7D',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/;"
+	if got != want {
+		t.Errorf("got = {%v} \n want = {%v}", got, want)
+	}
+
+	sources = nil
+	qry, _, err := NewUpdateBuilder().WithDialect(MySQL).Update("Stock.Product").Set(Eq("Price", Named("price"))).
+		Where("ProductID=2").Bind(map[string]interface{}{"price": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, line, _ = runtime.Caller(0)
+	got = AppendComment(context.Background(), qry)
+	want = "UPDATE Stock.Product SET Price=? WHERE ProductID=2 /*caller='" +
+		url.QueryEscape(filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file)+":"+strconv.Itoa(line+1)) + "'*/;"
+	if got != want {
+		t.Errorf("got = {%v} \n want = {%v}", got, want)
+	}
+	if fp := FingerprintSQL(got).Normalized; fp != "UPDATE stock.product SET price=? WHERE productid=?" {
+		t.Errorf("got fingerprint = {%v}", fp)
+	}
+}
+
+func TestDDLBuilders(t *testing.T) {
 	tests := []struct {
 		name string
 		want string
 		exec func() string
 	}{
 		{
-			"delete1",
-			"DELETE FROM Stock.Product WHERE ProductID=20;",
-			NewDeleteBuilder().Delete("Stock.Product").Where("ProductID=20").String,
+			"createtable1",
+			"CREATE TABLE IF NOT EXISTS Sales.Store (StoreID int NOT NULL,StoreName varchar(50) NOT NULL DEFAULT '',ContactID int,CONSTRAINT pk_store PRIMARY KEY (StoreID),FOREIGN KEY (ContactID) REFERENCES Person.Contact (ContactID) ON DELETE SET NULL,CHECK (StoreID>0));",
+			NewCreateTableBuilder().CreateTable("Sales.Store").IfNotExists().
+				Column("StoreID", "int").NotNull().
+				Column("StoreName", "varchar(50)").NotNull().Default("''").
+				Column("ContactID", "int").
+				Constraint("pk_store").PrimaryKey("StoreID").
+				ForeignKey("ContactID").References("Person.Contact", "ContactID").OnDelete("SET NULL").
+				Check(G("StoreID", 0)).String,
 		},
 		{
-			"delete2",
-			"DELETE FROM Stock.ProductPrice WHERE ProductID>2 AND PacketUnitPrice>=2000 OR CartonUnitPrice>40000;",
-			NewDeleteBuilder().Delete("Stock.ProductPrice").WhereWithMap(map[int]interface{}{
-				0: "ProductID>2 AND",
-				1: "PacketUnitPrice>=2000 OR",
-				2: "CartonUnitPrice>40000",
-			}).String,
+			"createtable2",
+			"IF OBJECT_ID(N'Sales.Store',N'U') IS NULL CREATE TABLE Sales.Store (StoreID int,UNIQUE (StoreID));",
+			NewCreateTableBuilder().WithDialect(SQLServer).CreateTable("Sales.Store").IfNotExists().
+				Column("StoreID", "int").Unique("StoreID").String,
 		},
 		{
-			"delete3",
-			"DELETE FROM Sales.OrderDetail WHERE OrderID>100 OR TotalAmountDue>90000 AND DueDate='10/11/2020';",
-			NewDeleteBuilder().Delete("Sales.OrderDetail").Where(G("OrderID", 100)).Or(G("TotalAmountDue", 90000)).And(Eq("DueDate", "10/11/2020")).String,
+			"altertable1",
+			"ALTER TABLE Person.Contact ADD COLUMN Email varchar(100) NOT NULL DEFAULT '',DROP COLUMN Fax;ALTER TABLE Person.Contact RENAME COLUMN Phone TO PhoneNumber;ALTER TABLE Person.Contact ADD CONSTRAINT uq_email UNIQUE (Email);",
+			NewAlterTableBuilder().AlterTable("Person.Contact").AddColumn("Email", "varchar(100)").NotNull().Default("''").
+				DropColumn("Fax").RenameColumn("Phone", "PhoneNumber").AddUnique("uq_email", "Email").String,
+		},
+		{
+			"altertable2",
+			"ALTER TABLE Person.Contact ADD Email varchar(100);EXEC sp_rename 'Person.Contact.Phone','PhoneNumber','COLUMN';ALTER TABLE Person.Contact ADD CONSTRAINT fk_address FOREIGN KEY (AddressID) REFERENCES Person.Address (AddressID);",
+			NewAlterTableBuilder().WithDialect(SQLServer).AlterTable("Person.Contact").AddColumn("Email", "varchar(100)").
+				RenameColumn("Phone", "PhoneNumber").AddForeignKey("fk_address", "AddressID").References("Person.Address", "AddressID").String,
+		},
+		{
+			"altertable3",
+			"ALTER TABLE Person.Contact ADD COLUMN Email varchar(100) NOT NULL,RENAME COLUMN Phone TO PhoneNumber,DROP COLUMN Fax;",
+			NewAlterTableBuilder().AlterTable("Person.Contact").AddColumn("Email", "varchar(100)").NotNull().
+				RenameColumn("Phone", "PhoneNumber").DropColumn("Fax").WithDialect(MySQL).String,
+		},
+		{
+			"altertable4",
+			"ALTER TABLE Person.Contact ADD Email varchar(100);EXEC sp_rename 'Person.Contact.Phone','PhoneNumber','COLUMN';",
+			NewAlterTableBuilder().AlterTable("Person.Contact").AddColumn("Email", "varchar(100)").
+				RenameColumn("Phone", "PhoneNumber").WithDialect(SQLServer).String,
+		},
+		{
+			"createindex1",
+			"CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS ix_contact_email ON Person.Contact (StoreID,(lower(Email))) WHERE DeletedAt IS NULL;",
+			NewCreateIndexBuilder().CreateIndex("ix_contact_email").Unique().Concurrently().IfNotExists().
+				On("Person.Contact", "StoreID").Expression("lower(Email)").Where(IsNull("DeletedAt")).String,
+		},
+		{
+			"createindex2",
+			"CREATE INDEX ix_order_store ON Sales.OrderHeader (StoreID) ALGORITHM=INPLACE LOCK=NONE;",
+			NewCreateIndexBuilder().WithDialect(MySQL).CreateIndex("ix_order_store").Concurrently().IfNotExists().
+				On("Sales.OrderHeader", "StoreID").String,
+		},
+		{
+			"createindex3",
+			"IF NOT EXISTS (SELECT * FROM sys.indexes WHERE name='ix_order_store' AND object_id=OBJECT_ID('Sales.OrderHeader')) CREATE INDEX ix_order_store ON Sales.OrderHeader (StoreID) WHERE StoreID IS NOT NULL;",
+			NewCreateIndexBuilder().WithDialect(SQLServer).CreateIndex("ix_order_store").IfNotExists().
+				On("Sales.OrderHeader", "StoreID").Where("StoreID IS NOT NULL").String,
+		},
+		{
+			"drop1",
+			"DROP TABLE IF EXISTS Sales.OrderDetail,Sales.OrderHeader CASCADE;",
+			NewDropBuilder().DropTable("Sales.OrderDetail", "Sales.OrderHeader").IfExists().Cascade().String,
+		},
+		{
+			"drop2",
+			"DROP INDEX ix_order_store ON Sales.OrderHeader;",
+			NewDropBuilder().WithDialect(SQLServer).DropIndex("ix_order_store").On("Sales.OrderHeader").Cascade().String,
+		},
+		{
+			"drop3",
+			"DROP INDEX ix_order_store ON Sales.OrderHeader;",
+			NewDropBuilder().WithDialect(MySQL).DropIndex("ix_order_store").On("Sales.OrderHeader").IfExists().Cascade().String,
 		},
 	}
 	for _, tt := range tests {
@@ -218,6 +2011,25 @@ func TestDeleteBuilder_Delete(t *testing.T) {
 			}
 		})
 	}
+
+	var unsupported *UnsupportedError
+	c := NewCreateIndexBuilder().WithDialect(MySQL).CreateIndex("ix_order_store").On("Sales.OrderHeader", "StoreID").Where("StoreID IS NOT NULL")
+	if !errors.As(c.Err(), &unsupported) {
+		t.Errorf("partial index on mysql: got err = %v, want an *UnsupportedError", c.Err())
+	}
+	d := NewDropBuilder().WithDialect(MySQL).DropIndex("ix_order_store").On("Sales.OrderHeader")
+	if err := d.Err(); err != nil {
+		t.Errorf("drop index on mysql: got err = %v, want nil", err)
+	}
+	if !errors.As(d.IfExists().Err(), &unsupported) {
+		t.Errorf("drop index if exists on mysql: got err = %v, want an *UnsupportedError", d.Err())
+	}
+	if !errors.As(d.DropIndex("ix_order_store").Cascade().Err(), &unsupported) {
+		t.Errorf("drop index cascade on mysql: got err = %v, want an *UnsupportedError", d.Err())
+	}
+	if err := d.DropTable("Sales.OrderHeader").IfExists().Err(); err != nil {
+		t.Errorf("drop table if exists on mysql: got err = %v, want nil", err)
+	}
 }
 
 var tables = []string{
@@ -363,3 +2175,72 @@ func BenchmarkDeleteBuilder_Delete(b *testing.B) {
 	}
 	result.Clear()
 }
+
+func BenchmarkSelectBuilder_String(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := rand.Intn(5)
+		_ = NewSelectBuilder().Select(fields[r]...).From(tables[r]).Where(Eq("StoreID", 3)).
+			And(G("TotalAmountDue", 100)).OrderBy(columns[r]).Limit(20).String()
+	}
+}
+
+func BenchmarkSelectBuilder_Bind(b *testing.B) {
+	params := map[string]interface{}{"store": 3, "min": 100}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := rand.Intn(5)
+		_, _, err := NewSelectBuilder().Select(fields[r]...).From(tables[r]).Where(Eq("StoreID", Named("store"))).
+			And(G("TotalAmountDue", Named("min"))).OrderBy(columns[r]).Limit(20).Bind(params)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTemplate_Args(b *testing.B) {
+	var templates [5]*Template
+	for r := range templates {
+		templates[r] = NewSelectBuilder().Select(fields[r]...).From(tables[r]).Where(Eq("StoreID", Named("store"))).
+			And(G("TotalAmountDue", Named("min"))).OrderBy(columns[r]).Limit(20).Compile()
+	}
+	params := map[string]interface{}{"store": 3, "min": 100}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tpl := templates[rand.Intn(5)]
+		if _, err := tpl.Args(params); err != nil {
+			b.Fatal(err)
+		}
+		_ = tpl.SQL()
+	}
+}
+
+func BenchmarkSelectBuilder_WhereFieldIn10k(b *testing.B) {
+	ids := make([]interface{}, 10000)
+	for i := range ids {
+		ids[i] = i
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = NewSelectBuilder().SelectAll("Stock.Product").WhereFieldIn("ProductID", ids...).String()
+	}
+}
+
+func BenchmarkInsertBuilder_ValuesSet10k(b *testing.B) {
+	rows := make([]map[int]interface{}, 10000)
+	for i := range rows {
+		rows[i] = map[int]interface{}{0: i, 1: "Mrs", 2: "Susan", 3: "+2319057573110"}
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ins := NewInsertBuilder().Insert("Person.Contact").Fields("ContactID", "Title", "FirstName", "PhoneNumber").
+			ValuesFromMap(rows[0])
+		for _, row := range rows[1:] {
+			ins.ValuesSet(row)
+		}
+		_ = ins.String()
+	}
+}
@@ -0,0 +1,171 @@
+package querytest
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type connector struct {
+	r *Recorder
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{r: c.r}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return drv{c.r}
+}
+
+type drv struct {
+	r *Recorder
+}
+
+func (d drv) Open(string) (driver.Conn, error) {
+	return &conn{r: d.r}, nil
+}
+
+type conn struct {
+	r *Recorder
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	c.r.recordTx("BEGIN")
+	return tx{c.r}, nil
+}
+
+// CheckNamedValue accepts every argument as it is,
+// so that expectations compare the values passed by the caller.
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.r.record(Call{Query: query, Args: values(args)})
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.result == nil {
+		return driver.RowsAffected(0), nil
+	}
+	return e.result, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.r.record(Call{Query: query, Args: values(args)})
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.rows == nil {
+		return &rows{}, nil
+	}
+
+	converted := make([][]driver.Value, len(e.rows.values))
+	for i, row := range e.rows.values {
+		if len(row) != len(e.rows.columns) {
+			return nil, fmt.Errorf("querytest: row %d has %d values for %d columns", i, len(row), len(e.rows.columns))
+		}
+		converted[i] = make([]driver.Value, len(row))
+		for j, v := range row {
+			if converted[i][j], err = driver.DefaultParameterConverter.ConvertValue(v); err != nil {
+				return nil, fmt.Errorf("querytest: row %d column %s: %v", i, e.rows.columns[j], err)
+			}
+		}
+	}
+	return &rows{columns: e.rows.columns, values: converted}, nil
+}
+
+func values(args []driver.NamedValue) []interface{} {
+	vals := make([]interface{}, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	return vals
+}
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("querytest: Stmt.Exec is not supported, use ExecContext")
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("querytest: Stmt.Query is not supported, use QueryContext")
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+func (s *stmt) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+type tx struct {
+	r *Recorder
+}
+
+func (t tx) Commit() error {
+	t.r.recordTx("COMMIT")
+	return nil
+}
+
+func (t tx) Rollback() error {
+	t.r.recordTx("ROLLBACK")
+	return nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
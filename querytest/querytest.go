@@ -0,0 +1,212 @@
+// Package querytest provides an in-process database/sql driver recording
+// every statement executed through it, so that code executing the queries
+// built by package query can be unit tested without a live database.
+//
+// Tests register the queries they expect along with canned rows, results
+// or errors, and check that every expectation was met:
+//
+//	rec := querytest.New()
+//	db := rec.DB()
+//	rec.ExpectBuilder(query.NewSelectBuilder().Select("ContactID").From("Person.Contact")).
+//		WillReturnRows(querytest.NewRows("ContactID").AddRow(1).AddRow(2))
+//
+//	// ... code under test using db ...
+//
+//	if err := rec.ExpectationsWereMet(); err != nil {
+//		t.Error(err)
+//	}
+package querytest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Call is a statement executed through a Recorder's database
+type Call struct {
+	Query string
+	Args  []interface{}
+}
+
+// Recorder records the statements executed through its database
+// and answers them from the registered expectations.
+type Recorder struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	unexpected   []Call
+}
+
+// New returns a new *Recorder
+func New() *Recorder {
+	return new(Recorder)
+}
+
+// DB returns a database whose statements are recorded by r,
+// every call returns a new *sql.DB sharing r.
+func (r *Recorder) DB() *sql.DB {
+	return sql.OpenDB(connector{r})
+}
+
+// Expect registers a query expected to be executed exactly as written
+func (r *Recorder) Expect(query string) *Expectation {
+	return r.add(&Expectation{desc: query, match: func(q string) bool { return q == query }})
+}
+
+// ExpectRegexp registers a query expected to match pattern,
+// it panics if pattern doesn't compile.
+func (r *Recorder) ExpectRegexp(pattern string) *Expectation {
+	re := regexp.MustCompile(pattern)
+	return r.add(&Expectation{desc: "/" + pattern + "/", match: re.MatchString})
+}
+
+// ExpectBuilder registers the query and args b, any builder of package query,
+// binds without parameters, as executed through query.Runner or the builders'
+// Exec and Query methods. It panics if b fails to bind.
+func (r *Recorder) ExpectBuilder(b interface {
+	Bind(params map[string]interface{}) (string, []interface{}, error)
+}) *Expectation {
+	qry, args, err := b.Bind(nil)
+	if err != nil {
+		panic("querytest: " + err.Error())
+	}
+	return r.Expect(qry).WithArgs(args...)
+}
+
+func (r *Recorder) add(e *Expectation) *Expectation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expectations = append(r.expectations, e)
+	return e
+}
+
+// Calls returns every statement executed so far, in order.
+// Transactions are recorded as BEGIN, COMMIT and ROLLBACK calls,
+// which never need an expectation.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// ExpectationsWereMet reports expectations that weren't met
+// and statements that weren't expected.
+func (r *Recorder) ExpectationsWereMet() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var problems []string
+	for _, e := range r.expectations {
+		if !e.met {
+			problems = append(problems, "expected query "+e.desc+" was not executed")
+		}
+	}
+	for _, c := range r.unexpected {
+		problems = append(problems, fmt.Sprintf("unexpected query %q with args %v", c.Query, c.Args))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("querytest: " + strings.Join(problems, "\n\t"))
+}
+
+// record stores call, the expectation answering it is returned
+func (r *Recorder) record(call Call) (*Expectation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+
+	for _, e := range r.expectations {
+		if e.met || !e.match(call.Query) {
+			continue
+		}
+		if e.args != nil && !reflect.DeepEqual(e.args, call.Args) {
+			continue
+		}
+		e.met = true
+		return e, nil
+	}
+	r.unexpected = append(r.unexpected, call)
+	return nil, fmt.Errorf("querytest: unexpected query %q with args %v", call.Query, call.Args)
+}
+
+func (r *Recorder) recordTx(stmt string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Query: stmt})
+}
+
+// Expectation is a query expected to be executed,
+// it is met by the first matching statement.
+type Expectation struct {
+	desc   string
+	match  func(query string) bool
+	args   []interface{}
+	rows   *Rows
+	result driver.Result
+	err    error
+	met    bool
+}
+
+// WithArgs only matches statements executed with args
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	if args == nil {
+		args = []interface{}{}
+	}
+	e.args = args
+	return e
+}
+
+// WillReturnRows answers the query with rows
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult answers a statement executed with Exec
+func (e *Expectation) WillReturnResult(lastInsertID int64, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID, rowsAffected}
+	return e
+}
+
+// WillReturnError fails the statement with err
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Rows are the canned rows answering a query
+type Rows struct {
+	columns []string
+	values  [][]interface{}
+}
+
+// NewRows returns empty rows with the given columns
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow adds a row holding values, one per column
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	r.values = append(r.values, values)
+	return r
+}
+
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
@@ -0,0 +1,84 @@
+package querytest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/danvixent/query"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := New()
+	db := rec.DB()
+	defer db.Close()
+
+	sel := query.NewSelectBuilder().Select("ContactID", "FirstName").From("Person.Contact").Where(query.G("ContactID", 100))
+	rec.ExpectBuilder(sel).WillReturnRows(NewRows("ContactID", "FirstName").AddRow(101, "Kelly").AddRow(102, "Gary"))
+	rec.ExpectRegexp(`^UPDATE Stock\.Product SET`).WithArgs(int64(3)).WillReturnResult(0, 2)
+	rec.Expect("DELETE FROM Stock.Product;").WillReturnError(errors.New("denied"))
+
+	qry, args, err := sel.Bind(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.Query(qry, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if strings.Join(names, ",") != "Kelly,Gary" {
+		t.Errorf("got names %v", names)
+	}
+
+	res, err := db.ExecContext(context.Background(), "UPDATE Stock.Product SET Quantity=0 WHERE CategoryID=$1", int64(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Errorf("got %d rows affected, want 2", n)
+	}
+
+	if err := rec.ExpectationsWereMet(); err == nil {
+		t.Error("the DELETE expectation hasn't been met yet")
+	}
+	if _, err := db.Exec(query.NewDeleteBuilder().Delete("Stock.Product").String()); err == nil || err.Error() != "denied" {
+		t.Errorf("got error %v, want denied", err)
+	}
+	if err := rec.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := db.Exec("SELECT 1;"); err == nil {
+		t.Error("unexpected queries should fail")
+	}
+	if err := rec.ExpectationsWereMet(); err == nil || !strings.Contains(err.Error(), `unexpected query "SELECT 1;"`) {
+		t.Errorf("got %v, want the unexpected query reported", err)
+	}
+	if calls := rec.Calls(); len(calls) != 4 {
+		t.Errorf("got %d calls, want 4", len(calls))
+	}
+
+	upd := query.NewUpdateBuilder().Update("Stock.Product").SetFromMap(map[int]interface{}{0: query.Eq("Quantity", query.Expr("?", 0))}).
+		Where(query.Eq("ProductID", query.Expr("?", 7)))
+	rec.ExpectBuilder(upd).WillReturnResult(0, 1)
+	qry, args, err = upd.Bind(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(upd.String()); err == nil {
+		t.Error("the query rendered by String shouldn't match the bound one")
+	}
+	if _, err := db.Exec(qry, args...); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,8 +1,24 @@
 package query
 
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
 //SelectBuilder is bulider for select statement
 type SelectBuilder struct {
-	query string
+	query   strings.Builder
+	dialect Dialect
+	// hasWhere is set once the query has a WHERE clause,
+	// which starts at whereAt in query
+	hasWhere bool
+	whereAt  int
+	idents   identifiers
+	// err is the first error of the query, reported by Err
+	err error
+	// lists are the conditions of WhereFieldIn, for Fingerprint
+	lists inLists
 }
 
 //NewSelectBuilder returns a pointer to a new SelectBuilder
@@ -10,15 +26,59 @@ func NewSelectBuilder() *SelectBuilder {
 	return new(SelectBuilder)
 }
 
+//WithDialect sets the dialect the builder's query is rendered for
+func (s *SelectBuilder) WithDialect(d Dialect) *SelectBuilder {
+	s.dialect = d
+	return s
+}
+
+//WithIdentMode sets how the table, column and alias names given
+//to the builder are rendered, they are added verbatim by default.
+func (s *SelectBuilder) WithIdentMode(m IdentMode) *SelectBuilder {
+	s.idents.mode = m
+	return s
+}
+
+//Err returns the first error since the query was started: a name rejected
+//by IdentStrict, a sort spec rejected by OrderByFromRequest or ErrNamedList.
+func (s *SelectBuilder) Err() error {
+	return s.err
+}
+
+//name renders name as set by the builder's IdentMode,
+//keeping the error of a name it rejects for Err
+func (s *SelectBuilder) name(name string) string {
+	rendered, err := s.idents.name(s.dialect, name)
+	s.fail(err)
+	return rendered
+}
+
+//names renders each of names like name
+func (s *SelectBuilder) names(names []string) []string {
+	rendered, err := s.idents.names(s.dialect, names)
+	s.fail(err)
+	return rendered
+}
+
+//fail keeps err for Err, unless the query already failed
+func (s *SelectBuilder) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
 //Select adds a select statement to the builder's query
 func (s *SelectBuilder) Select(fields ...string) *SelectBuilder {
-	s.query = addFields("SELECT", false, fields...)
+	s.Clear()
+	addFields(&s.query, "SELECT", false, s.names(fields)...)
 	return s
 }
 
 //SelectAll adds a SELECT * statement to the builder's query
 func (s *SelectBuilder) SelectAll(table string) *SelectBuilder {
-	s.query = "SELECT * FROM " + table
+	s.Clear()
+	s.query.WriteString("SELECT * FROM ")
+	s.query.WriteString(s.name(table))
 	return s
 }
 
@@ -26,18 +86,35 @@ func (s *SelectBuilder) SelectAll(table string) *SelectBuilder {
 //Select MUST be called prior to From, on the
 //same *SelectBuilder.
 func (s *SelectBuilder) From(table string) *SelectBuilder {
-	s.query += " FROM " + table
+	s.query.WriteString(" FROM ")
+	s.query.WriteString(s.name(table))
 	return s
 }
 
 //Where adds a WHERE clause to the builder's query
 //From MUST be called prior to Where, on the
 //same *SelectBuilder.
+//Once there's a WHERE clause, condition is added along with an AND,
+//the clause and condition being parenthesized if they have an OR.
 func (s *SelectBuilder) Where(condition string) *SelectBuilder {
-	s.query += where(condition)
+	if s.hasWhere {
+		if andWhere(&s.query, s.whereAt, condition) {
+			s.lists.parenthesized(s.whereAt)
+		}
+		return s
+	}
+	s.markWhere()
+	where(&s.query, condition)
 	return s
 }
 
+func (s *SelectBuilder) markWhere() {
+	if !s.hasWhere {
+		s.whereAt = s.query.Len()
+		s.hasWhere = true
+	}
+}
+
 //WhereWithMap adds a WHERE clause to the builder's query with fields and conditions
 //derived from fieldToCond, which should map fields to conditions desired to
 //be met.
@@ -49,13 +126,44 @@ func (s *SelectBuilder) Where(condition string) *SelectBuilder {
 //			1: "BarcodeID=22",
 //	})
 func (s *SelectBuilder) WhereWithMap(ixToCond map[int]interface{}) *SelectBuilder {
-	s.query += withMap("WHERE", ixToCond, false)
+	if len(ixToCond) == 0 {
+		return s
+	}
+	if s.hasWhere {
+		return s.Where(mapCondition(ixToCond))
+	}
+	s.markWhere()
+	withMap(&s.query, "WHERE", ixToCond, false)
 	return s
 }
 
-//WhereFieldIn adds a WHERE clause along with an IN operator
+//WhereFieldIn adds a WHERE clause along with an IN operator,
+//large lists are rendered with the strategy InAuto picks for the dialect.
 func (s *SelectBuilder) WhereFieldIn(field string, values ...interface{}) *SelectBuilder {
-	s.query += whereIn(field, values...)
+	return s.WhereFieldInWith(InAuto, field, values...)
+}
+
+//WhereFieldInWith adds a WHERE clause matching field against values
+//with the specified strategy.
+//
+//Usage example:
+//	WhereFieldInWith(InArray, "ProductID", Named("ids")).Bind(map[string]interface{}{"ids": ids})
+func (s *SelectBuilder) WhereFieldInWith(strategy InStrategy, field string, values ...interface{}) *SelectBuilder {
+	if values == nil {
+		return s
+	}
+	if err := strategy.check(s.dialect, values); err != nil {
+		s.fail(err)
+		return s
+	}
+	keyword := conditionKeyword(s.hasWhere)
+	if s.hasWhere {
+		if parenthesizeWhere(&s.query, s.whereAt) {
+			s.lists.parenthesized(s.whereAt)
+		}
+	}
+	s.markWhere()
+	s.lists = append(s.lists, whereInWith(&s.query, keyword, s.dialect, strategy, field, values...))
 	return s
 }
 
@@ -63,19 +171,19 @@ func (s *SelectBuilder) WhereFieldIn(field string, values ...interface{}) *Selec
 //
 //it adds an AND along with the condition specified
 func (s *SelectBuilder) And(condition string) *SelectBuilder {
-	s.query += and(condition)
+	and(&s.query, condition)
 	return s
 }
 
 //Offset  adds AN OFFSET clause to the query
 func (s *SelectBuilder) Offset(num uint64) *SelectBuilder {
-	s.query += offset(num)
+	offset(&s.query, num)
 	return s
 }
 
 //Limit adds a LIMIT clause to the query
 func (s *SelectBuilder) Limit(num uint64) *SelectBuilder {
-	s.query += limit(num)
+	limit(&s.query, num)
 	return s
 }
 
@@ -83,45 +191,195 @@ func (s *SelectBuilder) Limit(num uint64) *SelectBuilder {
 //
 //it adds an OR along with the condition specified
 func (s *SelectBuilder) Or(condition string) *SelectBuilder {
-	s.query += or(condition)
+	or(&s.query, condition)
 	return s
 }
 
-//OrderBy adds an ORDER BY clause to the builder's query
-func (s *SelectBuilder) OrderBy(field string) *SelectBuilder {
-	s.query += " ORDER BY " + field
+//OrderBy adds an ORDER BY clause on fields to the builder's query,
+//Asc and Desc apply to the last field.
+func (s *SelectBuilder) OrderBy(fields ...string) *SelectBuilder {
+	if len(fields) == 0 {
+		return s
+	}
+	addFields(&s.query, " ORDER BY", false, s.names(fields)...)
+	return s
+}
+
+//OrderByFromRequest adds an ORDER BY clause parsed from spec, a client's
+//comma-separated list of fields to sort by, each of which is optionally
+//prefixed with - for DESC (ASC is the default) and suffixed with :nullsfirst
+//or :nullslast. allowed maps the fields clients may sort by to their column.
+//An empty spec adds nothing.
+//
+//Fields missing from allowed and malformed specs aren't added, a *SortError
+//is reported by Err and Bind instead. NULLS FIRST and LAST are emulated
+//with a CASE on MySQL and SQL Server.
+//
+//Usage example:
+//	OrderByFromRequest("-created_at,name:nullslast", map[string]string{
+//		"created_at": "soh.OrderDate",
+//		"name":       "pc.LastName",
+//	})
+func (s *SelectBuilder) OrderByFromRequest(spec string, allowed map[string]string) *SelectBuilder {
+	if strings.TrimSpace(spec) == "" {
+		return s
+	}
+	terms, err := sortTerms(s.dialect, spec, allowed, func(col string) string {
+		return s.name(col)
+	})
+	if err != nil {
+		s.fail(err)
+		return s
+	}
+	addFields(&s.query, " ORDER BY", false, terms...)
 	return s
 }
 
 //GroupBy adds a GROUP BY clause the builder's query
 func (s *SelectBuilder) GroupBy(field string) *SelectBuilder {
-	s.query += " GROUP BY " + field
+	s.query.WriteString(" GROUP BY ")
+	s.query.WriteString(s.name(field))
 	return s
 }
 
 //Asc adds ASC for ordering
 func (s *SelectBuilder) Asc() *SelectBuilder {
-	s.query += " ASC"
+	s.query.WriteString(" ASC")
 	return s
 }
 
 //Desc adds DESC for ordering
 func (s *SelectBuilder) Desc() *SelectBuilder {
-	s.query += " DESC"
+	s.query.WriteString(" DESC")
 	return s
 }
 
 //Distinct adds a DISTINCT clause the builder's query
 func (s *SelectBuilder) Distinct(fields ...string) *SelectBuilder {
-	s.query += addFields("DISTINCT", false, fields...)
+	addFields(&s.query, "DISTINCT", false, s.names(fields)...)
+	return s
+}
+
+//WhereIf adds condition like Where, only if cond is true
+func (s *SelectBuilder) WhereIf(cond bool, condition string) *SelectBuilder {
+	if cond {
+		s.Where(condition)
+	}
+	return s
+}
+
+//AndIf adds condition along with an AND, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (s *SelectBuilder) AndIf(cond bool, condition string) *SelectBuilder {
+	if cond {
+		s.Where(condition)
+	}
 	return s
 }
 
+//OrIf adds condition along with an OR, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (s *SelectBuilder) OrIf(cond bool, condition string) *SelectBuilder {
+	if !cond {
+		return s
+	}
+	if s.hasWhere {
+		or(&s.query, condition)
+		return s
+	}
+	return s.Where(condition)
+}
+
+//WhereNonZero adds the condition built by op on field and v like Where,
+//only if v isn't a zero value. Non-nil pointers are never skipped.
+//
+//Usage example:
+//	WhereNonZero(Eq, "FirstName", req.FirstName).WhereNonZero(G, "TotalAmountDue", req.MinAmount)
+func (s *SelectBuilder) WhereNonZero(op Operator, field string, v interface{}) *SelectBuilder {
+	if !isZero(v) {
+		s.Where(op(field, v))
+	}
+	return s
+}
+
+//Comment appends the sqlcommenter comment of ctx to the query: the tags
+//set by WithTag and AddCommentSource, and the file:line Comment is called
+//from, e.g /*caller='orders%2Fstore.go%3A42',route='%2Forders'*/.
+//It should be called once the query is complete.
+func (s *SelectBuilder) Comment(ctx context.Context) *SelectBuilder {
+	s.query.WriteByte(' ')
+	s.query.WriteString(comment(ctx))
+	return s
+}
+
+//Bind renders the builder's query with named parameters replaced by the
+//dialect's positional placeholders, returning the values of params in
+//the same order. Names without a value and values not used by any name
+//are reported by a *BindError.
+func (s *SelectBuilder) Bind(params map[string]interface{}) (string, []interface{}, error) {
+	if s.err != nil {
+		return "", nil, s.err
+	}
+	return bindMap(s.sql(), s.dialect, params)
+}
+
+//BindStruct is like Bind, taking values from the fields of v, a struct
+//or a pointer to one. Fields are named after their db tag if they have one.
+func (s *SelectBuilder) BindStruct(v interface{}) (string, []interface{}, error) {
+	if s.err != nil {
+		return "", nil, s.err
+	}
+	return bindStruct(s.sql(), s.dialect, v)
+}
+
+//Debug binds params like Bind, then renders the query with the values
+//interpolated by Debug, for logs. It must never be executed.
+func (s *SelectBuilder) Debug(params map[string]interface{}, opts DebugOptions) (string, error) {
+	qry, args, err := s.Bind(params)
+	if err != nil {
+		return "", err
+	}
+	return Debug(qry, args, opts), nil
+}
+
+//Query binds params to the builder's query like Bind and runs it on ex,
+//a *sql.DB, *sql.Conn or *sql.Tx. The hooks registered by AddHook are called around it.
+func (s *SelectBuilder) Query(ctx context.Context, ex Executor, params map[string]interface{}) (*sql.Rows, error) {
+	return NewRunner(ex).Query(ctx, s, params)
+}
+
+//Compile renders the builder's query into a Template, whose named
+//parameters are bound to new values on each execution.
+func (s *SelectBuilder) Compile() *Template {
+	return compile(s.sql(), s.dialect)
+}
+
 //Clear erases the builder's query
 func (s *SelectBuilder) Clear() {
-	s.query = ""
+	s.query.Reset()
+	s.hasWhere = false
+	s.lists = nil
+	s.err = nil
 }
 
+//String returns the builder's query, with the values of an Expr rendered as ?
 func (s *SelectBuilder) String() string {
-	return s.query + ";"
+	return exprPlaceholders(s.sql())
+}
+
+//sql returns the builder's query, as bound by Bind
+func (s *SelectBuilder) sql() string {
+	return s.query.String() + ";"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (s *SelectBuilder) Pretty() string {
+	return Format(s.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query, whose
+//WhereFieldIn conditions have the same one whatever their InStrategy.
+func (s *SelectBuilder) Fingerprint() Fingerprint {
+	return fingerprint(s.sql(), s.lists)
 }
@@ -0,0 +1,67 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+)
+
+//SortError reports a sort spec rejected by OrderByFromRequest,
+//Item is the part of Spec which isn't allowed.
+type SortError struct {
+	Spec string
+	Item string
+}
+
+func (e *SortError) Error() string {
+	return "query: invalid sort " + strconv.Quote(e.Item) + " in " + strconv.Quote(e.Spec)
+}
+
+//sortTerms parses spec into the terms of an ORDER BY clause for d,
+//column renders the columns allowed maps public field names to.
+func sortTerms(d Dialect, spec string, allowed map[string]string, column func(string) string) ([]string, error) {
+	var terms []string
+	for _, item := range strings.Split(spec, ",") {
+		field := strings.TrimSpace(item)
+		dir := " ASC"
+		switch {
+		case strings.HasPrefix(field, "-"):
+			dir = " DESC"
+			field = field[1:]
+		case strings.HasPrefix(field, "+"):
+			field = field[1:]
+		}
+
+		var nulls string
+		if ix := strings.IndexByte(field, ':'); ix >= 0 {
+			switch strings.ToLower(field[ix+1:]) {
+			case "nullsfirst":
+				nulls = "FIRST"
+			case "nullslast":
+				nulls = "LAST"
+			default:
+				return nil, &SortError{Spec: spec, Item: item}
+			}
+			field = field[:ix]
+		}
+
+		col, ok := allowed[field]
+		if !ok {
+			return nil, &SortError{Spec: spec, Item: item}
+		}
+		col = column(col)
+
+		switch {
+		case nulls == "":
+			terms = append(terms, col+dir)
+		case d == Postgres:
+			terms = append(terms, col+dir+" NULLS "+nulls)
+		case nulls == "FIRST":
+			// MySQL and SQL Server have no NULLS FIRST/LAST,
+			// so nulls are sorted by a CASE first
+			terms = append(terms, "CASE WHEN "+col+" IS NULL THEN 0 ELSE 1 END", col+dir)
+		default:
+			terms = append(terms, "CASE WHEN "+col+" IS NULL THEN 1 ELSE 0 END", col+dir)
+		}
+	}
+	return terms, nil
+}
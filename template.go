@@ -0,0 +1,173 @@
+package query
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+)
+
+//Template is a query rendered once, with its named parameters already
+//replaced by the dialect's positional placeholders, so that only the
+//arguments are computed on each execution.
+//A Template is safe for concurrent use.
+type Template struct {
+	sql      string
+	names    []string
+	distinct int
+	// err is the error the builder's query was compiled with
+	err error
+
+	mu    sync.RWMutex
+	stmts map[*sql.DB]*sql.Stmt
+}
+
+//compile renders qry into a Template for d
+func compile(qry string, d Dialect) *Template {
+	t := new(Template)
+	t.err = exprErr(qry)
+	t.sql, _, _ = bindNamed(qry, d, func(name string) (interface{}, bool) {
+		if _, ok, _ := exprValue(name); !ok && !contains(t.names, name) {
+			t.distinct++
+		}
+		t.names = append(t.names, name)
+		return nil, true
+	})
+	return t
+}
+
+//Err returns the error the builder's query was compiled with, such as
+//ErrNoWhere for a guarded UPDATE or DELETE without a WHERE clause.
+//It is returned by Args, ArgsStruct and Stmt too.
+func (t *Template) Err() error {
+	return t.err
+}
+
+//SQL returns the template's query
+func (t *Template) SQL() string {
+	return t.sql
+}
+
+//Names returns the name of the parameter behind each placeholder, in order,
+//including the names generated for the values of an Expr.
+func (t *Template) Names() []string {
+	return append([]string(nil), t.names...)
+}
+
+//Args returns the values of params for each placeholder, in order.
+//Names without a value and values not used by any name
+//are reported by a *BindError.
+func (t *Template) Args(params map[string]interface{}) ([]interface{}, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	args := make([]interface{}, len(t.names))
+	var missing []string
+	var failed error //held values failing to bind are reported by compile
+	lookup := withExprValues(func(name string) (interface{}, bool) {
+		v, ok := params[name]
+		return v, ok
+	}, &failed)
+	for i, name := range t.names {
+		v, ok := lookup(name)
+		if !ok && !contains(missing, name) {
+			missing = append(missing, name)
+		}
+		args[i] = v
+	}
+	if len(missing) > 0 {
+		return nil, &BindError{Missing: missing}
+	}
+
+	// every name has a value by now, so there are more values than names
+	// only when some are unused
+	if len(params) > t.distinct {
+		var unused []string
+		for name := range params {
+			if !contains(t.names, name) {
+				unused = append(unused, name)
+			}
+		}
+		if len(unused) > 0 {
+			sort.Strings(unused)
+			return nil, &BindError{Unused: unused}
+		}
+	}
+	return args, nil
+}
+
+//ArgsStruct is like Args, taking values from the fields of v, a struct
+//or a pointer to one. Fields are named after their db tag if they have one.
+func (t *Template) ArgsStruct(v interface{}) ([]interface{}, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	lookup, err := structLookup(v)
+	if err != nil {
+		return nil, err
+	}
+	var failed error //held values failing to bind are reported by compile
+	lookup = withExprValues(lookup, &failed)
+
+	args := make([]interface{}, len(t.names))
+	var missing []string
+	for i, name := range t.names {
+		v, ok := lookup(name)
+		if !ok && !contains(missing, name) {
+			missing = append(missing, name)
+		}
+		args[i] = v
+	}
+	if len(missing) > 0 {
+		return nil, &BindError{Missing: missing}
+	}
+	return args, nil
+}
+
+//Stmt returns the template's query prepared on db,
+//it is prepared on the first call and cached for the next ones.
+func (t *Template) Stmt(ctx context.Context, db *sql.DB) (*sql.Stmt, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	t.mu.RLock()
+	stmt, ok := t.stmts[db]
+	t.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	//prepared without the lock, so that preparing on one db doesn't
+	//hold back the callers of Stmt on others
+	stmt, err := db.PrepareContext(ctx, t.sql)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cached, ok := t.stmts[db]; ok {
+		//another caller prepared it first
+		stmt.Close()
+		return cached, nil
+	}
+	if t.stmts == nil {
+		t.stmts = map[*sql.DB]*sql.Stmt{}
+	}
+	t.stmts[db] = stmt
+	return stmt, nil
+}
+
+//Close closes the statements cached by Stmt
+func (t *Template) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var first error
+	for db, stmt := range t.stmts {
+		if err := stmt.Close(); err != nil && first == nil {
+			first = err
+		}
+		delete(t.stmts, db)
+	}
+	return first
+}
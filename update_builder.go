@@ -1,8 +1,37 @@
 package query
 
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
 //UpdateBuilder is a builder for UPDATE statements
 type UpdateBuilder struct {
-	query string
+	query   strings.Builder
+	dialect Dialect
+	// hasWhere is set once the query has a WHERE clause, joinWhere
+	// while it only holds the join conditions moved there on Postgres
+	hasWhere  bool
+	joinWhere bool
+	// joinTarget is set while the ON condition of the last Join
+	// joins the updated table, and goes in the WHERE clause
+	joinTarget bool
+	guard    guard
+	idents   identifiers
+	// err is the first error of the query, reported by Err
+	err error
+	// setAt is where the SET clause starts in query,
+	// zero until Set or SetFromMap is called
+	setAt int
+	// table is the updated table, tableEnd is where it ends in query,
+	// sourceAt, whereAt and returningAt are where the tables updated from,
+	// WHERE and RETURNING start, for Preview
+	table       string
+	tableEnd    int
+	sourceAt    int
+	whereAt     int
+	returningAt int
 }
 
 //NewUpdateBuilder returns a new *UpdateBuilder
@@ -10,17 +39,64 @@ func NewUpdateBuilder() *UpdateBuilder {
 	return new(UpdateBuilder)
 }
 
+//WithDialect sets the dialect the builder's query is rendered for,
+//it affects how From, Join and bound parameters are rendered.
+func (u *UpdateBuilder) WithDialect(d Dialect) *UpdateBuilder {
+	u.dialect = d
+	return u
+}
+
+//WithIdentMode sets how the table, column and alias names given
+//to the builder are rendered, they are added verbatim by default.
+func (u *UpdateBuilder) WithIdentMode(m IdentMode) *UpdateBuilder {
+	u.idents.mode = m
+	return u
+}
+
+//Err returns the first name rejected by IdentStrict since the query was started
+func (u *UpdateBuilder) Err() error {
+	return u.err
+}
+
+//name renders name as set by the builder's IdentMode,
+//keeping the error of a name it rejects for Err
+func (u *UpdateBuilder) name(name string) string {
+	rendered, err := u.idents.name(u.dialect, name)
+	u.fail(err)
+	return rendered
+}
+
+//names renders each of names like name
+func (u *UpdateBuilder) names(names []string) []string {
+	rendered, err := u.idents.names(u.dialect, names)
+	u.fail(err)
+	return rendered
+}
+
+//fail keeps err for Err, unless the query already failed
+func (u *UpdateBuilder) fail(err error) {
+	if u.err == nil {
+		u.err = err
+	}
+}
+
 //Update adds an UPDATE statemens to the builder's query
 //table represents the database table to update.
 func (u *UpdateBuilder) Update(table string) *UpdateBuilder {
-	u.query = "UPDATE " + table
+	u.Clear()
+	u.table = u.name(table)
+	u.query.WriteString("UPDATE ")
+	u.query.WriteString(u.table)
+	u.tableEnd = u.query.Len()
 	return u
 }
 
 //Set adds a field and its new value to the builder's query
 //Update must be called prior to Set.
 func (u *UpdateBuilder) Set(field string) *UpdateBuilder {
-	u.query += " SET " + field
+	u.markSet()
+	u.query.WriteString(" SET ")
+	u.query.WriteString(field)
 	return u
 }
 
@@ -39,17 +115,141 @@ func (u *UpdateBuilder) Set(field string) *UpdateBuilder {
 // Note that string values in ixToValues beginning with '(' won't be quoted
 // by this method, as they will be assumed to be subqueries.
 func (u *UpdateBuilder) SetFromMap(ixToField map[int]interface{}) *UpdateBuilder {
-	u.query += withSetMap("SET", ixToField)
+	u.markSet()
+	withSetMap(&u.query, ixToField)
 	return u
 }
 
+//From adds other tables to update from, whose columns may then be used
+//in Set and Where. It renders UPDATE ... SET ... FROM on Postgres and SQL Server,
+//and the multi-table UPDATE a,b SET ... on MySQL.
+//
+//Usage example:
+//	Update("Sales.OrderHeader").Set("TotalAmountDue=sod.Total").
+//		From("Sales.OrderTotals AS sod").Where("Sales.OrderHeader.OrderID=sod.OrderID")
+func (u *UpdateBuilder) From(tables ...string) *UpdateBuilder {
+	if u.dialect == MySQL {
+		return u.addSource("," + strings.Join(u.names(tables), ","))
+	}
+	return u.addSource(fieldList(" FROM", false, u.names(tables)...))
+}
+
+//Join adds a JOIN clause to the builder's query,
+//table represents the name of the table to join to.
+//It is rendered in the form of each dialect:
+//	MySQL:      UPDATE a JOIN b ON a.x=b.y SET ...
+//	SQL Server: UPDATE a SET ... FROM a JOIN b ON a.x=b.y
+//	Postgres:   UPDATE a SET ... FROM b WHERE a.x=b.y
+//The condition Postgres has in WHERE doesn't count as one for AllRows.
+//On SQL Server, From may be called first to repeat the updated table
+//with an alias, it is repeated as it is given to Update otherwise.
+func (u *UpdateBuilder) Join(table string) *UpdateBuilder {
+	table = u.name(table)
+	u.joinTarget = false
+	if u.sourceAt == 0 {
+		switch u.dialect {
+		case Postgres:
+			u.joinTarget = true
+			return u.addSource(" FROM " + table)
+		case SQLServer:
+			return u.addSource(" FROM " + u.table + " JOIN " + table)
+		}
+	}
+	return u.addSource(" JOIN " + table)
+}
+
+//As sets an alias for the last table joined to
+func (u *UpdateBuilder) As(alias string) *UpdateBuilder {
+	return u.addSource(" AS " + u.name(alias))
+}
+
+//On adds the matching colmuns in joined tables.
+func (u *UpdateBuilder) On(column1 string, column2 string) *UpdateBuilder {
+	return u.on(u.name(column1) + "=" + u.name(column2))
+}
+
+//on adds cond, the condition joining the last table joined to
+func (u *UpdateBuilder) on(cond string) *UpdateBuilder {
+	if !u.joinTarget {
+		return u.addSource(" ON " + cond)
+	}
+	u.joinTarget = false
+	if u.hasWhere {
+		andWhere(&u.query, u.whereAt, cond)
+		return u
+	}
+	u.markWhere()
+	where(&u.query, cond)
+	u.joinWhere = true
+	return u
+}
+
+// addSource adds s to the tables updated from, which MySQL needs
+// before the SET clause, and the other dialects before WHERE.
+func (u *UpdateBuilder) addSource(s string) *UpdateBuilder {
+	at := u.query.Len()
+	switch {
+	case u.dialect == MySQL && u.setAt > 0:
+		at = u.setAt
+	case u.dialect == MySQL:
+	case u.hasWhere:
+		at = u.whereAt
+	case u.returningAt > 0:
+		at = u.returningAt
+	}
+	if u.dialect != MySQL && u.sourceAt == 0 {
+		u.sourceAt = at
+	}
+	if at == u.query.Len() {
+		u.query.WriteString(s)
+		return u
+	}
+
+	qry := u.query.String()
+	u.query.Reset()
+	u.query.WriteString(qry[:at])
+	u.query.WriteString(s)
+	u.query.WriteString(qry[at:])
+	if u.setAt >= at {
+		u.setAt += len(s)
+	}
+	if u.hasWhere && u.whereAt >= at {
+		u.whereAt += len(s)
+	}
+	if u.returningAt >= at {
+		u.returningAt += len(s)
+	}
+	return u
+}
+
+func (u *UpdateBuilder) markSet() {
+	if u.setAt == 0 {
+		u.setAt = u.query.Len()
+	}
+}
+
 //Where adds a WHERE clause to the builder's query.
 //condition is the desired condition
+//Once there's a WHERE clause, condition is added along with an AND,
+//the clause and condition being parenthesized if they have an OR.
 func (u *UpdateBuilder) Where(condition string) *UpdateBuilder {
-	u.query += where(condition)
+	u.joinWhere = false
+	if u.hasWhere {
+		andWhere(&u.query, u.whereAt, condition)
+		return u
+	}
+	u.markWhere()
+	where(&u.query, condition)
 	return u
 }
 
+func (u *UpdateBuilder) markWhere() {
+	if !u.hasWhere {
+		u.whereAt = u.query.Len()
+		u.hasWhere = true
+	}
+}
+
 //WhereWithMap adds a WHERE clause to the builder's query with fields and conditions
 //derived from ixToCond which should map integers(allows for proper ordering)
 //to conditions desired to be met.
@@ -60,27 +260,82 @@ func (u *UpdateBuilder) Where(condition string) *UpdateBuilder {
 //			1: "BarcodeID=22",
 //	})
 func (u *UpdateBuilder) WhereWithMap(ixToCond map[int]interface{}) *UpdateBuilder {
-	u.query += withMap("WHERE", ixToCond, false)
+	if len(ixToCond) == 0 {
+		return u
+	}
+	if u.hasWhere {
+		return u.Where(mapCondition(ixToCond))
+	}
+	u.markWhere()
+	withMap(&u.query, "WHERE", ixToCond, false)
 	return u
 }
 
 //Returning selects fields from the temporary inserted table
 func (u *UpdateBuilder) Returning(fields ...string) *UpdateBuilder {
-	u.query += " RETURNING" + addFields("", false, fields...)
+	u.markReturning()
+	u.query.WriteString(" RETURNING")
+	addFields(&u.query, "", false, u.names(fields)...)
 	return u
 }
 
 //ReturningAll selects all fields from the temporary inserted table
 func (u *UpdateBuilder) ReturningAll() *UpdateBuilder {
-	u.query += " RETURNING *"
+	u.markReturning()
+	u.query.WriteString(" RETURNING *")
 	return u
 }
 
+func (u *UpdateBuilder) markReturning() {
+	if u.returningAt == 0 {
+		u.returningAt = u.query.Len()
+	}
+}
+
+//Preview returns a *SelectBuilder selecting the rows the builder's query
+//updates, from the same tables and with the same WHERE clause.
+//
+//On SQL Server the updated table is expected to be repeated in From,
+//as documented by Join.
+func (u *UpdateBuilder) Preview() *SelectBuilder {
+	return u.preview(false)
+}
+
+//PreviewCount is like Preview, selecting the number of rows updated
+//with COUNT(*) instead.
+func (u *UpdateBuilder) PreviewCount() *SelectBuilder {
+	return u.preview(true)
+}
+
+func (u *UpdateBuilder) preview(count bool) *SelectBuilder {
+	qry := u.query.String()
+	end := len(qry)
+	if u.returningAt > 0 {
+		end = u.returningAt
+	}
+	whereAt := end
+	if u.hasWhere {
+		whereAt = u.whereAt
+	}
+
+	var sources string
+	switch {
+	case u.dialect == MySQL && u.setAt > 0:
+		sources = qry[u.tableEnd:u.setAt]
+	case u.dialect == MySQL:
+		sources = qry[u.tableEnd:whereAt]
+	case u.sourceAt > 0:
+		sources = qry[u.sourceAt:whereAt]
+	}
+	from := previewFrom(u.table, sources, u.dialect == SQLServer)
+	return preview(u.dialect, previewColumns(u.table, sources, count), from, qry[whereAt:end])
+}
+
 //And is an alternative to WhereWithMap
 //
 //it adds an AND along with the condition specified
 func (u *UpdateBuilder) And(condition string) *UpdateBuilder {
-	u.query += and(condition)
+	and(&u.query, condition)
 	return u
 }
 
@@ -88,15 +343,171 @@ func (u *UpdateBuilder) And(condition string) *UpdateBuilder {
 //
 //it adds an OR along with the condition specified
 func (u *UpdateBuilder) Or(condition string) *UpdateBuilder {
-	u.query += or(condition)
+	or(&u.query, condition)
+	return u
+}
+
+//WhereIf adds condition like Where, only if cond is true
+func (u *UpdateBuilder) WhereIf(cond bool, condition string) *UpdateBuilder {
+	if cond {
+		u.Where(condition)
+	}
+	return u
+}
+
+//AndIf adds condition along with an AND, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (u *UpdateBuilder) AndIf(cond bool, condition string) *UpdateBuilder {
+	if cond {
+		u.Where(condition)
+	}
 	return u
 }
 
-//Clear erases the builder's query
+//OrIf adds condition along with an OR, only if cond is true.
+//The condition starts the WHERE clause when there's none yet.
+func (u *UpdateBuilder) OrIf(cond bool, condition string) *UpdateBuilder {
+	if !cond {
+		return u
+	}
+	if u.hasWhere && !u.joinWhere {
+		or(&u.query, condition)
+		return u
+	}
+	return u.Where(condition)
+}
+
+//WhereNonZero adds the condition built by op on field and v like Where,
+//only if v isn't a zero value. Non-nil pointers are never skipped.
+//
+//Usage example:
+//	WhereNonZero(Eq, "FirstName", req.FirstName).WhereNonZero(G, "TotalAmountDue", req.MinAmount)
+func (u *UpdateBuilder) WhereNonZero(op Operator, field string, v interface{}) *UpdateBuilder {
+	if !isZero(v) {
+		u.Where(op(field, v))
+	}
+	return u
+}
+
+//AllRows allows the query to have no WHERE clause, so that it updates
+//every row of the table. Without it, ToSQL, Bind, Exec and the Template
+//of Compile return ErrNoWhere.
+//It MUST be called after Update, which starts a new query with it unset,
+//so that reusing the builder never affects every row by accident.
+func (u *UpdateBuilder) AllRows() *UpdateBuilder {
+	u.guard.allRows = true
+	return u
+}
+
+//MaxAffected makes Exec run the query in a transaction, which is rolled
+//back with a *MaxAffectedError if more than n rows were affected. Given
+//a *sql.Tx, Exec rolls back to a savepoint taken before the query instead.
+func (u *UpdateBuilder) MaxAffected(n int64) *UpdateBuilder {
+	u.guard.maxAffected = n
+	return u
+}
+
+//ToSQL returns the builder's query, or ErrNoWhere if it has no WHERE clause
+//and AllRows wasn't called. Names rejected by IdentStrict are reported too.
+func (u *UpdateBuilder) ToSQL() (string, error) {
+	if err := u.check(); err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+//Exec binds params to the builder's query like Bind and runs it on ex,
+//a *sql.DB, *sql.Conn or *sql.Tx, checking the number of affected rows
+//if MaxAffected was called. The hooks registered by AddHook are called around it.
+func (u *UpdateBuilder) Exec(ctx context.Context, ex Executor, params map[string]interface{}) (sql.Result, error) {
+	return NewRunner(ex).Exec(ctx, u, params)
+}
+
+func (u *UpdateBuilder) guarded() (guard, Dialect) {
+	return u.guard, u.dialect
+}
+
+func (u *UpdateBuilder) check() error {
+	if u.err != nil {
+		return u.err
+	}
+	return u.guard.check(u.hasWhere && !u.joinWhere)
+}
+
+//Comment appends the sqlcommenter comment of ctx to the query: the tags
+//set by WithTag and AddCommentSource, and the file:line Comment is called
+//from, e.g /*caller='orders%2Fstore.go%3A42',route='%2Forders'*/.
+//It should be called once the query is complete.
+func (u *UpdateBuilder) Comment(ctx context.Context) *UpdateBuilder {
+	u.query.WriteByte(' ')
+	u.query.WriteString(comment(ctx))
+	return u
+}
+
+//Bind renders the builder's query with named parameters replaced by the
+//dialect's positional placeholders, returning the values of params in
+//the same order. Names without a value and values not used by any name
+//are reported by a *BindError.
+func (u *UpdateBuilder) Bind(params map[string]interface{}) (string, []interface{}, error) {
+	if err := u.check(); err != nil {
+		return "", nil, err
+	}
+	return bindMap(u.sql(), u.dialect, params)
+}
+
+//BindStruct is like Bind, taking values from the fields of v, a struct
+//or a pointer to one. Fields are named after their db tag if they have one.
+func (u *UpdateBuilder) BindStruct(v interface{}) (string, []interface{}, error) {
+	if err := u.check(); err != nil {
+		return "", nil, err
+	}
+	return bindStruct(u.sql(), u.dialect, v)
+}
+
+//Debug binds params like Bind, then renders the query with the values
+//interpolated by Debug, for logs. It must never be executed.
+func (u *UpdateBuilder) Debug(params map[string]interface{}, opts DebugOptions) (string, error) {
+	qry, args, err := u.Bind(params)
+	if err != nil {
+		return "", err
+	}
+	return Debug(qry, args, opts), nil
+}
+
+//Compile renders the builder's query into a Template, whose named
+//parameters are bound to new values on each execution. The errors
+//ToSQL would return are returned by the Template's Args, ArgsStruct and Stmt.
+func (u *UpdateBuilder) Compile() *Template {
+	t := compile(u.sql(), u.dialect)
+	if err := u.check(); err != nil {
+		t.err = err
+	}
+	return t
+}
+
+//Clear erases the builder's query, keeping its dialect, IdentMode and
+//the setting of MaxAffected. AllRows is reset, it only applies to one query.
 func (u *UpdateBuilder) Clear() {
-	u.query = ""
+	*u = UpdateBuilder{dialect: u.dialect, idents: identifiers{mode: u.idents.mode}, guard: guard{maxAffected: u.guard.maxAffected}}
 }
 
+//String returns the builder's query, with the values of an Expr rendered as ?
 func (u *UpdateBuilder) String() string {
-	return u.query + ";"
+	return exprPlaceholders(u.sql())
+}
+
+//sql returns the builder's query, as bound by Bind
+func (u *UpdateBuilder) sql() string {
+	return u.query.String() + ";"
+}
+
+//Pretty returns the builder's query laid out by Format
+//with the zero FormatOptions.
+func (u *UpdateBuilder) Pretty() string {
+	return Format(u.String(), FormatOptions{})
+}
+
+//Fingerprint returns the Fingerprint of the builder's query
+func (u *UpdateBuilder) Fingerprint() Fingerprint {
+	return FingerprintSQL(u.String())
 }
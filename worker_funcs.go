@@ -4,168 +4,243 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
-//withMap returns a string composed of values from mapper
+//withMap writes values from mapper to b
 //if parenthesize is true, each value in mapper is parenthesized before
-//adding it to qry, prefix allows for different statements(like WHERE or VALUES) to be specified
-func withMap(prefix string, mapper map[int]interface{}, parenthesize bool) string {
+//writing it, prefix allows for different statements(like WHERE or VALUES) to be specified
+func withMap(b *strings.Builder, prefix string, mapper map[int]interface{}, parenthesize bool) {
 	if mapper == nil {
-		return ""
+		return
 	}
 
-	qry := ""
-	if prefix == "," {
-		qry = prefix
-	} else {
-		qry = " " + prefix
+	if prefix != "," {
+		b.WriteByte(' ')
 	}
-	keys := make([]int, 0, len(mapper))
-	for k := range mapper {
-		keys = append(keys, k)
-	}
-	sort.Ints(keys)
-	l := len(keys) - 1
+	b.WriteString(prefix)
 
-	if parenthesize {
-		for ix, key := range keys {
-			if ix == l {
-				qry += "(" + stringifyNoQuote(mapper[key]) + ")"
-				break
+	for ix, key := range sortedKeys(mapper) {
+		if parenthesize {
+			if ix > 0 {
+				b.WriteByte(',')
 			}
-			qry += "(" + stringifyNoQuote(mapper[key]) + "),"
+			b.WriteByte('(')
+			b.WriteString(stringifyNoQuote(mapper[key]))
+			b.WriteByte(')')
+			continue
 		}
-		return qry
+		b.WriteByte(' ')
+		b.WriteString(stringifyNoQuote(mapper[key]))
 	}
-
-	for ix, key := range keys {
-		if ix == l {
-			qry += " " + stringifyNoQuote(mapper[key])
-			break
-		}
-		qry += " " + stringifyNoQuote(mapper[key])
-	}
-	return qry
 }
 
-func withSetMap(prefix string, mapper map[int]interface{}) string {
+func withSetMap(b *strings.Builder, mapper map[int]interface{}) {
 	if mapper == nil {
-		return ""
+		return
 	}
 
-	qry := " SET "
-	keys := make([]int, 0, len(mapper))
-	for k := range mapper {
-		keys = append(keys, k)
-	}
-	sort.Ints(keys)
-	l := len(keys) - 1
-
-	for ix, key := range keys {
-		if ix == l {
-			qry += stringifyNoQuote(mapper[key])
-			break
+	b.WriteString(" SET ")
+	for ix, key := range sortedKeys(mapper) {
+		if ix > 0 {
+			b.WriteByte(',')
 		}
-		qry += stringifyNoQuote(mapper[key]) + ","
+		b.WriteString(stringifyNoQuote(mapper[key]))
 	}
-	return qry
 }
 
-// values mutates all values in mapper to a one string,
+// values writes all values in mapper to b,
 // with commas seperating each value.
-// obsolete keys will be deleted and the only the first index
-// will remain
 // mapper only supports pointers to int,uint types and the string data type.
-func values(mapper map[int]interface{}) string {
-	if mapper == nil {
-		return ""
+func values(b *strings.Builder, mapper map[int]interface{}) {
+	for ix, key := range sortedKeys(mapper) {
+		if ix > 0 {
+			b.WriteByte(',')
+		}
+		writeQuote(b, mapper[key])
 	}
+}
 
-	qry := ""
+//sortedKeys returns the keys of mapper in ascending order
+func sortedKeys(mapper map[int]interface{}) []int {
 	keys := make([]int, 0, len(mapper))
 	for k := range mapper {
 		keys = append(keys, k)
 	}
 	sort.Ints(keys)
-	l := len(keys) - 1
+	return keys
+}
 
-	for ix, key := range keys {
-		if ix == l {
-			qry += stringifyQuote(mapper[key])
-			break
+//in writes field IN(values...) to b
+func in(b *strings.Builder, field string, values []interface{}) {
+	b.WriteString(field)
+	b.WriteString(" IN(")
+	for ix, v := range values {
+		if ix > 0 {
+			b.WriteByte(',')
 		}
-		qry += stringifyQuote(mapper[key]) + ","
+		writeQuote(b, v)
 	}
-	return qry
+	b.WriteByte(')')
 }
 
-//whereIn adds a WHERE clause along with IN keyword with values derived from
-//the values parameter
-func whereIn(field string, values ...interface{}) string {
-	if values == nil {
-		return ""
+func addFields(b *strings.Builder, prefix string, parenthesize bool, fields ...string) {
+	b.WriteString(prefix)
+	b.WriteByte(' ')
+	if parenthesize {
+		b.WriteByte('(')
 	}
-
-	qry := " WHERE " + field + " IN("
-	l := len(values) - 1
-	for ix, v := range values {
-		if ix == l {
-			qry += stringifyQuote(v)
-			break
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteByte(',')
 		}
-		qry += stringifyQuote(v) + ","
+		b.WriteString(field)
+	}
+	if parenthesize {
+		b.WriteByte(')')
 	}
-	qry += ")"
-	return qry
 }
 
-func addFields(prefix string, parenthesize bool, fields ...string) string {
-	qry := prefix + " "
-	if parenthesize {
-		qry += "("
-		for i, field := range fields {
-			if i == len(fields)-1 {
-				qry += field
-				break
-			}
-			qry += field + ","
-		}
-		qry += ")"
-		return qry
+//fieldList returns what addFields writes, for builders rendering
+//their query from parts
+func fieldList(prefix string, parenthesize bool, fields ...string) string {
+	var b strings.Builder
+	addFields(&b, prefix, parenthesize, fields...)
+	return b.String()
+}
+
+//offset writes an OFFSET clause
+func offset(b *strings.Builder, num uint64) {
+	b.WriteString(" OFFSET ")
+	b.WriteString(strconv.FormatUint(num, 10))
+}
+
+func limit(b *strings.Builder, num uint64) {
+	b.WriteString(" LIMIT ")
+	b.WriteString(strconv.FormatUint(num, 10))
+}
+
+//conditionKeyword returns the keyword introducing a condition,
+//WHERE for the first one and AND for the next ones
+func conditionKeyword(hasWhere bool) string {
+	if hasWhere {
+		return " AND "
+	}
+	return " WHERE "
+}
+
+//isZero reports whether v is nil, the zero value of its type or an empty
+//slice or map. Non-nil pointers aren't zero, even when pointing to a zero value.
+func isZero(v interface{}) bool {
+	if v == nil {
+		return true
 	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
 
-	for i, field := range fields {
-		if i == len(fields)-1 {
-			qry += field
-			break
-		}
-		qry += field + ","
+func where(b *strings.Builder, cond string) {
+	b.WriteString(" WHERE ")
+	b.WriteString(cond)
+}
+
+//andWhere adds cond to the WHERE clause of b starting at whereAt, along
+//with an AND. The clause and cond are parenthesized when they have an OR
+//outside parentheses, so that cond applies to every row the clause matches:
+//WHERE (a=1 OR b=2) AND c=3 rather than WHERE a=1 OR b=2 AND c=3.
+//It reports whether the clause was parenthesized.
+func andWhere(b *strings.Builder, whereAt int, cond string) bool {
+	parenthesized := parenthesizeWhere(b, whereAt)
+	and(b, parenthesizeOr(cond))
+	return parenthesized
+}
+
+//parenthesizeWhere parenthesizes the WHERE clause of b starting
+//at whereAt, if it has an OR outside parentheses, and reports whether it did.
+func parenthesizeWhere(b *strings.Builder, whereAt int) bool {
+	qry := b.String()
+	start := whereAt + len(" WHERE ")
+	if start > len(qry) || !hasOr(qry[start:]) {
+		return false
 	}
-	return qry
+	b.Reset()
+	b.WriteString(qry[:start])
+	b.WriteByte('(')
+	b.WriteString(qry[start:])
+	b.WriteByte(')')
+	return true
 }
 
-//offset adds an OFFSET clause,
-//only if num is a number
-func offset(num uint64) string {
-	return " OFFSET " + strconv.FormatUint(num, 10)
+//parenthesizeOr returns cond parenthesized if it has an OR outside parentheses
+func parenthesizeOr(cond string) string {
+	if hasOr(cond) {
+		return "(" + cond + ")"
+	}
+	return cond
+}
+
+//hasOr reports whether cond has an OR outside parentheses and quotes
+func hasOr(cond string) bool {
+	depth := 0
+	for _, tok := range tokenize(cond) {
+		switch {
+		case tok.kind == fmtPunct && tok.text == "(":
+			depth++
+		case tok.kind == fmtPunct && tok.text == ")":
+			depth--
+		case tok.kind == fmtWord && depth == 0 && strings.EqualFold(tok.text, "OR"):
+			return true
+		}
+	}
+	return false
 }
 
-func limit(num uint64) string {
-	return " LIMIT " + strconv.FormatUint(num, 10)
+//mapCondition returns the conditions of ixToCond in order,
+//separated by spaces as WhereWithMap writes them.
+func mapCondition(ixToCond map[int]interface{}) string {
+	conds := make([]string, 0, len(ixToCond))
+	for _, key := range sortedKeys(ixToCond) {
+		conds = append(conds, stringifyNoQuote(ixToCond[key]))
+	}
+	return strings.Join(conds, " ")
 }
 
-func where(cond string) string {
-	qry := " WHERE " + cond
-	return qry
+func and(b *strings.Builder, cond string) {
+	b.WriteString(" AND ")
+	b.WriteString(cond)
 }
 
-func and(cond string) string {
-	return " AND " + cond
+func or(b *strings.Builder, cond string) {
+	b.WriteString(" OR ")
+	b.WriteString(cond)
 }
 
-func or(cond string) string {
-	return " OR " + cond
+//writeQuote writes what stringifyQuote returns to b,
+//without building an intermediate string for string values
+func writeQuote(b *strings.Builder, i interface{}) {
+	var buf [20]byte
+	switch v := i.(type) {
+	case string:
+		b.WriteByte('\'')
+		if strings.IndexByte(v, '\'') >= 0 {
+			v = strings.Replace(v, "'", "''", -1)
+		}
+		b.WriteString(v)
+		b.WriteByte('\'')
+	case int:
+		b.Write(strconv.AppendInt(buf[:0], int64(v), 10))
+	case int64:
+		b.Write(strconv.AppendInt(buf[:0], v, 10))
+	case uint64:
+		b.Write(strconv.AppendUint(buf[:0], v, 10))
+	default:
+		b.WriteString(stringifyQuote(i))
+	}
 }
 
 // stringer allows us to avoid importing fmt
@@ -177,6 +252,8 @@ func stringifyNoQuote(i interface{}) string {
 	switch i.(type) {
 	case string:
 		return i.(string)
+	case NamedParam:
+		return i.(NamedParam).String()
 	case *string:
 		return *i.(*string)
 	case *time.Time:
@@ -193,20 +270,29 @@ func stringifyNoQuote(i interface{}) string {
 func stringifyQuote(i interface{}) string {
 	switch i.(type) {
 	case string:
-		return "'" + i.(string) + "'"
+		return quote(i.(string))
+	case NamedParam:
+		return i.(NamedParam).String()
+	case Expression:
+		return i.(Expression).String()
 	case *string:
-		return "'" + *i.(*string) + "'"
+		return quote(*i.(*string))
 	case *time.Time:
 		return "'" + i.(*time.Time).UTC().Format(time.RFC3339) + "'"
 	case time.Time:
 		return "'" + i.(time.Time).UTC().Format(time.RFC3339) + "'"
 	case stringer:
-		return "'" + i.(stringer).String() + "'"
+		return quote(i.(stringer).String())
 	}
 
 	return valueString(reflect.ValueOf(i))
 }
 
+//quote returns s as a string literal, with its quotes escaped
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
 func valueString(v reflect.Value) string {
 	switch v.Kind() {
 	case reflect.Ptr, reflect.Interface: